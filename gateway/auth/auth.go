@@ -3,17 +3,31 @@ package auth
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
 
+	"github.com/abaldeweg/warehouse-server/gateway/proxy"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
 
-// User represents a user object.
+// BranchHeader selects the active branch of a request.
+const BranchHeader = "X-Branch-ID"
+
+// membershipKey is the context key of the membership of the active branch.
+const membershipKey = "membership"
+
+// RoleSuperAdmin is the role of users administrating all branches. It is
+// granted independently of the active branch.
+const RoleSuperAdmin = "ROLE_SUPER_ADMIN"
+
+// User represents a user object. Branch and Roles are the default branch
+// and the roles in it, Memberships lists all branches the user works in.
 type User struct {
-	Id       int      `json:"id"`
-	Username string   `json:"username"`
-	Branch   Branch   `json:"branch"`
-	Roles    []string `json:"roles"`
+	Id          int          `json:"id"`
+	Username    string       `json:"username"`
+	Branch      Branch       `json:"branch"`
+	Roles       []string     `json:"roles"`
+	Memberships []Membership `json:"memberships"`
 }
 
 // Branch represents a branch object.
@@ -21,8 +35,92 @@ type Branch struct {
 	Id int `json:"id"`
 }
 
+// Membership grants a user roles in a branch.
+type Membership struct {
+	Branch Branch   `json:"branch"`
+	Roles  []string `json:"roles"`
+}
+
+// BranchMemberships returns the memberships of the user. A user without
+// memberships is a member of its default branch only.
+func (u User) BranchMemberships() []Membership {
+	if len(u.Memberships) > 0 {
+		return u.Memberships
+	}
+	if u.Branch.Id == 0 {
+		return []Membership{}
+	}
+	return []Membership{{Branch: u.Branch, Roles: u.Roles}}
+}
+
+// Membership returns the membership of the user in the branch.
+func (u User) Membership(branchId int) (Membership, bool) {
+	for _, m := range u.BranchMemberships() {
+		if m.Branch.Id == branchId {
+			return m, true
+		}
+	}
+	return Membership{}, false
+}
+
+// DefaultMembership returns the membership in the default branch or, if
+// the user isn't a member of it, the first membership.
+func (u User) DefaultMembership() (Membership, bool) {
+	if m, ok := u.Membership(u.Branch.Id); ok {
+		return m, true
+	}
+	if memberships := u.BranchMemberships(); len(memberships) > 0 {
+		return memberships[0], true
+	}
+	return Membership{}, false
+}
+
+// IsSuperAdmin reports whether the user has the super admin role in any of
+// its branches.
+func (u User) IsSuperAdmin() bool {
+	for _, m := range u.BranchMemberships() {
+		if slices.Contains(m.Roles, RoleSuperAdmin) {
+			return true
+		}
+	}
+	return slices.Contains(u.Roles, RoleSuperAdmin)
+}
+
+// UserID returns the ID of the logged in user.
+func UserID(c *gin.Context) (int, bool) {
+	user, ok := c.Get("user")
+	if !ok {
+		return 0, false
+	}
+	return user.(User).Id, true
+}
+
+// SetActiveBranch sets the membership of the branch the request works in.
+func SetActiveBranch(c *gin.Context, m Membership) {
+	c.Set(membershipKey, m)
+}
+
+// ActiveBranch returns the membership of the branch the request works in.
+func ActiveBranch(c *gin.Context) (Membership, bool) {
+	m, ok := c.Get(membershipKey)
+	if !ok {
+		return Membership{}, false
+	}
+	return m.(Membership), true
+}
+
+// BranchID returns the ID of the branch the request works in.
+func BranchID(c *gin.Context) (uint, bool) {
+	m, ok := ActiveBranch(c)
+	if !ok {
+		return 0, false
+	}
+	return uint(m.Branch.Id), true
+}
+
 // Authenticate authenticates a user based on the Authorization header.
 // It makes a request to the auth service to validate the token and retrieve user information.
+// The request shares the timeout and circuit breaker of the proxied upstreams.
 func Authenticate(c *gin.Context) bool {
 	viper.SetDefault("AUTH_API_ME", "/")
 
@@ -34,15 +132,14 @@ func Authenticate(c *gin.Context) bool {
 
 	token := authHeader[7:]
 
-	req, err := http.NewRequest("GET", viper.GetString("AUTH_API_ME"), nil)
+	req, err := http.NewRequestWithContext(c.Request.Context(), "GET", viper.GetString("AUTH_API_ME"), nil)
 	if err != nil {
 		return false
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := proxy.Do(req)
 	if err != nil {
 		return false
 	}
@@ -83,3 +83,47 @@ func TestUser(t *testing.T) {
 
     assert.Equal(t, user, c.MustGet("user").(User))
 }
+
+func TestMemberships(t *testing.T) {
+	user := User{Id: 1, Branch: Branch{Id: 1}, Roles: []string{"ROLE_USER"}}
+
+	m, ok := user.DefaultMembership()
+	assert.True(t, ok)
+	assert.Equal(t, Membership{Branch: Branch{Id: 1}, Roles: []string{"ROLE_USER"}}, m)
+	_, ok = user.Membership(2)
+	assert.False(t, ok)
+
+	user.Memberships = []Membership{
+		{Branch: Branch{Id: 2}, Roles: []string{"ROLE_USER", "ROLE_ADMIN"}},
+		{Branch: Branch{Id: 3}, Roles: []string{"ROLE_USER"}},
+	}
+
+	m, ok = user.Membership(2)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"ROLE_USER", "ROLE_ADMIN"}, m.Roles)
+	_, ok = user.Membership(1)
+	assert.False(t, ok)
+
+	m, ok = user.DefaultMembership()
+	assert.True(t, ok)
+	assert.Equal(t, 2, m.Branch.Id)
+
+	_, ok = User{}.DefaultMembership()
+	assert.False(t, ok)
+
+	assert.False(t, user.IsSuperAdmin())
+	user.Memberships[1].Roles = append(user.Memberships[1].Roles, RoleSuperAdmin)
+	assert.True(t, user.IsSuperAdmin())
+}
+
+func TestActiveBranch(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	_, ok := BranchID(c)
+	assert.False(t, ok)
+
+	SetActiveBranch(c, Membership{Branch: Branch{Id: 3}})
+	id, ok := BranchID(c)
+	assert.True(t, ok)
+	assert.Equal(t, uint(3), id)
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// permissionsKey is the context key of the permissions in the active branch.
+const permissionsKey = "permissions"
+
+// Permissions known to the gateway.
+const (
+	PermAuthorView      = "author.view"
+	PermAuthorEdit      = "author.edit"
+	PermAuthorDelete    = "author.delete"
+	PermBookView        = "book.view"
+	PermBookEdit        = "book.edit"
+	PermBookSell        = "book.sell"
+	PermBookReserve     = "book.reserve"
+	PermBookRemove      = "book.remove"
+	PermBookDelete      = "book.delete"
+	PermBookStats       = "book.stats"
+	PermBookClean       = "book.clean"
+	PermCoverClean      = "cover.clean"
+	PermBranchView      = "branch.view"
+	PermBranchEdit      = "branch.edit"
+	PermConditionView   = "condition.view"
+	PermConditionEdit   = "condition.edit"
+	PermFormatView      = "format.view"
+	PermFormatEdit      = "format.edit"
+	PermGenreView       = "genre.view"
+	PermGenreEdit       = "genre.edit"
+	PermTagView         = "tag.view"
+	PermTagCreate       = "tag.create"
+	PermTagEdit         = "tag.edit"
+	PermInventoryView   = "inventory.view"
+	PermInventoryCount  = "inventory.count"
+	PermInventoryEdit   = "inventory.edit"
+	PermInventoryClose  = "inventory.close"
+	PermPricelistView   = "pricelist.view"
+	PermPricelistEdit   = "pricelist.edit"
+	PermReservationView = "reservation.view"
+	PermReservationEdit = "reservation.edit"
+	PermTransferView    = "transfer.view"
+	PermTransferEdit    = "transfer.edit"
+	PermTransferReceive = "transfer.receive"
+	PermAnalyzeView     = "analyze.view"
+	PermRoleEdit        = "role.edit"
+)
+
+// AllPermissions lists every permission.
+var AllPermissions = []string{
+	PermAuthorView, PermAuthorEdit, PermAuthorDelete,
+	PermBookView, PermBookEdit, PermBookSell, PermBookReserve, PermBookRemove, PermBookDelete, PermBookStats, PermBookClean,
+	PermCoverClean,
+	PermBranchView, PermBranchEdit,
+	PermConditionView, PermConditionEdit,
+	PermFormatView, PermFormatEdit,
+	PermGenreView, PermGenreEdit,
+	PermTagView, PermTagCreate, PermTagEdit,
+	PermInventoryView, PermInventoryCount, PermInventoryEdit, PermInventoryClose,
+	PermPricelistView, PermPricelistEdit,
+	PermReservationView, PermReservationEdit,
+	PermTransferView, PermTransferEdit, PermTransferReceive,
+	PermAnalyzeView,
+	PermRoleEdit,
+}
+
+// DefaultRoles are the permissions of the roles a branch hasn't configured
+// itself.
+var DefaultRoles = map[string][]string{
+	"ROLE_ADMIN": AllPermissions,
+	"ROLE_USER": {
+		PermAuthorView, PermAuthorEdit,
+		PermBookView, PermBookEdit, PermBookSell, PermBookReserve, PermBookRemove, PermBookDelete, PermBookStats,
+		PermBranchView,
+		PermConditionView,
+		PermFormatView,
+		PermGenreView,
+		PermTagView, PermTagCreate,
+		PermInventoryView, PermInventoryCount,
+		PermPricelistView,
+		PermReservationView, PermReservationEdit,
+		PermTransferView, PermTransferEdit, PermTransferReceive,
+		PermAnalyzeView,
+	},
+}
+
+// IsPermission reports whether the permission is known.
+func IsPermission(permission string) bool {
+	return slices.Contains(AllPermissions, permission)
+}
+
+// ResolvePermissions returns the permissions of the roles. Roles of the
+// branch replace the default roles of the same name, unknown roles grant
+// nothing.
+func ResolvePermissions(roles []string, branchRoles map[string][]string) []string {
+	granted := map[string]bool{}
+	for _, role := range roles {
+		permissions, ok := branchRoles[role]
+		if !ok {
+			permissions = DefaultRoles[role]
+		}
+		for _, p := range permissions {
+			granted[p] = true
+		}
+	}
+
+	permissions := []string{}
+	for _, p := range AllPermissions {
+		if granted[p] {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+// SetPermissions sets the permissions of the user in the active branch.
+func SetPermissions(c *gin.Context, permissions []string) {
+	c.Set(permissionsKey, permissions)
+}
+
+// HasPermission reports whether the user has the permission in the active
+// branch.
+func HasPermission(c *gin.Context, permission string) bool {
+	permissions, ok := c.Get(permissionsKey)
+	if !ok {
+		return false
+	}
+	return slices.Contains(permissions.([]string), permission)
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePermissions(t *testing.T) {
+	assert.Equal(t, AllPermissions, ResolvePermissions([]string{"ROLE_ADMIN", "ROLE_USER"}, nil))
+	assert.Equal(t, []string{}, ResolvePermissions([]string{"ROLE_UNKNOWN"}, nil))
+
+	user := ResolvePermissions([]string{"ROLE_USER"}, nil)
+	assert.Contains(t, user, PermBookSell)
+	assert.Contains(t, user, PermBookDelete)
+	assert.NotContains(t, user, PermFormatEdit)
+
+	branchRoles := map[string][]string{
+		"ROLE_VOLUNTEER": {PermBookSell, PermBookView},
+		"ROLE_USER":      {PermBookView},
+	}
+	assert.Equal(t, []string{PermBookView, PermBookSell}, ResolvePermissions([]string{"ROLE_VOLUNTEER"}, branchRoles))
+	assert.Equal(t, []string{PermBookView}, ResolvePermissions([]string{"ROLE_USER"}, branchRoles))
+
+	for _, permissions := range DefaultRoles {
+		for _, p := range permissions {
+			assert.True(t, IsPermission(p), p)
+		}
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.False(t, HasPermission(c, PermBookSell))
+
+	SetPermissions(c, []string{PermBookSell})
+	assert.True(t, HasPermission(c, PermBookSell))
+	assert.False(t, HasPermission(c, PermBookDelete))
+}
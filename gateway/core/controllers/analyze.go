@@ -85,12 +85,11 @@ func (ac *AnalyzeController) Create(c *gin.Context) {
 
 // GetShopSearchEntries handles GET requests returning analyze entries between start and end dates.
 func (ac *AnalyzeController) GetShopSearchEntries(c *gin.Context) {
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	branchId := uint(user.(auth.User).Branch.Id)
 
 	start := c.Query("start")
 	end := c.Query("end")
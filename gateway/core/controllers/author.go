@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -66,11 +68,21 @@ func (ac *AuthorController) CreateAuthor(c *gin.Context) {
 		return
 	}
 
+	author.Normalize()
+
 	if err := ac.v.Struct(author); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Not Valid"})
 		return
 	}
 
+	if existing, err := ac.repo.FindByName(author.Firstname, author.Surname); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Author exists already", "author": existing})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author"})
+		return
+	}
+
 	if err := ac.repo.Create(&author); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author"})
 		return
@@ -94,6 +106,7 @@ func (ac *AuthorController) UpdateAuthor(c *gin.Context) {
 	}
 
 	author.ID = id
+	author.Normalize()
 
 	if err := ac.v.Struct(author); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Not Valid"})
@@ -123,3 +136,54 @@ func (ac *AuthorController) DeleteAuthor(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, gin.H{})
 }
+
+// GetDuplicates lists groups of authors whose names are the same or nearly
+// the same.
+func (ac *AuthorController) GetDuplicates(c *gin.Context) {
+	duplicates, err := ac.repo.FindDuplicates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicates)
+}
+
+// MergeAuthors moves the books of the source authors to the target and
+// deletes the sources.
+func (ac *AuthorController) MergeAuthors(c *gin.Context) {
+	var merge models.AuthorMerge
+	if err := c.ShouldBindJSON(&merge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		return
+	}
+
+	if err := ac.v.Struct(merge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not Valid"})
+		return
+	}
+
+	slices.Sort(merge.Sources)
+	merge.Sources = slices.Compact(merge.Sources)
+
+	moved, err := ac.repo.Merge(merge.Target, merge.Sources)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidMerge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Target is one of the sources"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge authors"})
+		}
+		return
+	}
+
+	author, err := ac.repo.FindOneById(merge.Target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"author": author, "books": moved})
+}
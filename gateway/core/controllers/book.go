@@ -1,19 +1,28 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"strings"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/abaldeweg/warehouse-server/gateway/auth"
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
 	"github.com/abaldeweg/warehouse-server/gateway/cover"
+	"github.com/abaldeweg/warehouse-server/gateway/names"
+	"github.com/abaldeweg/warehouse-server/gateway/pricing"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// errInvalidContributorRole is returned for contributors with an unknown
+// role.
+var errInvalidContributorRole = errors.New("invalid contributor role")
+
 // BookController struct defines the database connection.
 type BookController struct {
 	DB   *gorm.DB
@@ -30,12 +39,11 @@ func NewBookController(db *gorm.DB) *BookController {
 
 // CleanBooks removes books that are marked as removed or sold.
 func (pbc *BookController) CleanBooks(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	branchId := uint(user.(auth.User).Branch.Id)
 	if err := pbc.Repo.DeleteBooksByBranch(branchId); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Failed to clean books"})
 		return
@@ -46,12 +54,11 @@ func (pbc *BookController) CleanBooks(ctx *gin.Context) {
 
 // FindInventory marks books as found in inventory.
 func (pbc *BookController) FindInventory(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	branchId := uint(user.(auth.User).Branch.Id)
 
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
@@ -74,7 +81,7 @@ func (pbc *BookController) FindInventory(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"msg": "Active inventory not found"})
 		return
 	}
-	if user.(auth.User).Branch.Id != int(inventory.Branch.ID) {
+	if branchId != inventory.Branch.ID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -106,12 +113,11 @@ func (pbc *BookController) FindInventory(ctx *gin.Context) {
 
 // NotFoundInventory marks books as not found in inventory.
 func (pbc *BookController) NotFoundInventory(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	branchId := uint(user.(auth.User).Branch.Id)
 
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
@@ -134,7 +140,7 @@ func (pbc *BookController) NotFoundInventory(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"msg": "Active inventory not found"})
 		return
 	}
-	if user.(auth.User).Branch.Id != int(inventory.Branch.ID) {
+	if branchId != inventory.Branch.ID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -166,7 +172,7 @@ func (pbc *BookController) NotFoundInventory(ctx *gin.Context) {
 
 // SellBook marks books as sold.
 func (pbc *BookController) SellBook(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -183,7 +189,7 @@ func (pbc *BookController) SellBook(ctx *gin.Context) {
 		return
 	}
 
-	if book.BranchID == nil || user.(auth.User).Branch.Id != int(*book.BranchID) {
+	if book.BranchID == nil || branchId != *book.BranchID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -211,7 +217,7 @@ func (pbc *BookController) SellBook(ctx *gin.Context) {
 
 // RemoveBook marks books as removed.
 func (pbc *BookController) RemoveBook(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -228,7 +234,7 @@ func (pbc *BookController) RemoveBook(ctx *gin.Context) {
 		return
 	}
 
-	if book.BranchID == nil || user.(auth.User).Branch.Id != int(*book.BranchID) {
+	if book.BranchID == nil || branchId != *book.BranchID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -254,7 +260,7 @@ func (pbc *BookController) RemoveBook(ctx *gin.Context) {
 
 // ReserveBook marks books as reserved.
 func (pbc *BookController) ReserveBook(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -271,7 +277,7 @@ func (pbc *BookController) ReserveBook(ctx *gin.Context) {
 		return
 	}
 
-	if book.BranchID == nil || user.(auth.User).Branch.Id != int(*book.BranchID) {
+	if book.BranchID == nil || branchId != *book.BranchID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -295,12 +301,11 @@ func (pbc *BookController) ReserveBook(ctx *gin.Context) {
 
 // ShowStats retrieves book statistics.
 func (pbc *BookController) ShowStats(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	branchId := uint(user.(auth.User).Branch.Id)
 
 	var (
 		all       int64
@@ -344,18 +349,24 @@ func (pbc *BookController) ShowStats(ctx *gin.Context) {
 	}
 
 	// storage size
-	var size int64 = 0
+	var size, used, orphaned int64
 	if s, err := cover.GetSize(); err == nil {
 		size = s
 	}
+	if usage, ok := cover.LastUsage(); ok {
+		used = usage.UsedBytes
+		orphaned = usage.OrphanBytes
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"all":       all,
-		"available": available,
-		"reserved":  reserved,
-		"sold":      sold,
-		"removed":   removed,
-		"storage":   float64(size) / 1000000.0,
+		"all":              all,
+		"available":        available,
+		"reserved":         reserved,
+		"sold":             sold,
+		"removed":          removed,
+		"storage":          float64(size) / 1000000.0,
+		"storage_used":     float64(used) / 1000000.0,
+		"storage_orphaned": float64(orphaned) / 1000000.0,
 	})
 }
 
@@ -377,6 +388,103 @@ func (pbc *BookController) ShowCover(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, covers)
 }
 
+// UploadCover validates and stores a new cover for a book of the user's branch.
+func (pbc *BookController) UploadCover(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+	id := ctx.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid book id", "code": "invalid_id"})
+		return
+	}
+
+	book, err := pbc.Repo.FindByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found", "code": "not_found"})
+		return
+	}
+
+	if book.BranchID == nil || branchId != *book.BranchID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Invalid Branch", "code": "invalid_branch"})
+		return
+	}
+
+	cover.SaveCover(ctx, book.ID.String())
+}
+
+// maxBatchCovers limits the number of books of a batch cover request.
+const maxBatchCovers = 200
+
+// BatchCovers returns the covers of several books of the user's branch in
+// one size. Books that don't exist or belong to another branch are flagged
+// as not_found, books without a cover as missing.
+func (pbc *BookController) BatchCovers(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	var input struct {
+		IDs    []uuid.UUID `json:"ids" binding:"required"`
+		Size   string      `json:"size"`
+		Inline bool        `json:"inline"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+	if len(input.IDs) > maxBatchCovers {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d books per request", maxBatchCovers)})
+		return
+	}
+	if input.Size == "" {
+		input.Size = "m"
+	}
+	if _, ok := cover.Sizes[input.Size]; !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid size"})
+		return
+	}
+
+	books, err := pbc.Repo.FindByIDs(input.IDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch books"})
+		return
+	}
+
+	found := make([]uuid.UUID, 0, len(books))
+	for _, b := range books {
+		if b.BranchID != nil && *b.BranchID == branchId {
+			found = append(found, b.ID)
+		}
+	}
+
+	covers, err := cover.Batch(found, input.Size, input.Inline)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load covers"})
+		return
+	}
+
+	byID := make(map[uuid.UUID]cover.BatchCover, len(covers))
+	for _, c := range covers {
+		byID[c.ID] = c
+	}
+
+	result := make([]cover.BatchCover, 0, len(input.IDs))
+	for _, id := range input.IDs {
+		c, ok := byID[id]
+		if !ok {
+			c = cover.BatchCover{ID: id, Status: cover.StatusNotFound}
+		}
+		result = append(result, c)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"covers": result})
+}
+
 // DeleteCover deletes the cover images for a book.
 func (pbc *BookController) DeleteCover(ctx *gin.Context) {
 	id := ctx.Param("id")
@@ -386,13 +494,39 @@ func (pbc *BookController) DeleteCover(ctx *gin.Context) {
 		return
 	}
 
-	cover.DeleteCover(bookID)
+	if err := cover.DeleteCover(bookID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete cover images"})
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "cover images deleted"})
 }
 
+// ShowOrphanedCovers reports cover files without a book and books with an
+// incomplete set of covers.
+func (pbc *BookController) ShowOrphanedCovers(ctx *gin.Context) {
+	report, err := cover.Scan(pbc.Repo.ExistingIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to scan covers"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// DeleteOrphanedCovers deletes cover files without a book.
+func (pbc *BookController) DeleteOrphanedCovers(ctx *gin.Context) {
+	report, err := cover.CollectGarbage(pbc.Repo.ExistingIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete orphaned covers", "report": report})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
 // ShowBook retrieves a book by its ID.
 func (pbc *BookController) ShowBook(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -409,7 +543,7 @@ func (pbc *BookController) ShowBook(ctx *gin.Context) {
 		return
 	}
 
-	if book.BranchID == nil || user.(auth.User).Branch.Id != int(*book.BranchID) {
+	if book.BranchID == nil || branchId != *book.BranchID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -417,9 +551,56 @@ func (pbc *BookController) ShowBook(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, book)
 }
 
+// SearchBooks finds the books of the branch matching the term, best first,
+// optionally only those of a genre and its subgenres.
+func (pbc *BookController) SearchBooks(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid offset"})
+		return
+	}
+
+	var genreIDs []uint
+	if genre := ctx.Query("genre"); genre != "" {
+		genreID, err := strconv.ParseUint(genre, 10, 0)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid genre"})
+			return
+		}
+		genreRepo := repository.NewGenreRepository(pbc.DB)
+		if g, err := genreRepo.FindOne(uint(genreID)); err != nil || g.BranchID != branchId {
+			ctx.JSON(http.StatusNotFound, gin.H{"msg": "Genre not found"})
+			return
+		}
+		if genreIDs, err = genreRepo.FindDescendantIDs(uint(genreID)); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Failed to search books"})
+			return
+		}
+	}
+
+	books, counter, err := pbc.Repo.Search(branchId, ctx.Query("term"), genreIDs, offset, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Failed to search books"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"books": books, "counter": counter})
+}
+
 // DeleteBook deletes a book.
 func (pbc *BookController) DeleteBook(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -436,7 +617,7 @@ func (pbc *BookController) DeleteBook(ctx *gin.Context) {
 		return
 	}
 
-	if book.BranchID == nil || user.(auth.User).Branch.Id != int(*book.BranchID) {
+	if book.BranchID == nil || branchId != *book.BranchID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -451,7 +632,7 @@ func (pbc *BookController) DeleteBook(ctx *gin.Context) {
 
 // UpdateBook updates a book.
 func (pbc *BookController) UpdateBook(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -468,7 +649,7 @@ func (pbc *BookController) UpdateBook(ctx *gin.Context) {
 		return
 	}
 
-	if book.BranchID == nil || user.(auth.User).Branch.Id != int(*book.BranchID) {
+	if book.BranchID == nil || branchId != *book.BranchID {
 		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
 		return
 	}
@@ -490,35 +671,20 @@ func (pbc *BookController) UpdateBook(ctx *gin.Context) {
 	if bu.ShortDescription != nil {
 		book.ShortDescription = bu.ShortDescription
 	}
-	if bu.Author != nil {
-		fname := ""
-		sname := ""
-		if *bu.Author != "" {
-			authorStr := *bu.Author
-			if strings.Contains(authorStr, ",") {
-				parts := strings.SplitN(authorStr, ",", 2)
-				sname = strings.TrimSpace(parts[0])
-				fname = strings.TrimSpace(parts[1])
-			} else {
-				parts := strings.Fields(authorStr)
-				if len(parts) == 1 {
-					fname = parts[0]
-				} else if len(parts) >= 2 {
-					fname = parts[0]
-					sname = strings.Join(parts[1:], " ")
-				}
-			}
+	if bu.Contributors != nil || bu.Author != nil {
+		contributors, err := pbc.contributors(book, bu)
+		if errors.Is(err, errInvalidContributorRole) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid contributor role"})
+			return
 		}
-		if book.Author == nil {
-			book.Author = &models.Author{Firstname: fname, Surname: sname}
-		} else {
-			if fname != "" {
-				book.Author.Firstname = fname
-			}
-			if sname != "" {
-				book.Author.Surname = sname
-			}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Failed to save author"})
+			return
 		}
+
+		// The authors are found or created by name when the book is saved,
+		// the first author is kept as the author of the book.
+		book.Contributors = contributors
 	}
 	if bu.GenreID != nil {
 		if bu.GenreID.Val == nil {
@@ -530,9 +696,19 @@ func (pbc *BookController) UpdateBook(ctx *gin.Context) {
 	}
 	if bu.Price != nil {
 		if bu.Price.Val == nil {
-			book.Price = 0
+			book.Price.Amount = 0
 		} else {
-			book.Price = *bu.Price.Val
+			branch, err := repository.NewBranchRepository(pbc.DB).FindOne(*book.BranchID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+				return
+			}
+			price, err := bu.Price.Val.In(branch.Currency)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid price"})
+				return
+			}
+			book.Price = price.RoundToSteps(branch.Steps)
 		}
 	}
 	if bu.Sold != nil {
@@ -654,3 +830,96 @@ func (pbc *BookController) UpdateBook(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, updatedBook)
 }
+
+// contributors resolves the contributors of a book update. The
+// contributors of the update replace all contributors of the book, the
+// author, e.g. "Goethe, Johann Wolfgang; Friedrich Schiller", replaces its
+// authors only.
+func (pbc *BookController) contributors(book *models.Book, bu models.BookUpdate) ([]models.BookContributor, error) {
+	type entry struct {
+		name names.Name
+		role string
+	}
+
+	var entries []entry
+	var kept []models.BookContributor
+	if bu.Contributors != nil {
+		for _, c := range bu.Contributors {
+			role := c.Role
+			if role == "" {
+				role = models.ContributorAuthor
+			}
+			if !slices.Contains(models.ContributorRoles, role) {
+				return nil, errInvalidContributorRole
+			}
+			for _, name := range names.ParseList(c.Name) {
+				entries = append(entries, entry{name, role})
+			}
+		}
+	} else {
+		for _, name := range names.ParseList(*bu.Author) {
+			entries = append(entries, entry{name, models.ContributorAuthor})
+		}
+		if err := pbc.DB.Where("book_id = ? AND role <> ?", book.ID, models.ContributorAuthor).Order("position").Find(&kept).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	contributors := []models.BookContributor{}
+	for _, e := range entries {
+		contributors = append(contributors, models.BookContributor{
+			Author: &models.Author{Firstname: e.name.Firstname, Surname: e.name.Surname},
+			Role:   e.role,
+		})
+	}
+	for _, c := range kept {
+		contributors = append(contributors, models.BookContributor{AuthorID: c.AuthorID, Role: c.Role})
+	}
+
+	return contributors, nil
+}
+
+// SuggestedPrice calculates the price of a book from the price list of its branch.
+func (pbc *BookController) SuggestedPrice(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+	id := ctx.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid book id"})
+		return
+	}
+
+	book, err := pbc.Repo.FindByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"msg": "Book not found"})
+		return
+	}
+
+	if book.BranchID == nil || branchId != *book.BranchID {
+		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Invalid Branch"})
+		return
+	}
+
+	branch, err := repository.NewBranchRepository(pbc.DB).FindOne(*book.BranchID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		return
+	}
+
+	rules, err := repository.NewPriceRuleRepository(pbc.DB).FindAllByBranchID(branch.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		return
+	}
+
+	suggestion, ok := pricing.Suggest(book, rules, branch.Steps)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"msg": "No matching price rule"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, suggestion)
+}
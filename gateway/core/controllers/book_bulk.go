@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errBulkLimit is returned if a filter matches more books than allowed.
+var errBulkLimit = errors.New("too many books")
+
+// BulkBook applies one action to a list of books or to all books matching a filter.
+// All changes are written in one transaction and a result is returned for every book.
+func (pbc *BookController) BulkBook(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	var bulk models.BookBulk
+	if err := ctx.ShouldBindJSON(&bulk); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid JSON"})
+		return
+	}
+
+	if err := bulk.Validate(validator.New()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid bulk operation \n " + err.Error()})
+		return
+	}
+
+	if !auth.HasPermission(ctx, bulkPermission(bulk.Action)) {
+		ctx.JSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+		return
+	}
+
+	if msg, ok := pbc.checkBulkReferences(branchId, bulk); !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"msg": msg})
+		return
+	}
+
+	branch, err := repository.NewBranchRepository(pbc.DB).FindOne(branchId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		return
+	}
+
+	var results []models.BookBulkResult
+	err = pbc.DB.Transaction(func(tx *gorm.DB) error {
+		repo := repository.NewBookRepository(tx)
+
+		books, skipped, err := findBulkBooks(repo, branchId, bulk)
+		if err != nil {
+			return err
+		}
+		results = skipped
+
+		for i := range books {
+			book := &books[i]
+
+			applyBulkAction(book, bulk, branch.Steps)
+
+			if bulk.Action == models.BookBulkGenre {
+				existing, err := repo.FindDuplicate(book)
+				if err != nil {
+					return err
+				}
+				if existing != nil && existing.ID != book.ID {
+					results = append(results, models.BookBulkResult{ID: book.ID.String(), Status: "error", Msg: "Book exists already"})
+					continue
+				}
+			}
+
+			if err := repo.Update(book); err != nil {
+				return err
+			}
+
+			results = append(results, models.BookBulkResult{ID: book.ID.String(), Status: "ok"})
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errBulkLimit) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"msg": "Too many books, the limit is 1000"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Failed to update books"})
+		return
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Status == "ok" {
+			succeeded++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"action":    bulk.Action,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// bulkPermission returns the permission that the action of a bulk operation
+// requires.
+func bulkPermission(action string) string {
+	switch action {
+	case models.BookBulkSell:
+		return auth.PermBookSell
+	case models.BookBulkRemove, models.BookBulkRestore:
+		return auth.PermBookRemove
+	}
+	return auth.PermBookEdit
+}
+
+// checkBulkReferences ensures that genre, condition and tag of a bulk operation belong to the branch.
+func (pbc *BookController) checkBulkReferences(branchId uint, bulk models.BookBulk) (string, bool) {
+	switch bulk.Action {
+	case models.BookBulkGenre:
+		genre, err := repository.NewGenreRepository(pbc.DB).FindOne(*bulk.GenreID)
+		if err != nil || genre.BranchID != branchId {
+			return "Genre not found", false
+		}
+	case models.BookBulkCondition:
+		condition, err := repository.NewConditionRepository(pbc.DB).FindOneByID(*bulk.ConditionID)
+		if err != nil || condition.BranchID != branchId {
+			return "Condition not found", false
+		}
+	case models.BookBulkTagAdd, models.BookBulkTagRemove:
+		tag, err := repository.NewTagRepository(pbc.DB).FindOne(*bulk.TagID)
+		if err != nil || tag.BranchID != branchId {
+			return "Tag not found", false
+		}
+	}
+
+	return "", true
+}
+
+// findBulkBooks loads the books of a bulk operation. Requested books that
+// don't exist or belong to another branch are returned as failed results.
+func findBulkBooks(repo *repository.BookRepository, branchId uint, bulk models.BookBulk) ([]models.Book, []models.BookBulkResult, error) {
+	if len(bulk.IDs) == 0 {
+		books, err := repo.FindByFilter(branchId, *bulk.Filter, models.BookBulkLimit+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(books) > models.BookBulkLimit {
+			return nil, nil, errBulkLimit
+		}
+		return books, nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(bulk.IDs))
+	for _, id := range bulk.IDs {
+		ids = append(ids, uuid.MustParse(id))
+	}
+
+	found, err := repo.FindByIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uuid.UUID]models.Book, len(found))
+	for _, b := range found {
+		byID[b.ID] = b
+	}
+
+	var books []models.Book
+	var results []models.BookBulkResult
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		book, ok := byID[id]
+		if !ok {
+			results = append(results, models.BookBulkResult{ID: id.String(), Status: "error", Msg: "Book not found"})
+			continue
+		}
+		if book.BranchID == nil || *book.BranchID != branchId {
+			results = append(results, models.BookBulkResult{ID: id.String(), Status: "error", Msg: "Invalid Branch"})
+			continue
+		}
+		books = append(books, book)
+	}
+
+	return books, results, nil
+}
+
+// applyBulkAction changes the book according to the action of the bulk operation.
+// New prices are rounded to the steps of the branch.
+func applyBulkAction(book *models.Book, bulk models.BookBulk, steps models.Money) {
+	now := time.Now()
+
+	switch bulk.Action {
+	case models.BookBulkSell:
+		book.Sold = true
+		if book.SoldOn == nil {
+			book.SoldOn = &now
+		}
+		book.Reserved = false
+		book.ReservedAt = nil
+		book.Reservation = nil
+		book.ReservationID = nil
+	case models.BookBulkRemove:
+		book.Removed = true
+		if book.RemovedOn == nil {
+			book.RemovedOn = &now
+		}
+		book.Reserved = false
+		book.ReservedAt = nil
+	case models.BookBulkRestore:
+		book.Sold = false
+		book.SoldOn = nil
+		book.Removed = false
+		book.RemovedOn = nil
+	case models.BookBulkGenre:
+		v := *bulk.GenreID
+		book.GenreID = &v
+	case models.BookBulkCondition:
+		v := *bulk.ConditionID
+		book.ConditionID = &v
+	case models.BookBulkTagAdd:
+		for _, t := range book.Tags {
+			if t.ID == *bulk.TagID {
+				return
+			}
+		}
+		book.Tags = append(book.Tags, &models.Tag{ID: *bulk.TagID})
+	case models.BookBulkTagRemove:
+		tags := []*models.Tag{}
+		for _, t := range book.Tags {
+			if t.ID != *bulk.TagID {
+				tags = append(tags, t)
+			}
+		}
+		book.Tags = tags
+	case models.BookBulkReprice:
+		book.Price = book.Price.AddPercent(*bulk.Percentage).RoundToSteps(steps)
+	case models.BookBulkRecommendation:
+		if bulk.Recommendation != nil {
+			book.Recommendation = *bulk.Recommendation
+		} else {
+			book.Recommendation = !book.Recommendation
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/cover"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BookImageController handles the additional images of books.
+type BookImageController struct {
+	DB       *gorm.DB
+	Repo     *repository.BookImageRepository
+	BookRepo *repository.BookRepository
+}
+
+// NewBookImageController creates a new instance of BookImageController.
+func NewBookImageController(db *gorm.DB) *BookImageController {
+	return &BookImageController{
+		DB:       db,
+		Repo:     repository.NewBookImageRepository(db),
+		BookRepo: repository.NewBookRepository(db),
+	}
+}
+
+// List returns the images of a book.
+func (ic *BookImageController) List(ctx *gin.Context) {
+	book, ok := ic.findBook(ctx)
+	if !ok {
+		return
+	}
+
+	images, err := ic.Repo.FindByBook(book.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+// Create uploads a new image and appends it to the gallery of the book.
+// The caption is read from the form field caption.
+func (ic *BookImageController) Create(ctx *gin.Context) {
+	book, ok := ic.findBook(ctx)
+	if !ok {
+		return
+	}
+
+	data, fields, err := cover.ReadUpload(ctx, "image")
+	if err != nil {
+		cover.WriteError(ctx, err)
+		return
+	}
+
+	position, err := ic.Repo.NextPosition(book.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create image"})
+		return
+	}
+
+	image := models.BookImage{ID: uuid.New(), BookID: book.ID, Position: position, Caption: fields["caption"]}
+	if !image.Validate(ic.DB) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid caption", "code": "invalid_caption"})
+		return
+	}
+
+	if err := cover.SaveImage(data, book.ID, image.ID); err != nil {
+		cover.WriteError(ctx, err)
+		return
+	}
+
+	if err := ic.Repo.Create(&image); err != nil {
+		if err := cover.DeleteImage(book.ID, image.ID); err != nil {
+			log.Printf("failed to delete image %s: %v", image.ID, err)
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create image"})
+		return
+	}
+
+	image.SetURLs()
+	ctx.JSON(http.StatusCreated, image)
+}
+
+// Update changes the caption of an image.
+func (ic *BookImageController) Update(ctx *gin.Context) {
+	image, ok := ic.findImage(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Caption string `json:"caption"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	image.Caption = input.Caption
+	if !image.Validate(ic.DB) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid caption"})
+		return
+	}
+
+	if err := ic.Repo.Update(image); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update image"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, image)
+}
+
+// Reorder sets the order of the images. The list must contain every image
+// of the book exactly once.
+func (ic *BookImageController) Reorder(ctx *gin.Context) {
+	book, ok := ic.findBook(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		IDs []uuid.UUID `json:"ids" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	images, err := ic.Repo.FindByBook(book.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
+		return
+	}
+
+	known := make(map[uuid.UUID]bool, len(images))
+	for _, image := range images {
+		known[image.ID] = true
+	}
+	if len(input.IDs) != len(images) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "The order must contain every image of the book"})
+		return
+	}
+	for _, id := range input.IDs {
+		if !known[id] {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "The order must contain every image of the book"})
+			return
+		}
+		delete(known, id)
+	}
+
+	if err := ic.Repo.Reorder(book.ID, input.IDs); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder images"})
+		return
+	}
+
+	images, err = ic.Repo.FindByBook(book.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+// Delete removes an image and its files.
+func (ic *BookImageController) Delete(ctx *gin.Context) {
+	image, ok := ic.findImage(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ic.Repo.Delete(image); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		return
+	}
+
+	if err := cover.DeleteImage(image.BookID, image.ID); err != nil {
+		log.Printf("failed to delete image %s: %v", image.ID, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// findBook loads the book of the route and checks that it belongs to the
+// branch of the user.
+func (ic *BookImageController) findBook(ctx *gin.Context) (*models.Book, bool) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return nil, false
+	}
+
+	if _, err := uuid.Parse(ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid book id"})
+		return nil, false
+	}
+
+	book, err := ic.BookRepo.FindByID(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return nil, false
+	}
+
+	if book.BranchID == nil || branchId != *book.BranchID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Invalid Branch"})
+		return nil, false
+	}
+
+	return book, true
+}
+
+// findImage loads the image of the route.
+func (ic *BookImageController) findImage(ctx *gin.Context) (*models.BookImage, bool) {
+	book, ok := ic.findBook(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	imageID, err := uuid.Parse(ctx.Param("image"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid image id"})
+		return nil, false
+	}
+
+	image, err := ic.Repo.FindOne(book.ID, imageID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return nil, false
+	}
+
+	return image, true
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
 	"github.com/gin-gonic/gin"
@@ -21,7 +22,7 @@ type BranchController struct {
 func NewBranchController(db *gorm.DB) *BranchController {
 	return &BranchController{
 		repo: repository.NewBranchRepository(db),
-		v:    validator.New(),
+		v:    models.NewValidator(),
 	}
 }
 
@@ -36,6 +37,42 @@ func (c *BranchController) List(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, branches)
 }
 
+// Memberships returns the branches of the user with the roles in each. The
+// active branch is marked, another one is selected with the X-Branch-ID header.
+func (c *BranchController) Memberships(ctx *gin.Context) {
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+	activeId, _ := auth.BranchID(ctx)
+
+	branches, err := c.repo.FindAll()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load branches"})
+		return
+	}
+	byId := make(map[uint]models.Branch, len(branches))
+	for _, b := range branches {
+		byId[b.ID] = b
+	}
+
+	memberships := []gin.H{}
+	for _, m := range user.(auth.User).BranchMemberships() {
+		branch, ok := byId[uint(m.Branch.Id)]
+		if !ok {
+			continue
+		}
+		memberships = append(memberships, gin.H{
+			"branch": branch,
+			"roles":  m.Roles,
+			"active": branch.ID == activeId,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, memberships)
+}
+
 // Show returns one branch by id.
 func (c *BranchController) Show(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
@@ -67,9 +104,19 @@ func (c *BranchController) Update(ctx *gin.Context) {
 		return
 	}
 
-	branch.ID = uint(id)
+	existing, err := c.repo.FindOne(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
+		return
+	}
+
+	branch.ID = existing.ID
+	branch.Archived = existing.Archived
+	if branch.Archived {
+		branch.Public = false
+	}
 
-	if err := c.v.Struct(branch); err != nil {
+	if err := branch.Validate(c.v); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Branch not valid"})
 		return
 	}
@@ -81,3 +128,68 @@ func (c *BranchController) Update(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, branch)
 }
+
+// Create creates a branch, optionally with the genres, conditions and
+// formats of a template branch.
+func (c *BranchController) Create(ctx *gin.Context) {
+	var payload models.BranchCreate
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Bad request"})
+		return
+	}
+
+	branch := payload.Branch
+	branch.ID = 0
+	branch.Archived = false
+
+	if err := branch.Validate(c.v); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Branch not valid"})
+		return
+	}
+
+	if payload.TemplateID != nil {
+		if _, err := c.repo.FindOne(*payload.TemplateID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Template branch not found"})
+			return
+		}
+	}
+
+	if err := c.repo.Create(&branch, payload.TemplateID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Branch not created"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, branch)
+}
+
+// Archive archives a branch. It stays in the database but is not public
+// anymore.
+func (c *BranchController) Archive(ctx *gin.Context) {
+	c.setArchived(ctx, true)
+}
+
+// Restore restores an archived branch.
+func (c *BranchController) Restore(ctx *gin.Context) {
+	c.setArchived(ctx, false)
+}
+
+func (c *BranchController) setArchived(ctx *gin.Context, archived bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	branch, err := c.repo.FindOne(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
+		return
+	}
+
+	if err := c.repo.SetArchived(&branch, archived); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Branch not updated"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, branch)
+}
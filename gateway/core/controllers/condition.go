@@ -27,13 +27,13 @@ func NewConditionController(db *gorm.DB) *ConditionController {
 
 // FindAll retrieves all conditions for the authenticated user's branch.
 func (cc *ConditionController) FindAll(c *gin.Context) {
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	conditions, err := cc.ConditionRepo.FindAllByBranchID(uint(user.(auth.User).Branch.Id))
+	conditions, err := cc.ConditionRepo.FindAllByBranchID(branchId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conditions"})
 		return
@@ -67,12 +67,12 @@ func (cc *ConditionController) Create(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	condition.BranchID = uint(user.(auth.User).Branch.Id)
+	condition.BranchID = branchId
 
 	if !condition.Validate(cc.DB) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
@@ -104,14 +104,14 @@ func (cc *ConditionController) Update(c *gin.Context) {
 	condition.ID = uint(id)
 
 	// Retrieve user from context
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
 	// Check if the user's branch ID matches the condition's branch ID
-	if user.(auth.User).Branch.Id == int(condition.BranchID) {
+	if branchId == condition.BranchID {
 		if !condition.Validate(cc.DB) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
 			return
@@ -137,7 +137,7 @@ func (cc *ConditionController) Delete(c *gin.Context) {
 	}
 
 	// Retrieve user from context
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -151,7 +151,7 @@ func (cc *ConditionController) Delete(c *gin.Context) {
 	}
 
 	// Check if the user's branch ID matches the condition's branch ID
-	if user.(auth.User).Branch.Id == int(condition.BranchID) {
+	if branchId == condition.BranchID {
 		if err := cc.ConditionRepo.Delete(uint(id)); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete condition"})
 			return
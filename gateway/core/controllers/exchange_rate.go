@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/currency"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// ExchangeRateController handles the manually maintained exchange rates.
+type ExchangeRateController struct {
+	DB   *gorm.DB
+	Repo *repository.ExchangeRateRepository
+}
+
+// NewExchangeRateController creates a new ExchangeRateController.
+func NewExchangeRateController(db *gorm.DB) *ExchangeRateController {
+	return &ExchangeRateController{
+		DB:   db,
+		Repo: repository.NewExchangeRateRepository(db),
+	}
+}
+
+// FindAll retrieves all exchange rates.
+func (ec *ExchangeRateController) FindAll(c *gin.Context) {
+	rates, err := ec.Repo.FindAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// Save creates an exchange rate or updates the rate of an existing currency pair.
+func (ec *ExchangeRateController) Save(c *gin.Context) {
+	var rate models.ExchangeRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	rate.ID = 0
+	rate.Base = strings.ToUpper(strings.TrimSpace(rate.Base))
+	rate.Quote = strings.ToUpper(strings.TrimSpace(rate.Quote))
+
+	if !rate.Validate(ec.DB) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
+		return
+	}
+
+	if err := ec.Repo.Save(&rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// Delete deletes an exchange rate by ID.
+func (ec *ExchangeRateController) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if _, err := ec.Repo.FindOne(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+
+	if err := ec.Repo.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Import reads the local rates file configured in EXCHANGE_RATES_FILE and
+// saves all of its rates.
+func (ec *ExchangeRateController) Import(c *gin.Context) {
+	viper.SetDefault("EXCHANGE_RATES_FILE", "rates.json")
+
+	file, err := os.Open(viper.GetString("EXCHANGE_RATES_FILE"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rates file not found"})
+		return
+	}
+	defer file.Close()
+
+	ratesFile, err := currency.ReadRatesFile(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rates := make([]models.ExchangeRate, 0, len(ratesFile.Rates))
+	for quote, rate := range ratesFile.Rates {
+		rates = append(rates, models.ExchangeRate{Base: ratesFile.Base, Quote: quote, Rate: rate})
+	}
+
+	if err := ec.Repo.SaveAll(rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"msg": "Exchange rates imported", "imported": len(rates)})
+}
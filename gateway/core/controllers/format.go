@@ -27,13 +27,13 @@ func NewFormatController(db *gorm.DB) *FormatController {
 
 // FindAll retrieves all formats for the authenticated user's branch.
 func (fc *FormatController) FindAll(c *gin.Context) {
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	formats, err := fc.formatRepo.FindAllByBranchID(uint(user.(auth.User).Branch.Id))
+	formats, err := fc.formatRepo.FindAllByBranchID(branchId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve formats"})
 		return
@@ -72,12 +72,12 @@ func (fc *FormatController) Create(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	format.BranchID = uint(user.(auth.User).Branch.Id)
+	format.BranchID = branchId
 
 	if !format.Validate(fc.db) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
@@ -113,13 +113,13 @@ func (fc *FormatController) Update(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	if uint(user.(auth.User).Branch.Id) != existingFormat.BranchID {
+	if branchId != existingFormat.BranchID {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -160,7 +160,7 @@ func (fc *FormatController) Delete(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -176,7 +176,7 @@ func (fc *FormatController) Delete(c *gin.Context) {
 		return
 	}
 
-	if uint(user.(auth.User).Branch.Id) != format.BranchID {
+	if branchId != format.BranchID {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -27,13 +28,13 @@ func NewGenreController(db *gorm.DB) *GenreController {
 
 // FindAll retrieves all genres.
 func (gc *GenreController) FindAll(c *gin.Context) {
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	genres, err := gc.GenreRepo.FindAllByBranchID(uint(user.(auth.User).Branch.Id))
+	genres, err := gc.GenreRepo.FindAllByBranchID(branchId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve genres"})
 		return
@@ -42,6 +43,23 @@ func (gc *GenreController) FindAll(c *gin.Context) {
 	c.JSON(http.StatusOK, genres)
 }
 
+// Tree retrieves the genres as a tree with the number of books in stock.
+func (gc *GenreController) Tree(c *gin.Context) {
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	tree, err := gc.GenreRepo.FindTree(branchId, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve genres"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
 // FindOne retrieves a specific genre by ID.
 func (gc *GenreController) FindOne(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -67,18 +85,23 @@ func (gc *GenreController) Create(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	genre.BranchID = uint(user.(auth.User).Branch.Id)
+	genre.BranchID = branchId
 
 	if !genre.Validate(gc.DB) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
 		return
 	}
 
+	if genre.ParentID != nil && !gc.inBranch(*genre.ParentID, branchId) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent"})
+		return
+	}
+
 	if err := gc.GenreRepo.Create(&genre); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create genre"})
 		return
@@ -107,7 +130,7 @@ func (gc *GenreController) Update(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -119,7 +142,7 @@ func (gc *GenreController) Update(c *gin.Context) {
 		return
 	}
 
-	if uint(user.(auth.User).Branch.Id) != existingGenre.BranchID {
+	if branchId != existingGenre.BranchID {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -139,7 +162,61 @@ func (gc *GenreController) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, existingGenre)
 }
 
-// Delete deletes a genre by ID.
+// Move moves a genre below another genre of the branch or to the top. A
+// genre can't be moved below itself or one of its subgenres.
+func (gc *GenreController) Move(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var move models.GenreMove
+	if err := c.ShouldBindJSON(&move); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	genre, err := gc.GenreRepo.FindOne(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+		return
+	}
+
+	if branchId != genre.BranchID {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+		return
+	}
+
+	if move.ParentID != nil && !gc.inBranch(*move.ParentID, branchId) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent"})
+		return
+	}
+
+	err = gc.GenreRepo.Move(genre.ID, move.ParentID)
+	switch {
+	case errors.Is(err, repository.ErrGenreCycle):
+		c.JSON(http.StatusConflict, gin.H{"error": "Genre can't be moved below itself"})
+		return
+	case errors.Is(err, repository.ErrGenreExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Genre already exists"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move genre"})
+		return
+	}
+	genre.ParentID = move.ParentID
+
+	c.JSON(http.StatusOK, genre)
+}
+
+// Delete deletes a genre by ID. Its subgenres and books move to its parent.
 func (gc *GenreController) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -147,7 +224,7 @@ func (gc *GenreController) Delete(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -159,15 +236,25 @@ func (gc *GenreController) Delete(c *gin.Context) {
 		return
 	}
 
-	if uint(user.(auth.User).Branch.Id) != genre.BranchID {
+	if branchId != genre.BranchID {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
 
 	if err := gc.GenreRepo.Delete(uint(id)); err != nil {
+		if errors.Is(err, repository.ErrGenreExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A subgenre exists already in the parent genre"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete genre"})
 		return
 	}
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// inBranch tells whether the genre exists in the branch.
+func (gc *GenreController) inBranch(id uint, branchID uint) bool {
+	genre, err := gc.GenreRepo.FindOne(id)
+	return err == nil && genre.BranchID == branchID
+}
@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/abaldeweg/warehouse-server/gateway/auth"
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/export"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -24,13 +28,13 @@ func NewInventoryController(db *gorm.DB) *InventoryController {
 
 // List lists all inventory items.
 func (ctrl *InventoryController) List(c *gin.Context) {
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	inventories, err := ctrl.Repo.FindAllByBranch(uint(user.(auth.User).Branch.Id))
+	inventories, err := ctrl.Repo.FindAllByBranch(branchId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
 		return
@@ -52,13 +56,13 @@ func (ctrl *InventoryController) Show(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	if inventory.BranchID != uint(user.(auth.User).Branch.Id) {
+	if inventory.BranchID != branchId {
 		c.JSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -70,14 +74,14 @@ func (ctrl *InventoryController) Show(c *gin.Context) {
 func (ctrl *InventoryController) Create(c *gin.Context) {
 	inventory := models.NewInventory()
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	inventory.BranchID = uint(user.(auth.User).Branch.Id)
+	inventory.BranchID = branchId
 
-	activeCount, err := ctrl.Repo.FindActive(uint(user.(auth.User).Branch.Id))
+	activeCount, err := ctrl.Repo.FindActive(branchId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
 		return
@@ -110,14 +114,15 @@ func (ctrl *InventoryController) Update(c *gin.Context) {
 	}
 
 	var jsonBody struct {
-		EndedAt float64 `json:"endedAt"`
+		EndedAt            float64 `json:"endedAt"`
+		UnscannedAsMissing bool    `json:"unscannedAsMissing"`
 	}
 	if err := c.ShouldBindJSON(&jsonBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid JSON"})
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -129,29 +134,122 @@ func (ctrl *InventoryController) Update(c *gin.Context) {
 		return
 	}
 
-	if existingInventory.BranchID != uint(user.(auth.User).Branch.Id) {
+	if existingInventory.BranchID != branchId {
 		c.JSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
 
+	if existingInventory.EndedAtTimestamp != nil {
+		c.JSON(http.StatusConflict, gin.H{"msg": "Inventory is closed already"})
+		return
+	}
+
 	endedAt := int64(jsonBody.EndedAt)
 	existingInventory.EndedAtTimestamp = &endedAt
 
-	if err := ctrl.Repo.Update(existingInventory); err != nil {
+	rr := repository.NewInventoryReportRepository(ctrl.DB)
+	if _, err := rr.Reconcile(existingInventory, jsonBody.UnscannedAsMissing); err != nil {
+		if errors.Is(err, repository.ErrInventoryClosed) {
+			c.JSON(http.StatusConflict, gin.H{"msg": "Inventory is closed already"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Failed to reconcile inventory"})
+		return
+	}
+	c.JSON(http.StatusOK, existingInventory)
+}
+
+// Report shows the reconciliation report of a closed inventory.
+// The report can be exported with the query parameter format=csv or format=pdf.
+func (ctrl *InventoryController) Report(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid ID"})
+		return
+	}
+
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	inventory, err := ctrl.Repo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"msg": "Not Found"})
+		return
+	}
+
+	if inventory.BranchID != branchId {
+		c.JSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+		return
+	}
+
+	rr := repository.NewInventoryReportRepository(ctrl.DB)
+	report, err := rr.FindByInventoryID(inventory.ID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"msg": "Report not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
 		return
 	}
 
-  if existingInventory.EndedAtTimestamp != nil {
-		br := repository.NewBookRepository(ctrl.DB)
-		if err := br.RemoveNotFoundBooks(uint(user.(auth.User).Branch.Id)); err != nil {
+	filename := fmt.Sprintf("inventory-%d", inventory.ID)
+
+	switch c.Query("format") {
+	case "", "json":
+		c.JSON(http.StatusOK, report)
+	case "csv":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Header("Content-Type", export.ContentTypeCSV)
+		c.Status(http.StatusOK)
+		if err := export.WriteCSV(c.Writer, inventoryReportTable(inventory, report)); err != nil {
 			c.Error(err)
 		}
-		if err := br.ResetInventory(uint(user.(auth.User).Branch.Id)); err != nil {
+	case "pdf":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.pdf"`)
+		c.Header("Content-Type", export.ContentTypePDF)
+		c.Status(http.StatusOK)
+		if err := export.WritePDF(c.Writer, inventoryReportTable(inventory, report)); err != nil {
 			c.Error(err)
 		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid format"})
 	}
-	c.JSON(http.StatusOK, existingInventory)
+}
+
+// inventoryReportTable converts a report into an exportable table.
+func inventoryReportTable(inventory *models.Inventory, report *models.InventoryReport) export.Table {
+	currency := ""
+	if inventory.Branch != nil {
+		currency = " " + inventory.Branch.Currency
+	}
+
+	table := export.Table{
+		Title: fmt.Sprintf("Inventory %d", inventory.ID),
+		Lines: []string{
+			"Started: " + inventory.StartedAt.Format(time.DateTime),
+			"Closed: " + report.CreatedAt.Format(time.DateTime),
+			fmt.Sprintf("Found: %d, Not found: %d, Unscanned: %d, Removed: %d", inventory.Found, inventory.NotFound, report.Unscanned, report.Removed),
+			fmt.Sprintf("Stock value before: %s%s, after: %s%s", report.ValueBefore, currency, report.ValueAfter, currency),
+		},
+		Header: []string{"id", "title", "author", "price", "status", "removed"},
+	}
+
+	for _, item := range report.Items {
+		table.Rows = append(table.Rows, []string{
+			item.BookID.String(),
+			item.Title,
+			item.Author,
+			item.Price.String(),
+			item.Status,
+			strconv.FormatBool(item.Removed),
+		})
+	}
+
+	return table
 }
 
 // Delete deletes an inventory item by ID.
@@ -168,13 +266,13 @@ func (ctrl *InventoryController) Delete(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	if inventory.BranchID != uint(user.(auth.User).Branch.Id) {
+	if inventory.BranchID != branchId {
 		c.JSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/pricing"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PriceRuleController handles the structured price list of a branch.
+type PriceRuleController struct {
+	DB   *gorm.DB
+	Repo *repository.PriceRuleRepository
+}
+
+// NewPriceRuleController creates a new PriceRuleController.
+func NewPriceRuleController(db *gorm.DB) *PriceRuleController {
+	return &PriceRuleController{
+		DB:   db,
+		Repo: repository.NewPriceRuleRepository(db),
+	}
+}
+
+// FindAll retrieves all price rules of the branch.
+func (pc *PriceRuleController) FindAll(c *gin.Context) {
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	rules, err := pc.Repo.FindAllByBranchID(branchId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve price rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// FindOne retrieves a price rule by ID.
+func (pc *PriceRuleController) FindOne(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	rule, err := pc.Repo.FindOne(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price rule not found"})
+		return
+	}
+
+	if branchId != rule.BranchID {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// Create creates a new price rule.
+func (pc *PriceRuleController) Create(c *gin.Context) {
+	var rule models.PriceRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+	rule.BranchID = branchId
+
+	if !rule.Validate(pc.DB) || !pc.hasValidReferences(&rule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
+		return
+	}
+
+	if err := pc.Repo.Create(&rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// Update updates an existing price rule.
+func (pc *PriceRuleController) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var rule models.PriceRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	existingRule, err := pc.Repo.FindOne(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price rule not found"})
+		return
+	}
+
+	if branchId != existingRule.BranchID {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+		return
+	}
+
+	rule.ID = existingRule.ID
+	rule.BranchID = existingRule.BranchID
+
+	if !rule.Validate(pc.DB) || !pc.hasValidReferences(&rule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
+		return
+	}
+
+	if err := pc.Repo.Update(&rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update price rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// Delete deletes a price rule by ID.
+func (pc *PriceRuleController) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	rule, err := pc.Repo.FindOne(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price rule not found"})
+		return
+	}
+
+	if branchId != rule.BranchID {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+		return
+	}
+
+	if err := pc.Repo.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete price rule"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Preview calculates the prices of all books in stock and lists the books
+// whose price would change. Nothing is saved. The query parameter genre
+// limits the preview to a genre and its subgenres.
+func (pc *PriceRuleController) Preview(c *gin.Context) {
+	branchId, ok := auth.BranchID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	sold, removed := false, false
+	filter := models.BookBulkFilter{Sold: &sold, Removed: &removed}
+	if g := c.Query("genre"); g != "" {
+		genreId, err := strconv.Atoi(g)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre"})
+			return
+		}
+		v := uint(genreId)
+		filter.GenreID = &v
+	}
+
+	branch, err := repository.NewBranchRepository(pc.DB).FindOne(branchId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
+		return
+	}
+
+	rules, err := pc.Repo.FindAllByBranchID(branchId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve price rules"})
+		return
+	}
+
+	books, err := repository.NewBookRepository(pc.DB).FindByFilter(branchId, filter, -1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve books"})
+		return
+	}
+
+	type previewItem struct {
+		ID             string             `json:"id"`
+		Title          string             `json:"title"`
+		Price          models.Money       `json:"price"`
+		SuggestedPrice models.Money       `json:"suggestedPrice"`
+		Suggestion     pricing.Suggestion `json:"suggestion"`
+	}
+
+	items := []previewItem{}
+	unmatched := 0
+	var valueBefore, valueAfter models.Money
+	for i := range books {
+		book := &books[i]
+		valueBefore = valueBefore.Add(book.Price)
+
+		s, ok := pricing.Suggest(book, rules, branch.Steps)
+		if !ok {
+			unmatched++
+			valueAfter = valueAfter.Add(book.Price)
+			continue
+		}
+		valueAfter = valueAfter.Add(s.Price)
+
+		if s.Price != book.Price {
+			items = append(items, previewItem{
+				ID:             book.ID.String(),
+				Title:          book.Title,
+				Price:          book.Price,
+				SuggestedPrice: s.Price,
+				Suggestion:     s,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"books":       len(books),
+		"changed":     len(items),
+		"unmatched":   unmatched,
+		"valueBefore": valueBefore,
+		"valueAfter":  valueAfter,
+		"items":       items,
+	})
+}
+
+// hasValidReferences ensures that format, condition and genre of the rule belong to its branch.
+func (pc *PriceRuleController) hasValidReferences(rule *models.PriceRule) bool {
+	if rule.FormatID != nil {
+		format, err := repository.NewFormatRepository(pc.DB).FindOne(*rule.FormatID)
+		if err != nil || format.BranchID != rule.BranchID {
+			return false
+		}
+	}
+	if rule.ConditionID != nil {
+		condition, err := repository.NewConditionRepository(pc.DB).FindOneByID(*rule.ConditionID)
+		if err != nil || condition.BranchID != rule.BranchID {
+			return false
+		}
+	}
+	if rule.GenreID != nil {
+		genre, err := repository.NewGenreRepository(pc.DB).FindOne(*rule.GenreID)
+		if err != nil || genre.BranchID != rule.BranchID {
+			return false
+		}
+	}
+	return true
+}
@@ -2,12 +2,13 @@ package controllers
 
 import (
 	"net/http"
-	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/cover"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -36,7 +37,7 @@ func (pbc *PublicBookController) Show(c *gin.Context) {
 	}
 
 	var book models.PublicBook
-	if err := pbc.DB.Preload("Branch").Preload("Genre").Preload("Condition").Preload("Format").Preload("Author").First(&book, "id = ?", id).Error; err != nil {
+	if err := pbc.DB.Preload("Branch").Preload("Genre").Preload("Condition").Preload("Format").Preload("Author").Scopes(models.PreloadContributors).Preload("Images", orderImages).First(&book, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"msg": "Book not found"})
 		} else {
@@ -45,12 +46,16 @@ func (pbc *PublicBookController) Show(c *gin.Context) {
 		return
 	}
 
-	if book.Sold || book.Removed || book.Reserved {
+	if book.Sold || book.Removed || book.Reserved || book.BranchID == nil {
 		c.JSON(http.StatusNotFound, gin.H{"msg": "Book not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, book)
+	books := []models.PublicBook{book}
+	localizePrices(c, pbc.DB, books)
+	inlineCovers(c, books)
+
+	c.JSON(http.StatusOK, books[0])
 }
 
 // Recommendation retrieves recommended books for a specific branch.
@@ -68,39 +73,138 @@ func (pbc *PublicBookController) Recommendation(c *gin.Context) {
 	}
 
 	var books []models.PublicBook
-	if err := pbc.DB.Preload("Branch").Preload("Genre").Preload("Condition").Preload("Format").Preload("Author").Where("branch_id = ? AND sold = ? AND removed = ? AND reserved = ? AND recommendation = ?", branchID, false, false, false, true).Find(&books).Error; err != nil {
+	if err := pbc.DB.Preload("Branch").Preload("Genre").Preload("Condition").Preload("Format").Preload("Author").Scopes(models.PreloadContributors).Preload("Images", orderImages).Where("branch_id = ? AND sold = ? AND removed = ? AND reserved = ? AND recommendation = ?", branchID, false, false, false, true).Find(&books).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal server error"})
 		return
 	}
 
+	localizePrices(c, pbc.DB, books)
+	inlineCovers(c, books)
+
 	c.JSON(http.StatusOK, gin.H{"books": books, "counter": len(books)})
 }
 
-// Image retrieves the cover image of a book by its ID and dimensions.
-func (pbc *PublicBookController) Image(c *gin.Context) {
-	image := c.Param("image")
-	id := strings.Split(image, "_")[0]
-	dimensions := strings.Split(strings.Split(image, "_")[1], ".")[0]
+// Search searches the available books of all public branches. The query
+// parameter term filters by title, subtitle and author, branches by a comma
+// separated list of branch IDs, genre by a genre and its subgenres, which
+// limits the search to the branch of the genre, and limit sets the books
+// shown per branch.
+func (pbc *PublicBookController) Search(c *gin.Context) {
+	var branchIDs []uint
+	if branches := c.Query("branches"); branches != "" {
+		for _, id := range strings.Split(branches, ",") {
+			branchID, err := strconv.ParseUint(strings.TrimSpace(id), 10, 0)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid branch ID"})
+				return
+			}
+			branchIDs = append(branchIDs, uint(branchID))
+		}
+	}
 
-	if _, err := uuid.Parse(id); err != nil {
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid limit"})
+			return
+		}
+	}
+
+	var genreIDs []uint
+	if genre := c.Query("genre"); genre != "" {
+		genreID, err := strconv.ParseUint(genre, 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid genre"})
+			return
+		}
+		// A genre belongs to one branch, only that branch is searched.
+		genres := repository.NewGenreRepository(pbc.DB)
+		genre, err := genres.FindOne(uint(genreID))
+		if err != nil || !genre.Branch.Public || genre.Branch.Archived || (len(branchIDs) > 0 && !slices.Contains(branchIDs, genre.BranchID)) {
+			c.JSON(http.StatusNotFound, gin.H{"msg": "Genre not found"})
+			return
+		}
+		if genreIDs, err = genres.FindDescendantIDs(genre.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal server error"})
+			return
+		}
+		branchIDs = []uint{genre.BranchID}
+	}
+
+	results, err := pbc.Repo.Search(c.Query("term"), branchIDs, genreIDs, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal server error"})
+		return
+	}
+
+	var counter int64
+	for i := range results {
+		localizePrices(c, pbc.DB, results[i].Books)
+		inlineCovers(c, results[i].Books)
+		counter += results[i].Counter
+	}
+
+	c.JSON(http.StatusOK, gin.H{"branches": results, "counter": counter})
+}
+
+// Image retrieves the cover image of a book by its ID and dimensions, e.g.
+// {uuid}_400x0.jpg. A height of zero keeps the aspect ratio, otherwise the
+// cover is cropped. The format is negotiated with the Accept header.
+func (pbc *PublicBookController) Image(c *gin.Context) {
+	id, dimensions, ok := strings.Cut(c.Param("image"), "_")
+	if _, err := uuid.Parse(id); !ok || err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid book ID format"})
 		return
 	}
 
-	width, err := strconv.Atoi(strings.Split(dimensions, "x")[0])
-	if err != nil {
+	dimensions, _, _ = strings.Cut(dimensions, ".")
+	w, h, _ := strings.Cut(dimensions, "x")
+
+	width, err := strconv.Atoi(w)
+	if err != nil || width <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid dimensions format"})
 		return
 	}
 
-	size := "s"
-	if width >= 200 {
-		size = "m"
+	height := 0
+	if h != "" {
+		if height, err = strconv.Atoi(h); err != nil || height < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid dimensions format"})
+			return
+		}
+	}
+
+	cover.ServeResized(c, id, width, height)
+}
+
+// GalleryImage retrieves an additional image of a book by its key, e.g.
+// {book uuid}-{image uuid}-l.jpg.
+func (pbc *PublicBookController) GalleryImage(c *gin.Context) {
+	key := c.Param("image")
+	if _, _, _, ok := cover.ParseImageKey(key); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid image format"})
+		return
 	}
-	if width >= 400 {
-		size = "l"
+
+	cover.Serve(c, key)
+}
+
+// orderImages sorts preloaded book images by their position.
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position asc, created_at asc")
+}
+
+// inlineCovers replaces the cover URLs with base64 data URIs if the query
+// parameter inlineCovers is set to true.
+func inlineCovers(c *gin.Context, books []models.PublicBook) {
+	if c.Query("inlineCovers") != "true" {
+		return
 	}
 
-	filePath := filepath.Join("uploads", id+"-"+size+".jpg")
-	c.FileAttachment(filePath, id+"-"+size+".jpg")
+	for i := range books {
+		books[i].CoverS = cover.ShowCover("s", books[i].ID)
+		books[i].CoverM = cover.ShowCover("m", books[i].ID)
+		books[i].CoverL = cover.ShowCover("l", books[i].ID)
+	}
 }
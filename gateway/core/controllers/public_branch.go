@@ -3,8 +3,11 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/schedule"
 	"gorm.io/gorm"
 
 	"github.com/gin-gonic/gin"
@@ -33,7 +36,7 @@ func (ctrl *PublicBranchController) GetBranches(c *gin.Context) {
 	c.JSON(http.StatusOK, branches)
 }
 
-// GetBranch retrieves a branch by its ID.
+// GetBranch retrieves a branch by its ID and tells whether it is open now.
 func (ctrl *PublicBranchController) GetBranch(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -52,5 +55,11 @@ func (ctrl *PublicBranchController) GetBranch(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, branch)
+	c.JSON(http.StatusOK, struct {
+		models.Branch
+		OpenNow bool `json:"open_now"`
+	}{
+		Branch:  branch,
+		OpenNow: schedule.IsOpen(branch.Schedule, time.Now()),
+	})
 }
@@ -25,7 +25,8 @@ func NewPublicGenreController(db *gorm.DB) *PublicGenreController {
 	}
 }
 
-// FindAll retrieves all genres by the given branch ID.
+// FindAll retrieves the genres of the given branch ID as a tree with the
+// number of available books.
 func (gc *PublicGenreController) FindAll(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -50,11 +51,11 @@ func (gc *PublicGenreController) FindAll(c *gin.Context) {
 		return
 	}
 
-	genres, err := gc.GenreRepo.FindAllByBranchID(uint(branchID))
+	tree, err := gc.GenreRepo.FindTree(uint(branchID), true)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve genres"})
 		return
 	}
 
-	c.JSON(http.StatusOK, genres)
+	c.JSON(http.StatusOK, tree)
 }
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/currency"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// localizePrices formats the prices of public books for the locale of the
+// visitor (query parameter locale or the Accept-Language header). If the
+// query parameter currency is set, the prices are converted into it as well.
+func localizePrices(c *gin.Context, db *gorm.DB, books []models.PublicBook) {
+	locale := currency.ParseLocale(c.Query("locale"))
+	if c.Query("locale") == "" {
+		locale = currency.ParseLocale(c.GetHeader("Accept-Language"))
+	}
+
+	var target *currency.Currency
+	var rates *currency.Rates
+	if code := c.Query("currency"); code != "" {
+		if cur, err := currency.Parse(code); err == nil {
+			target = &cur
+			rates = requestRates(c, db)
+		}
+	}
+
+	for i := range books {
+		book := &books[i]
+
+		cur, err := currency.Parse(book.Currency)
+		if err != nil {
+			continue
+		}
+
+		book.PriceFormatted = cur.Format(book.Price.Amount, locale)
+
+		if target == nil || target.Code == cur.Code {
+			continue
+		}
+
+		rate, ok := rates.Rate(cur.Code, target.Code)
+		if !ok {
+			continue
+		}
+
+		price, _ := rates.Convert(book.Price.Amount, cur, *target)
+		book.Converted = &models.PublicPrice{
+			Currency:       target.Code,
+			Price:          models.NewMoney(price, target.Code),
+			PriceFormatted: target.Format(price, locale),
+			Rate:           rate,
+		}
+	}
+}
+
+// requestRates loads the exchange rates once per request.
+func requestRates(c *gin.Context, db *gorm.DB) *currency.Rates {
+	const key = "exchange_rates"
+	if rates, ok := c.Get(key); ok {
+		return rates.(*currency.Rates)
+	}
+
+	rates := loadRates(db)
+	c.Set(key, rates)
+	return rates
+}
+
+// loadRates loads all exchange rates. Errors result in an empty set of rates.
+func loadRates(db *gorm.DB) *currency.Rates {
+	rates := currency.NewRates()
+
+	list, err := repository.NewExchangeRateRepository(db).FindAll()
+	if err != nil {
+		return rates
+	}
+
+	for _, r := range list {
+		rates.Set(r.Base, r.Quote, r.Rate)
+	}
+
+	return rates
+}
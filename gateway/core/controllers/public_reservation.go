@@ -54,7 +54,8 @@ func (rc *PublicReservationController) Create(c *gin.Context) {
 		}
 		var book models.Book
 		if err := rc.db.Preload("Branch").First(&book, "id = ?", bookID).Error; err == nil {
-			if book.Sold || book.Removed || book.Reserved {
+			// books in transit belong to no branch until received
+			if book.Sold || book.Removed || book.Reserved || book.BranchID == nil || book.TransferID != nil {
 				continue
 			}
 			booksToUpdate = append(booksToUpdate, &book)
@@ -64,6 +65,9 @@ func (rc *PublicReservationController) Create(c *gin.Context) {
 
 	if len(reservation.Books) > 0 && reservation.Books[0].BranchID != nil {
 		reservation.BranchID = *reservation.Books[0].BranchID
+		if reservation.Books[0].Branch != nil {
+			reservation.PickupUntil = pickupUntil(*reservation.Books[0].Branch, reservation.CreatedAt)
+		}
 	}
 
 	if !reservation.Validate(rc.db) {
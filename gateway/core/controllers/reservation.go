@@ -8,6 +8,7 @@ import (
 	"github.com/abaldeweg/warehouse-server/gateway/auth"
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/abaldeweg/warehouse-server/gateway/schedule"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -29,13 +30,13 @@ func NewReservationController(db *gorm.DB) *ReservationController {
 
 // FindAll retrieves all reservations for the current user's branch.
 func (rc *ReservationController) FindAll(c *gin.Context) {
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	reservations, err := rc.reservationRepo.FindAll(uint(user.(auth.User).Branch.Id))
+	reservations, err := rc.reservationRepo.FindAll(branchId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
@@ -46,13 +47,13 @@ func (rc *ReservationController) FindAll(c *gin.Context) {
 
 // ReservationStatus retrieves the number of open reservations for the current user's branch.
 func (rc *ReservationController) ReservationStatus(c *gin.Context) {
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	count, err := rc.reservationRepo.ReservationStatus(uint(user.(auth.User).Branch.Id))
+	count, err := rc.reservationRepo.ReservationStatus(branchId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
@@ -69,7 +70,7 @@ func (rc *ReservationController) FindOne(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -85,7 +86,7 @@ func (rc *ReservationController) FindOne(c *gin.Context) {
 		return
 	}
 
-	if uint(user.(auth.User).Branch.Id) != reservation.BranchID {
+	if branchId != reservation.BranchID {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -121,7 +122,7 @@ func (rc *ReservationController) Create(c *gin.Context) {
 		}
 		var book models.Book
 		if err := rc.db.First(&book, "id = ?", bookID).Error; err == nil {
-			if book.Sold || book.Removed || book.Reserved {
+			if book.Sold || book.Removed || book.Reserved || book.BranchID == nil || book.TransferID != nil {
 				continue
 			}
 			book.Reserved = true
@@ -136,12 +137,16 @@ func (rc *ReservationController) Create(c *gin.Context) {
 		}
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	reservation.BranchID = uint(user.(auth.User).Branch.Id)
+	reservation.BranchID = branchId
+
+	if branch, err := repository.NewBranchRepository(rc.db).FindOne(branchId); err == nil {
+		reservation.PickupUntil = pickupUntil(branch, reservation.CreatedAt)
+	}
 
 	if !reservation.Validate(rc.db) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation data"})
@@ -176,13 +181,13 @@ func (rc *ReservationController) Update(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	if uint(user.(auth.User).Branch.Id) != existingReservation.BranchID {
+	if branchId != existingReservation.BranchID {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -194,15 +199,16 @@ func (rc *ReservationController) Update(c *gin.Context) {
 	}
 
 	reservation := models.Reservation{
-		ID:         existingReservation.ID,
-		CreatedAt:  existingReservation.CreatedAt,
-		Notes:      reservationForm.Notes,
-		Salutation: reservationForm.Salutation,
-		Firstname:  reservationForm.Firstname,
-		Surname:    reservationForm.Surname,
-		Mail:       reservationForm.Mail,
-		Phone:      reservationForm.Phone,
-		Open:       reservationForm.Open,
+		ID:          existingReservation.ID,
+		CreatedAt:   existingReservation.CreatedAt,
+		PickupUntil: existingReservation.PickupUntil,
+		Notes:       reservationForm.Notes,
+		Salutation:  reservationForm.Salutation,
+		Firstname:   reservationForm.Firstname,
+		Surname:     reservationForm.Surname,
+		Mail:        reservationForm.Mail,
+		Phone:       reservationForm.Phone,
+		Open:        reservationForm.Open,
 	}
 
 	// reservation.Books = make([]*models.Book, 0)
@@ -220,7 +226,7 @@ func (rc *ReservationController) Update(c *gin.Context) {
 	// 	}
 	// }
 
-	reservation.BranchID = uint(user.(auth.User).Branch.Id)
+	reservation.BranchID = branchId
 
 	if !reservation.Validate(rc.db) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation data"})
@@ -251,7 +257,7 @@ func (rc *ReservationController) Delete(c *gin.Context) {
 		return
 	}
 
-	user, ok := c.Get("user")
+	branchId, ok := auth.BranchID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -263,7 +269,7 @@ func (rc *ReservationController) Delete(c *gin.Context) {
 		return
 	}
 
-	if uint(user.(auth.User).Branch.Id) != existingReservation.BranchID {
+	if branchId != existingReservation.BranchID {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 		return
 	}
@@ -283,3 +289,13 @@ func (rc *ReservationController) Delete(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// pickupUntil returns until when the branch holds books reserved at the
+// time, nil if it sets no deadline.
+func pickupUntil(branch models.Branch, t time.Time) *time.Time {
+	deadline, ok := schedule.PickupDeadline(branch.Schedule, t)
+	if !ok {
+		return nil
+	}
+	return &deadline
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RoleController represents a role controller.
+type RoleController struct {
+	DB   *gorm.DB
+	Repo *repository.RoleRepository
+}
+
+// NewRoleController creates a new role controller.
+func NewRoleController(db *gorm.DB) *RoleController {
+	return &RoleController{
+		DB:   db,
+		Repo: repository.NewRoleRepository(db),
+	}
+}
+
+// FindAll lists the roles configured by the branch.
+func (rc *RoleController) FindAll(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	roles, err := rc.Repo.FindAllByBranchID(branchId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+// Permissions lists all permissions, the default roles and the permissions
+// of the user in the active branch.
+func (rc *RoleController) Permissions(ctx *gin.Context) {
+	granted := []string{}
+	for _, p := range auth.AllPermissions {
+		if auth.HasPermission(ctx, p) {
+			granted = append(granted, p)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"permissions": auth.AllPermissions,
+		"defaults":    auth.DefaultRoles,
+		"granted":     granted,
+	})
+}
+
+// Create creates a role in the branch.
+func (rc *RoleController) Create(ctx *gin.Context) {
+	var role models.Role
+	if err := ctx.ShouldBindJSON(&role); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+	role.ID = 0
+	role.BranchID = branchId
+
+	if !rc.validate(ctx, &role) {
+		return
+	}
+
+	if err := rc.Repo.Create(&role); err != nil {
+		rc.writeError(ctx, err, "Failed to create role")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, role)
+}
+
+// Update updates a role of the branch.
+func (rc *RoleController) Update(ctx *gin.Context) {
+	existing, ok := rc.find(ctx)
+	if !ok {
+		return
+	}
+
+	var role models.Role
+	if err := ctx.ShouldBindJSON(&role); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	role.ID = existing.ID
+	role.BranchID = existing.BranchID
+
+	if !rc.validate(ctx, &role) {
+		return
+	}
+
+	if err := rc.Repo.Update(&role); err != nil {
+		rc.writeError(ctx, err, "Failed to update role")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, role)
+}
+
+// Delete deletes a role of the branch, a default role of the same name
+// applies again.
+func (rc *RoleController) Delete(ctx *gin.Context) {
+	role, ok := rc.find(ctx)
+	if !ok {
+		return
+	}
+
+	if err := rc.Repo.Delete(role.ID); err != nil {
+		rc.writeError(ctx, err, "Failed to delete role")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// find loads the role of the route and checks that it belongs to the
+// active branch.
+func (rc *RoleController) find(ctx *gin.Context) (*models.Role, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return nil, false
+	}
+
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return nil, false
+	}
+
+	role, err := rc.Repo.FindOne(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && role.BranchID != branchId) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve role"})
+		return nil, false
+	}
+
+	return role, true
+}
+
+// validate checks the role and that its name is unique in the branch.
+func (rc *RoleController) validate(ctx *gin.Context, role *models.Role) bool {
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+
+	if !role.Validate(rc.DB) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
+		return false
+	}
+
+	exists, err := rc.Repo.ExistsByName(role.BranchID, role.Name, role.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return false
+	}
+	if exists {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return false
+	}
+
+	return true
+}
+
+// writeError answers with a conflict if the branch would lose its last role
+// that can edit roles.
+func (rc *RoleController) writeError(ctx *gin.Context, err error, msg string) {
+	if errors.Is(err, repository.ErrLastRoleEditor) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "The last role with role.edit can't lose it"})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
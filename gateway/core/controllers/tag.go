@@ -27,13 +27,13 @@ func NewTagController(db *gorm.DB) *TagController {
 
 // FindAll finds all tags.
 func (c *TagController) FindAll(ctx *gin.Context) {
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	tags, err := c.TagRepo.FindAllByBranchID(uint(user.(auth.User).Branch.Id))
+	tags, err := c.TagRepo.FindAllByBranchID(branchId)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags"})
 		return
@@ -71,12 +71,12 @@ func (c *TagController) Create(ctx *gin.Context) {
 		return
 	}
 
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
-	tag.BranchID = uint(user.(auth.User).Branch.Id)
+	tag.BranchID = branchId
 
 	if !tag.Validate(c.DB) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
@@ -107,15 +107,15 @@ func (c *TagController) Update(ctx *gin.Context) {
 
 	tag.ID = uint(id)
 
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
 	}
 
-	tag.BranchID = uint(user.(auth.User).Branch.Id)
+	tag.BranchID = branchId
 
-	if user.(auth.User).Branch.Id == int(tag.BranchID) {
+	if branchId == tag.BranchID {
 		if !tag.Validate(c.DB) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
 			return
@@ -140,7 +140,7 @@ func (c *TagController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	user, ok := ctx.Get("user")
+	branchId, ok := auth.BranchID(ctx)
 	if !ok {
 		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 		return
@@ -156,7 +156,7 @@ func (c *TagController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if user.(auth.User).Branch.Id == int(tag.BranchID) {
+	if branchId == tag.BranchID {
 		if err := c.TagRepo.Delete(uint(id)); err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
 			return
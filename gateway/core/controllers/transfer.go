@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TransferController handles the transfer of books between branches.
+type TransferController struct {
+	DB   *gorm.DB
+	Repo *repository.TransferRepository
+}
+
+// NewTransferController creates a new transfer controller.
+func NewTransferController(db *gorm.DB) *TransferController {
+	return &TransferController{
+		DB:   db,
+		Repo: repository.NewTransferRepository(db),
+	}
+}
+
+// FindAll lists the transfers from and to the active branch. The query
+// parameter status filters by state.
+func (tc *TransferController) FindAll(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+
+	status := ctx.Query("status")
+	switch status {
+	case "", models.TransferDraft, models.TransferShipped, models.TransferReceived, models.TransferCancelled:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	transfers, err := tc.Repo.FindAllByBranchID(branchId, status)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transfers"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+// FindOne shows a transfer with its books.
+func (tc *TransferController) FindOne(ctx *gin.Context) {
+	transfer, ok := tc.find(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// Create creates a draft transfer of books of the active branch.
+func (tc *TransferController) Create(ctx *gin.Context) {
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+	userId, _ := auth.UserID(ctx)
+
+	transfer := models.Transfer{
+		SourceBranchID: branchId,
+		Status:         models.TransferDraft,
+		CreatedBy:      userId,
+	}
+	if !tc.bind(ctx, &transfer) {
+		return
+	}
+
+	if err := tc.Repo.Create(&transfer); err != nil {
+		if errors.Is(err, repository.ErrTransferCurrency) {
+			tc.writeError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, transfer)
+}
+
+// Update changes the target, note and books of a draft transfer.
+func (tc *TransferController) Update(ctx *gin.Context) {
+	transfer, ok := tc.findOwn(ctx, models.TransferDraft)
+	if !ok {
+		return
+	}
+
+	if !tc.bind(ctx, transfer) {
+		return
+	}
+
+	if err := tc.Repo.Update(transfer); err != nil {
+		tc.writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// Ship sends a draft transfer, the books are unavailable until received.
+func (tc *TransferController) Ship(ctx *gin.Context) {
+	transfer, ok := tc.findOwn(ctx, models.TransferDraft)
+	if !ok {
+		return
+	}
+	userId, _ := auth.UserID(ctx)
+
+	if err := tc.Repo.Ship(transfer, userId); err != nil {
+		tc.writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// Cancel cancels a draft or shipped transfer, shipped books return to the
+// source branch.
+func (tc *TransferController) Cancel(ctx *gin.Context) {
+	transfer, ok := tc.findOwn(ctx, "")
+	if !ok {
+		return
+	}
+
+	if err := tc.Repo.Cancel(transfer); err != nil {
+		tc.writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// Mapping proposes the genres, conditions, formats and tags of the active
+// branch for the references of the books of a shipped transfer.
+func (tc *TransferController) Mapping(ctx *gin.Context) {
+	transfer, ok := tc.findIncoming(ctx)
+	if !ok {
+		return
+	}
+
+	refs, err := tc.Repo.Mapping(transfer)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve mapping"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, refs)
+}
+
+// Receive adds the books of a shipped transfer to the active branch, using
+// the mapping of the request for their references.
+func (tc *TransferController) Receive(ctx *gin.Context) {
+	transfer, ok := tc.findIncoming(ctx)
+	if !ok {
+		return
+	}
+	userId, _ := auth.UserID(ctx)
+
+	var mapping models.TransferMapping
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&mapping); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+	}
+
+	if err := tc.Repo.Receive(transfer, mapping, userId); err != nil {
+		tc.writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// bind reads the payload into a draft transfer and checks the target branch
+// and the books.
+func (tc *TransferController) bind(ctx *gin.Context, transfer *models.Transfer) bool {
+	var payload models.TransferCreate
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return false
+	}
+	if err := payload.Validate(validator.New()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
+		return false
+	}
+
+	transfer.TargetBranchID = payload.TargetBranchID
+	transfer.TargetBranch = nil
+	transfer.Note = payload.Note
+	if !transfer.Validate(tc.DB) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
+		return false
+	}
+
+	if _, err := repository.NewBranchRepository(tc.DB).FindOne(transfer.TargetBranchID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Target branch not found"})
+		return false
+	}
+
+	ids := make([]uuid.UUID, 0, len(payload.Books))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range payload.Books {
+		bookId := uuid.MustParse(id)
+		if !seen[bookId] {
+			seen[bookId] = true
+			ids = append(ids, bookId)
+		}
+	}
+
+	books, invalid, err := tc.Repo.CheckBooks(transfer.SourceBranchID, ids)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve books"})
+		return false
+	}
+	if len(invalid) > 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Books not available", "books": invalid})
+		return false
+	}
+
+	transfer.Items = make([]models.TransferItem, 0, len(books))
+	for _, b := range books {
+		transfer.Items = append(transfer.Items, models.TransferItem{
+			BookID:         b.ID,
+			Title:          b.Title,
+			Price:          b.Price,
+			SourceFormatID: b.FormatID,
+		})
+	}
+
+	return true
+}
+
+// find loads the transfer of the route, it must come from or go to the
+// active branch.
+func (tc *TransferController) find(ctx *gin.Context) (*models.Transfer, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return nil, false
+	}
+
+	branchId, ok := auth.BranchID(ctx)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return nil, false
+	}
+
+	transfer, err := tc.Repo.FindOne(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && transfer.SourceBranchID != branchId && transfer.TargetBranchID != branchId) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transfer"})
+		return nil, false
+	}
+
+	return transfer, true
+}
+
+// findOwn loads a transfer of the active branch, optionally only in the
+// given state.
+func (tc *TransferController) findOwn(ctx *gin.Context, status string) (*models.Transfer, bool) {
+	transfer, ok := tc.find(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	if branchId, _ := auth.BranchID(ctx); transfer.SourceBranchID != branchId {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only the source branch can change the transfer"})
+		return nil, false
+	}
+	if status != "" && transfer.Status != status {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Transfer is " + transfer.Status})
+		return nil, false
+	}
+
+	return transfer, true
+}
+
+// findIncoming loads a shipped transfer to the active branch.
+func (tc *TransferController) findIncoming(ctx *gin.Context) (*models.Transfer, bool) {
+	transfer, ok := tc.find(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	if branchId, _ := auth.BranchID(ctx); transfer.TargetBranchID != branchId {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only the target branch can receive the transfer"})
+		return nil, false
+	}
+	if transfer.Status != models.TransferShipped {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Transfer is " + transfer.Status})
+		return nil, false
+	}
+
+	return transfer, true
+}
+
+func (tc *TransferController) writeError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrTransferState):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Transfer can't change to this state"})
+	case errors.Is(err, repository.ErrTransferBook), errors.Is(err, repository.ErrTransferMapping), errors.Is(err, repository.ErrTransferCurrency):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transfer"})
+	}
+}
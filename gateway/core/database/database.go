@@ -1,8 +1,11 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
@@ -10,8 +13,21 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// moneyColumns lists the columns that held decimal amounts and are stored
+// in cents now.
+var moneyColumns = []struct{ table, column string }{
+	{"book", "price"},
+	{"branch", "steps"},
+	{"inventory_report", "value_before"},
+	{"inventory_report", "value_after"},
+	{"inventory_report_item", "price"},
+	{"price_rule", "base_price"},
+	{"price_rule", "amount"},
+}
+
 func Connect() *gorm.DB {
 	viper.SetDefault("MYSQL_URL", "adm:pass@tcp(localhost:3306)/warehouse?charset=utf8mb4&parseTime=True&loc=Local")
 	viper.SetDefault("SQLITE_NAME", "warehouse")
@@ -37,9 +53,27 @@ func Connect() *gorm.DB {
 
 	fmt.Println("Connected to database!")
 
+	if databaseType == "mysql" {
+		if err := checkSchema(db); err != nil {
+			log.Fatalf("Database schema is out of date, apply the migrations in gateway/core/database/mysql: %v", err)
+		}
+	}
+
 	if databaseType != "mysql" {
+		if err := migrateMoney(db); err != nil {
+			log.Fatalf("Failed to migrate money columns: %v", err)
+		}
+
+		if err := migrateGenreIndex(db); err != nil {
+			log.Fatalf("Failed to migrate genre index: %v", err)
+		}
+
 		runMigrations(db)
 
+		if err := migrateContributors(db); err != nil {
+			log.Fatalf("Failed to migrate contributors: %v", err)
+		}
+
 		br := repository.NewBookRepository(db)
 		if err := br.DeleteBooks(0); err != nil {
 			log.Printf("warning: DeleteBooks failed: %v", err)
@@ -49,19 +83,31 @@ func Connect() *gorm.DB {
 	return db
 }
 
+// tables lists the models stored in the database.
+var tables = []any{
+	&models.Author{},
+	&models.Branch{},
+	&models.Condition{},
+	&models.Tag{},
+	&models.Genre{},
+	&models.Format{},
+	&models.Reservation{},
+	&models.Book{},
+	&models.BookImage{},
+	&models.BookContributor{},
+	// &models.PublicBook{},
+	&models.Inventory{},
+	&models.InventoryReport{},
+	&models.InventoryReportItem{},
+	&models.PriceRule{},
+	&models.ExchangeRate{},
+	&models.Role{},
+	&models.Transfer{},
+	&models.TransferItem{},
+}
+
 func runMigrations(db *gorm.DB) {
-	err := db.AutoMigrate(
-		&models.Author{},
-		&models.Branch{},
-		&models.Condition{},
-		&models.Tag{},
-		&models.Genre{},
-		&models.Format{},
-		&models.Reservation{},
-		&models.Book{},
-		// &models.PublicBook{},
-		&models.Inventory{},
-	)
+	err := db.AutoMigrate(tables...)
 
 	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
@@ -69,3 +115,166 @@ func runMigrations(db *gorm.DB) {
 
 	fmt.Println("Migrations run successfully!")
 }
+
+// migrateContributors adds the author of books without contributors as
+// their first contributor. Books that have contributors already are
+// skipped, so it is safe to run it on every start.
+func migrateContributors(db *gorm.DB) error {
+	result := db.Exec(`INSERT INTO book_contributor (book_id, author_id, role, position)
+		SELECT id, author_id, ?, 0 FROM book
+		WHERE author_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM book_contributor c WHERE c.book_id = book.id)`, models.ContributorAuthor)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		fmt.Printf("Migrated the authors of %d books to contributors\n", result.RowsAffected)
+	}
+	return nil
+}
+
+// checkSchema reports missing tables and columns and money columns that
+// aren't stored in cents. The schema on MySQL is shared with the core and
+// migrated with the scripts in the mysql directory, never at startup.
+func checkSchema(db *gorm.DB) error {
+	var problems []string
+	m := db.Migrator()
+
+	for _, model := range tables {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+
+		if !m.HasTable(model) {
+			problems = append(problems, fmt.Sprintf("table %s is missing", stmt.Table))
+			continue
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !m.HasColumn(model, field.DBName) {
+				problems = append(problems, fmt.Sprintf("column %s.%s is missing", stmt.Table, field.DBName))
+			}
+		}
+	}
+
+	for _, mc := range moneyColumns {
+		decimal, err := isDecimal(db, mc.table, mc.column)
+		if err != nil {
+			return err
+		}
+		if decimal {
+			problems = append(problems, fmt.Sprintf("%s.%s isn't stored in cents", mc.table, mc.column))
+		}
+	}
+
+	outdated, err := isGenreIndexOutdated(db)
+	if err != nil {
+		return err
+	}
+	if outdated {
+		problems = append(problems, fmt.Sprintf("genre.%s doesn't include parent_id", genreIndex))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// isDecimal reports whether the column exists and doesn't hold integers.
+func isDecimal(db *gorm.DB, table, column string) (bool, error) {
+	m := db.Migrator()
+	if !m.HasTable(table) {
+		return false, nil
+	}
+
+	types, err := m.ColumnTypes(table)
+	if err != nil {
+		return false, err
+	}
+
+	for _, t := range types {
+		if t.Name() == column {
+			return !strings.Contains(strings.ToLower(t.DatabaseTypeName()), "int"), nil
+		}
+	}
+	return false, nil
+}
+
+// migrateMoney converts the decimal money columns into integer cents.
+// Columns that already hold integers are skipped, so it is safe to run it
+// on every start.
+func migrateMoney(db *gorm.DB) error {
+	for _, mc := range moneyColumns {
+		decimal, err := isDecimal(db, mc.table, mc.column)
+		if err != nil {
+			return err
+		}
+		if !decimal {
+			continue
+		}
+
+		cents := mc.column + "_cents"
+		err = db.Transaction(func(tx *gorm.DB) error {
+			table := clause.Table{Name: mc.table}
+			if err := tx.Exec("ALTER TABLE ? ADD COLUMN ? bigint", table, clause.Column{Name: cents}).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE ? SET ? = ROUND(? * 100)", table, clause.Column{Name: cents}, clause.Column{Name: mc.column}).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", table, clause.Column{Name: mc.column}).Error; err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE ? RENAME COLUMN ? TO ?", table, clause.Column{Name: cents}, clause.Column{Name: mc.column}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", mc.table, mc.column, err)
+		}
+
+		fmt.Printf("Migrated %s.%s to cents\n", mc.table, mc.column)
+	}
+
+	return nil
+}
+
+// genreIndex keeps the names of the genres with the same parent unique.
+const genreIndex = "idx_branch_name"
+
+// isGenreIndexOutdated reports whether the unique index of the genre names
+// still covers the whole branch instead of the genres with the same parent.
+func isGenreIndexOutdated(db *gorm.DB) (bool, error) {
+	m := db.Migrator()
+	if !m.HasTable(&models.Genre{}) {
+		return false, nil
+	}
+
+	indexes, err := m.GetIndexes(&models.Genre{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, index := range indexes {
+		if index.Name() == genreIndex {
+			return !slices.Contains(index.Columns(), "parent_id"), nil
+		}
+	}
+	return false, nil
+}
+
+// migrateGenreIndex drops the unique index of the genre names if it covers
+// the whole branch, AutoMigrate creates it again with the parent.
+func migrateGenreIndex(db *gorm.DB) error {
+	outdated, err := isGenreIndexOutdated(db)
+	if err != nil || !outdated {
+		return err
+	}
+
+	if err := db.Migrator().DropIndex(&models.Genre{}, genreIndex); err != nil {
+		return err
+	}
+
+	fmt.Println("Migrated the genre names to be unique per parent")
+	return nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCheckSchema(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Exec("CREATE TABLE book (id text PRIMARY KEY, price decimal(10,2))").Error)
+
+	err = checkSchema(db)
+	assert.ErrorContains(t, err, "column book.transfer_id is missing")
+	assert.ErrorContains(t, err, "table transfer is missing")
+	assert.ErrorContains(t, err, "book.price isn't stored in cents")
+
+	assert.NoError(t, migrateMoney(db))
+	runMigrations(db)
+	assert.NoError(t, checkSchema(db))
+}
+
+func TestMigrateGenreIndex(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/warehouse.db"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Exec("CREATE TABLE genre (id integer PRIMARY KEY AUTOINCREMENT, name varchar(255) NOT NULL, branch_id integer, parent_id integer)").Error)
+	assert.NoError(t, db.Exec("CREATE UNIQUE INDEX idx_branch_name ON genre (name, branch_id)").Error)
+
+	assert.ErrorContains(t, checkSchema(db), "genre.idx_branch_name doesn't include parent_id")
+
+	assert.NoError(t, migrateGenreIndex(db))
+	runMigrations(db)
+	assert.NoError(t, checkSchema(db))
+
+	assert.NoError(t, db.Exec("INSERT INTO genre (id, name, branch_id) VALUES (1, 'Fiction', 1), (2, 'Non-fiction', 1)").Error)
+	assert.NoError(t, db.Exec("INSERT INTO genre (name, branch_id, parent_id) VALUES ('Crime', 1, 1), ('Crime', 1, 2)").Error)
+	assert.Error(t, db.Exec("INSERT INTO genre (name, branch_id, parent_id) VALUES ('Crime', 1, 1)").Error)
+}
@@ -0,0 +1,14 @@
+-- Stores money in cents instead of decimal(10,2). Run it together with the
+-- release of the core that reads and writes cents, the gateway refuses to
+-- start on MySQL until the columns are integers. MySQL commits every
+-- ALTER TABLE on its own, so back up the database first.
+
+ALTER TABLE book ADD COLUMN price_cents BIGINT NOT NULL DEFAULT 0;
+UPDATE book SET price_cents = ROUND(price * 100);
+ALTER TABLE book DROP COLUMN price;
+ALTER TABLE book RENAME COLUMN price_cents TO price;
+
+ALTER TABLE branch ADD COLUMN steps_cents BIGINT NOT NULL DEFAULT 0;
+UPDATE branch SET steps_cents = ROUND(steps * 100);
+ALTER TABLE branch DROP COLUMN steps;
+ALTER TABLE branch RENAME COLUMN steps_cents TO steps;
@@ -0,0 +1,138 @@
+-- Adds the tables and columns of images, contributors, inventory reports,
+-- price rules, exchange rates, roles, transfers, nested genres, branch
+-- schedules and pickup deadlines.
+
+CREATE TABLE `book_image` (
+  `id` CHAR(36) NOT NULL,
+  `book_id` CHAR(36) NOT NULL,
+  `position` BIGINT DEFAULT 0,
+  `caption` LONGTEXT,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_book_image_book_id` (`book_id`)
+);
+
+CREATE TABLE `book_contributor` (
+  `book_id` CHAR(36) NOT NULL,
+  `author_id` BIGINT UNSIGNED NOT NULL,
+  `role` VARCHAR(32) NOT NULL,
+  `position` BIGINT DEFAULT 0,
+  PRIMARY KEY (`book_id`, `author_id`, `role`),
+  INDEX `idx_book_contributor_author_id` (`author_id`),
+  CONSTRAINT `fk_book_contributor_author` FOREIGN KEY (`author_id`) REFERENCES `author` (`id`)
+);
+
+INSERT INTO `book_contributor` (`book_id`, `author_id`, `role`, `position`)
+  SELECT `id`, `author_id`, 'author', 0 FROM `book` WHERE `author_id` IS NOT NULL;
+
+CREATE TABLE `inventory_report` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT,
+  `inventory_id` BIGINT UNSIGNED,
+  `branch_id` BIGINT UNSIGNED,
+  `created_at` DATETIME(3) NULL,
+  `unscanned_as_missing` BOOLEAN DEFAULT false,
+  `value_before` BIGINT DEFAULT 0,
+  `value_after` BIGINT DEFAULT 0,
+  `removed` BIGINT,
+  `unscanned` BIGINT,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_inventory_report_inventory_id` (`inventory_id`),
+  INDEX `idx_inventory_report_branch_id` (`branch_id`)
+);
+
+CREATE TABLE `inventory_report_item` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT,
+  `report_id` BIGINT UNSIGNED,
+  `book_id` CHAR(36),
+  `title` VARCHAR(255),
+  `author` VARCHAR(255),
+  `price` BIGINT DEFAULT 0,
+  `status` VARCHAR(16),
+  `removed` BOOLEAN DEFAULT false,
+  PRIMARY KEY (`id`),
+  INDEX `idx_inventory_report_item_report_id` (`report_id`),
+  CONSTRAINT `fk_inventory_report_items` FOREIGN KEY (`report_id`) REFERENCES `inventory_report` (`id`)
+);
+
+CREATE TABLE `price_rule` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT,
+  `branch_id` BIGINT UNSIGNED,
+  `name` VARCHAR(255),
+  `position` BIGINT DEFAULT 0,
+  `format_id` BIGINT UNSIGNED DEFAULT NULL,
+  `cond_id` BIGINT UNSIGNED DEFAULT NULL,
+  `genre_id` BIGINT UNSIGNED DEFAULT NULL,
+  `year_from` BIGINT DEFAULT NULL,
+  `year_to` BIGINT DEFAULT NULL,
+  `base_price` BIGINT DEFAULT NULL,
+  `percentage` DECIMAL(10,2) DEFAULT 0,
+  `amount` BIGINT DEFAULT 0,
+  PRIMARY KEY (`id`),
+  INDEX `idx_price_rule_branch_id` (`branch_id`),
+  CONSTRAINT `fk_price_rule_branch` FOREIGN KEY (`branch_id`) REFERENCES `branch` (`id`)
+);
+
+CREATE TABLE `exchange_rate` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT,
+  `base` VARCHAR(3) NOT NULL,
+  `quote` VARCHAR(3) NOT NULL,
+  `rate` DECIMAL(18,8) NOT NULL,
+  `updated_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_base_quote` (`base`, `quote`)
+);
+
+CREATE TABLE `role` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT,
+  `branch_id` BIGINT UNSIGNED,
+  `name` VARCHAR(64),
+  `permissions` TEXT,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_role_branch_name` (`branch_id`, `name`)
+);
+
+CREATE TABLE `transfer` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT,
+  `source_branch_id` BIGINT UNSIGNED,
+  `target_branch_id` BIGINT UNSIGNED,
+  `status` VARCHAR(16),
+  `note` VARCHAR(255),
+  `created_by` BIGINT,
+  `created_at` DATETIME(3) NULL,
+  `shipped_by` BIGINT,
+  `shipped_at` DATETIME(3) NULL,
+  `received_by` BIGINT,
+  `received_at` DATETIME(3) NULL,
+  `cancelled_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_transfer_source_branch_id` (`source_branch_id`),
+  INDEX `idx_transfer_target_branch_id` (`target_branch_id`),
+  INDEX `idx_transfer_status` (`status`),
+  CONSTRAINT `fk_transfer_source_branch` FOREIGN KEY (`source_branch_id`) REFERENCES `branch` (`id`),
+  CONSTRAINT `fk_transfer_target_branch` FOREIGN KEY (`target_branch_id`) REFERENCES `branch` (`id`)
+);
+
+CREATE TABLE `transfer_item` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT,
+  `transfer_id` BIGINT UNSIGNED,
+  `book_id` CHAR(36),
+  `title` VARCHAR(255),
+  `price` BIGINT,
+  `source_genre_id` BIGINT UNSIGNED,
+  `source_condition_id` BIGINT UNSIGNED,
+  `source_format_id` BIGINT UNSIGNED,
+  `source_tag_ids` TEXT,
+  `target_genre_id` BIGINT UNSIGNED,
+  `target_condition_id` BIGINT UNSIGNED,
+  `target_format_id` BIGINT UNSIGNED,
+  `target_tag_ids` TEXT,
+  PRIMARY KEY (`id`),
+  INDEX `idx_transfer_item_transfer_id` (`transfer_id`),
+  INDEX `idx_transfer_item_book_id` (`book_id`),
+  CONSTRAINT `fk_transfer_items` FOREIGN KEY (`transfer_id`) REFERENCES `transfer` (`id`)
+);
+
+ALTER TABLE `genre` ADD COLUMN `parent_id` BIGINT UNSIGNED DEFAULT NULL, ADD INDEX `idx_genre_parent_id` (`parent_id`);
+ALTER TABLE `book` ADD COLUMN `transfer_id` BIGINT UNSIGNED DEFAULT NULL, ADD INDEX `idx_book_transfer_id` (`transfer_id`);
+ALTER TABLE `branch` ADD COLUMN `schedule` TEXT, ADD COLUMN `archived` BOOLEAN DEFAULT false, ADD INDEX `idx_branch_archived` (`archived`);
+ALTER TABLE `reservation` ADD COLUMN `pickup_until` DATETIME(3) NULL DEFAULT NULL;
@@ -0,0 +1,7 @@
+-- Makes the names of genres unique among the genres with the same parent
+-- instead of the whole branch, so e.g. Fiction > Crime and Non-fiction >
+-- Crime can both exist. The gateway refuses to start on MySQL until the
+-- index includes parent_id. If the core named the unique index of the genre
+-- names differently, drop that one instead.
+
+ALTER TABLE `genre` DROP INDEX `idx_branch_name`, ADD UNIQUE INDEX `idx_branch_name` (`name`, `branch_id`, `parent_id`);
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -11,6 +13,18 @@ type Author struct {
 	Surname   string `json:"surname" gorm:"size:255" validate:"required,min=1,max=255"`
 }
 
+// AuthorMerge merges the source authors into the target.
+type AuthorMerge struct {
+	Target  uint64   `json:"target" validate:"required"`
+	Sources []uint64 `json:"sources" validate:"required,min=1,max=100,dive,required"`
+}
+
+// Normalize trims the names and collapses whitespace.
+func (a *Author) Normalize() {
+	a.Firstname = strings.Join(strings.Fields(a.Firstname), " ")
+	a.Surname = strings.Join(strings.Fields(a.Surname), " ")
+}
+
 // Validate validates the Author struct based on defined validation tags.
 func (a *Author) Validate(v *validator.Validate) error {
 	return v.Struct(a)
@@ -11,86 +11,91 @@ import (
 
 // Book represents a book entity.
 type Book struct {
-	ID               uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey"`
-	BranchID         *uint        `json:"branch_id" gorm:"default:null"`
-	Branch           *Branch      `json:"branch" gorm:"foreignKey:BranchID"`
-	Added            time.Time    `json:"-" gorm:"column:added;autoCreateTime"`
-	Title            string       `json:"title" gorm:"type:varchar(255)" validate:"required,min=1,max=255"`
-	ShortDescription *string      `json:"shortDescription" gorm:"default:null"`
-	AuthorID         *uint        `json:"author_id" gorm:"default:null"`
-	Author           *Author      `json:"author" gorm:"foreignKey:AuthorID"`
-	GenreID          *uint        `json:"genre_id" gorm:"default:null"`
-	Genre            *Genre       `json:"genre" gorm:"foreignKey:GenreID"`
-	Price            float64      `json:"price" gorm:"type:decimal(10,2);default:0.00" validate:"gte=0.00"`
-	Sold             bool         `json:"sold" gorm:"default:false"`
-	SoldOn           *time.Time   `json:"-" gorm:"default:null"`
-	Removed          bool         `json:"removed" gorm:"default:false"`
-	RemovedOn        *time.Time   `json:"-" gorm:"default:null"`
-	Reserved         bool         `json:"reserved" gorm:"default:false"`
-	ReservedAt       *time.Time   `json:"-" gorm:"default:null"`
-	ReleaseYear      int          `json:"releaseYear" gorm:"type:int;column:release_year" validate:"gte=1000,lte=9999"`
-	Condition        *Condition   `json:"condition" gorm:"foreignKey:ConditionID"`
-	ConditionID      *uint        `json:"cond_id" gorm:"column:cond_id;default:null"`
-	Tags             []*Tag       `json:"tags" gorm:"many2many:book_tag;default:null"`
-	Recommendation   bool         `json:"recommendation" gorm:"foreignKey:BookID;default:false"`
-	Inventory        *bool        `json:"inventory" gorm:"default:null"`
-	Format           *Format      `json:"format" gorm:"foreignKey:FormatID"`
-	FormatID         uint         `json:"format_id" gorm:"not null"`
-	Subtitle         *string      `json:"subtitle" gorm:"default:null" validate:"max=255"`
-	Duplicate        bool         `json:"duplicate" gorm:"default:false"`
-	ReservationID    *uuid.UUID   `json:"reservation_id" gorm:"default:null"`
-	Reservation      *Reservation `json:"reservation" gorm:"foreignKey:ReservationID"`
-	AddedUnix        int64        `json:"added" gorm:"-"`
-	SoldOnUnix       *int64       `json:"soldOn,omitempty" gorm:"-"`
-	RemovedOnUnix    *int64       `json:"removedOn,omitempty" gorm:"-"`
-	ReservedAtUnix   *int64       `json:"reservedAt,omitempty" gorm:"-"`
+	ID               uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey"`
+	BranchID         *uint             `json:"branch_id" gorm:"default:null"`
+	Branch           *Branch           `json:"branch" gorm:"foreignKey:BranchID"`
+	Added            time.Time         `json:"-" gorm:"column:added;autoCreateTime"`
+	Title            string            `json:"title" gorm:"type:varchar(255)" validate:"required,min=1,max=255"`
+	ShortDescription *string           `json:"shortDescription" gorm:"default:null"`
+	AuthorID         *uint             `json:"author_id" gorm:"default:null"`
+	Author           *Author           `json:"author" gorm:"foreignKey:AuthorID"`
+	Contributors     []BookContributor `json:"contributors" gorm:"foreignKey:BookID"`
+	GenreID          *uint             `json:"genre_id" gorm:"default:null"`
+	Genre            *Genre            `json:"genre" gorm:"foreignKey:GenreID"`
+	Price            Money             `json:"price" gorm:"default:0" validate:"gte=0"`
+	Sold             bool              `json:"sold" gorm:"default:false"`
+	SoldOn           *time.Time        `json:"-" gorm:"default:null"`
+	Removed          bool              `json:"removed" gorm:"default:false"`
+	RemovedOn        *time.Time        `json:"-" gorm:"default:null"`
+	Reserved         bool              `json:"reserved" gorm:"default:false"`
+	ReservedAt       *time.Time        `json:"-" gorm:"default:null"`
+	ReleaseYear      int               `json:"releaseYear" gorm:"type:int;column:release_year" validate:"gte=1000,lte=9999"`
+	Condition        *Condition        `json:"condition" gorm:"foreignKey:ConditionID"`
+	ConditionID      *uint             `json:"cond_id" gorm:"column:cond_id;default:null"`
+	Tags             []*Tag            `json:"tags" gorm:"many2many:book_tag;default:null"`
+	Recommendation   bool              `json:"recommendation" gorm:"foreignKey:BookID;default:false"`
+	Inventory        *bool             `json:"inventory" gorm:"default:null"`
+	Format           *Format           `json:"format" gorm:"foreignKey:FormatID"`
+	FormatID         uint              `json:"format_id" gorm:"not null"`
+	Subtitle         *string           `json:"subtitle" gorm:"default:null" validate:"max=255"`
+	Duplicate        bool              `json:"duplicate" gorm:"default:false"`
+	ReservationID    *uuid.UUID        `json:"reservation_id" gorm:"default:null"`
+	Reservation      *Reservation      `json:"reservation" gorm:"foreignKey:ReservationID"`
+	TransferID       *uint             `json:"transfer_id" gorm:"default:null;index"`
+	AddedUnix        int64             `json:"added" gorm:"-"`
+	SoldOnUnix       *int64            `json:"soldOn,omitempty" gorm:"-"`
+	RemovedOnUnix    *int64            `json:"removedOn,omitempty" gorm:"-"`
+	ReservedAtUnix   *int64            `json:"reservedAt,omitempty" gorm:"-"`
 }
 
 // BookUpdate represents an update payload.
 type BookUpdate struct {
-	Added            *int64         `json:"added,omitempty"`
-	Title            *string        `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
-	ShortDescription *string        `json:"shortDescription,omitempty"`
-	Author           *string        `json:"author,omitempty"`
-	GenreID          *UintOrString  `json:"genre,omitempty"`
-	Price            *FloatOrString `json:"price,omitempty" validate:"omitempty,gte=0"`
-	Sold             *bool          `json:"sold,omitempty"`
-	Removed          *bool          `json:"removed,omitempty"`
-	Reserved         *bool          `json:"reserved,omitempty"`
-	ReleaseYear      *IntOrString   `json:"releaseYear,omitempty" validate:"omitempty,gte=1000,lte=9999"`
-	CondID           *UintOrString  `json:"cond,omitempty"`
-	Tags             []*int64       `json:"tags,omitempty"`
-	Recommendation   *bool          `json:"recommendation,omitempty"`
-	FormatID         *UintOrString  `json:"format,omitempty"`
-	Subtitle         *string        `json:"subtitle,omitempty" validate:"omitempty,max=255"`
-	Duplicate        *bool          `json:"duplicate,omitempty"`
+	Added            *int64              `json:"added,omitempty"`
+	Title            *string             `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
+	ShortDescription *string             `json:"shortDescription,omitempty"`
+	Author           *string             `json:"author,omitempty"`
+	Contributors     []ContributorUpdate `json:"contributors,omitempty"`
+	GenreID          *UintOrString       `json:"genre,omitempty"`
+	Price            *MoneyOrString      `json:"price,omitempty"`
+	Sold             *bool               `json:"sold,omitempty"`
+	Removed          *bool               `json:"removed,omitempty"`
+	Reserved         *bool               `json:"reserved,omitempty"`
+	ReleaseYear      *IntOrString        `json:"releaseYear,omitempty" validate:"omitempty,gte=1000,lte=9999"`
+	CondID           *UintOrString       `json:"cond,omitempty"`
+	Tags             []*int64            `json:"tags,omitempty"`
+	Recommendation   *bool               `json:"recommendation,omitempty"`
+	FormatID         *UintOrString       `json:"format,omitempty"`
+	Subtitle         *string             `json:"subtitle,omitempty" validate:"omitempty,max=255"`
+	Duplicate        *bool               `json:"duplicate,omitempty"`
 }
 
 type UintOrString struct {
 	Val *uint
 }
 
-type FloatOrString struct {
-	Val *float64
+type MoneyOrString struct {
+	Val *Money
 }
 
 type IntOrString struct {
 	Val *int
 }
 
-func (f *FloatOrString) UnmarshalJSON(data []byte) error {
+func (m *MoneyOrString) UnmarshalJSON(data []byte) error {
 	s := strings.TrimSpace(string(data))
 	s = strings.Trim(s, "\"")
 	if strings.EqualFold(s, "null") || s == "" {
-		f.Val = nil
+		m.Val = nil
 		return nil
 	}
-	s = strings.ReplaceAll(s, ",", ".")
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil {
+	var v Money
+	if err := v.UnmarshalJSON([]byte(s)); err != nil {
 		return err
 	}
-	f.Val = &v
+	if strings.HasPrefix(v.decimal, "-") {
+		return ErrInvalidMoney
+	}
+	m.Val = &v
 	return nil
 }
 
@@ -131,8 +136,23 @@ func (Book) TableName() string {
 	return "book"
 }
 
-// AfterFind is a GORM hook that populates AddedUnix after loading from DB.
+// AfterFind is a GORM hook that populates AddedUnix and the currency of the
+// price after loading from DB.
 func (b *Book) AfterFind(tx *gorm.DB) (err error) {
+	code := DefaultCurrency
+	if b.Branch != nil {
+		code = b.Branch.Currency
+	} else if code, err = branchCurrency(tx, b.BranchID); err != nil {
+		return err
+	}
+	if b.Price, err = b.Price.In(code); err != nil {
+		return err
+	}
+
+	if len(b.Contributors) > 0 {
+		b.Contributors = contributors(b.Contributors, b.AuthorID, b.Author)
+	}
+
 	b.AddedUnix = b.Added.Unix()
 	if b.SoldOn != nil {
 		v := b.SoldOn.Unix()
@@ -149,6 +169,15 @@ func (b *Book) AfterFind(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// AfterDelete is a GORM hook that removes the additional images and the
+// contributors of the book.
+func (b *Book) AfterDelete(tx *gorm.DB) (err error) {
+	if err := tx.Where("book_id = ?", b.ID).Delete(&BookImage{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("book_id = ?", b.ID).Delete(&BookContributor{}).Error
+}
+
 // BeforeSave will copy AddedUnix into Added if the unix value is set.
 func (b *Book) BeforeSave(tx *gorm.DB) (err error) {
 	if b.AddedUnix != 0 {
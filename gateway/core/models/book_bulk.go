@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Actions supported by bulk book operations.
+const (
+	BookBulkSell           = "sell"
+	BookBulkRemove         = "remove"
+	BookBulkRestore        = "restore"
+	BookBulkGenre          = "genre"
+	BookBulkCondition      = "condition"
+	BookBulkTagAdd         = "tag_add"
+	BookBulkTagRemove      = "tag_remove"
+	BookBulkReprice        = "reprice"
+	BookBulkRecommendation = "recommendation"
+)
+
+// BookBulkLimit is the maximum number of books affected by one bulk operation.
+const BookBulkLimit = 1000
+
+var (
+	// ErrEmptyBulkFilter is returned if a filter would select the whole stock.
+	ErrEmptyBulkFilter = errors.New("the filter needs at least one criterion")
+	// ErrEmptyBulk is returned if neither books nor a filter are given.
+	ErrEmptyBulk = errors.New("either ids or a filter is required")
+)
+
+// BookBulk represents a bulk operation on a list of books or on all books matching a filter.
+type BookBulk struct {
+	IDs            []string        `json:"ids" validate:"required_without=Filter,max=1000,dive,uuid"`
+	Filter         *BookBulkFilter `json:"filter" validate:"required_without=IDs"`
+	Action         string          `json:"action" validate:"required,oneof=sell remove restore genre condition tag_add tag_remove reprice recommendation"`
+	GenreID        *uint           `json:"genre" validate:"required_if=Action genre"`
+	ConditionID    *uint           `json:"cond" validate:"required_if=Action condition"`
+	TagID          *uint           `json:"tag" validate:"required_if=Action tag_add,required_if=Action tag_remove"`
+	Percentage     *float64        `json:"percentage" validate:"required_if=Action reprice,omitempty,gte=-100"`
+	Recommendation *bool           `json:"recommendation"`
+}
+
+// BookBulkFilter selects the books of the current branch a bulk operation applies to.
+type BookBulkFilter struct {
+	GenreID        *uint  `json:"genre"`
+	ConditionID    *uint  `json:"cond"`
+	FormatID       *uint  `json:"format"`
+	TagID          *uint  `json:"tag"`
+	Sold           *bool  `json:"sold"`
+	Removed        *bool  `json:"removed"`
+	Reserved       *bool  `json:"reserved"`
+	Recommendation *bool  `json:"recommendation"`
+	AddedBefore    *int64 `json:"addedBefore"`
+	AddedAfter     *int64 `json:"addedAfter"`
+}
+
+// BookBulkResult represents the outcome of a bulk operation for one book.
+type BookBulkResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Msg    string `json:"msg,omitempty"`
+}
+
+// IsEmpty reports whether the filter has no criterion and matches all books.
+func (f BookBulkFilter) IsEmpty() bool {
+	return f == BookBulkFilter{}
+}
+
+// Validate validates the BookBulk struct based on defined validation tags.
+// It needs books or a filter, which needs at least one criterion. An empty
+// list of IDs counts as given for required_without, so it's checked here.
+func (b *BookBulk) Validate(v *validator.Validate) error {
+	if err := v.Struct(b); err != nil {
+		return err
+	}
+	if len(b.IDs) == 0 && b.Filter == nil {
+		return ErrEmptyBulk
+	}
+	if len(b.IDs) == 0 && b.Filter != nil && b.Filter.IsEmpty() {
+		return ErrEmptyBulkFilter
+	}
+	return nil
+}
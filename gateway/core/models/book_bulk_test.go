@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookBulkValidate(t *testing.T) {
+	v := validator.New()
+
+	testCases := []struct {
+		name  string
+		bulk  BookBulk
+		valid bool
+	}{
+		{"ids", BookBulk{IDs: []string{"0b4d5c0e-7d3c-4f8e-9d2a-1f3b5c7d9e0a"}, Action: BookBulkSell}, true},
+		{"filter", BookBulk{Filter: &BookBulkFilter{GenreID: ptr(uint(1))}, Action: BookBulkSell}, true},
+		{"filter with false", BookBulk{Filter: &BookBulkFilter{Sold: ptr(false)}, Action: BookBulkSell}, true},
+		{"empty filter", BookBulk{Filter: &BookBulkFilter{}, Action: BookBulkSell}, false},
+		{"nothing", BookBulk{Action: BookBulkSell}, false},
+		{"empty ids", BookBulk{IDs: []string{}, Action: BookBulkSell}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.bulk.Validate(v)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	err := (&BookBulk{Filter: &BookBulkFilter{}, Action: BookBulkSell}).Validate(v)
+	assert.ErrorIs(t, err, ErrEmptyBulkFilter)
+
+	err = (&BookBulk{IDs: []string{}, Action: BookBulkSell}).Validate(v)
+	assert.ErrorIs(t, err, ErrEmptyBulk)
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Roles of the contributors of a book.
+const (
+	ContributorAuthor      = "author"
+	ContributorEditor      = "editor"
+	ContributorTranslator  = "translator"
+	ContributorIllustrator = "illustrator"
+)
+
+// ContributorRoles lists the roles of contributors.
+var ContributorRoles = []string{ContributorAuthor, ContributorEditor, ContributorTranslator, ContributorIllustrator}
+
+// BookContributor links an author to a book in a role. Position orders the
+// contributors of a book.
+type BookContributor struct {
+	BookID   uuid.UUID `json:"-" gorm:"type:uuid;primaryKey"`
+	AuthorID uint64    `json:"author_id" gorm:"primaryKey;index"`
+	Author   *Author   `json:"author" gorm:"foreignKey:AuthorID"`
+	Role     string    `json:"role" gorm:"primaryKey;size:32" validate:"oneof=author editor translator illustrator"`
+	Position int       `json:"position" gorm:"default:0"`
+}
+
+// ContributorUpdate names a contributor of a book in an update payload.
+type ContributorUpdate struct {
+	Name string `json:"name" validate:"required,max=511"`
+	Role string `json:"role" validate:"omitempty,oneof=author editor translator illustrator"`
+}
+
+// PublicContributor is a contributor in the public book responses.
+type PublicContributor struct {
+	Firstname string `json:"firstname"`
+	Surname   string `json:"surname"`
+	Role      string `json:"role"`
+}
+
+// TableName returns the book contributor table name.
+func (BookContributor) TableName() string {
+	return "book_contributor"
+}
+
+// PreloadContributors loads the contributors of books with their authors
+// in order.
+func PreloadContributors(db *gorm.DB) *gorm.DB {
+	return db.Preload("Contributors", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position asc")
+	}).Preload("Contributors.Author")
+}
+
+// contributors returns the contributors of a book. Books saved with an
+// author only have it as their single contributor. If the first author
+// isn't the one in author_id, e.g. because the core changed it, the authors
+// are replaced by that one.
+func contributors(list []BookContributor, authorID *uint, author *Author) []BookContributor {
+	i := slices.IndexFunc(list, func(c BookContributor) bool {
+		return c.Role == ContributorAuthor
+	})
+	if i < 0 && authorID == nil || i >= 0 && authorID != nil && list[i].AuthorID == uint64(*authorID) {
+		return list
+	}
+
+	result := []BookContributor{}
+	if authorID != nil {
+		c := BookContributor{AuthorID: uint64(*authorID), Role: ContributorAuthor}
+		if len(list) > 0 {
+			c.BookID = list[0].BookID
+		}
+		if author != nil && author.ID == c.AuthorID {
+			c.Author = author
+		}
+		result = append(result, c)
+	}
+	for _, c := range list {
+		if c.Role != ContributorAuthor {
+			c.Position = len(result)
+			result = append(result, c)
+		}
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContributors(t *testing.T) {
+	goethe := &Author{ID: 1, Firstname: "Johann Wolfgang", Surname: "Goethe"}
+	schiller := &Author{ID: 2, Firstname: "Friedrich", Surname: "Schiller"}
+	translator := BookContributor{AuthorID: 3, Role: ContributorTranslator, Position: 2}
+
+	list := []BookContributor{
+		{AuthorID: 1, Author: goethe, Role: ContributorAuthor},
+		{AuthorID: 2, Author: schiller, Role: ContributorAuthor, Position: 1},
+		translator,
+	}
+
+	testCases := []struct {
+		name     string
+		list     []BookContributor
+		authorID *uint
+		author   *Author
+		expected []BookContributor
+	}{
+		{"in sync", list, ptr(uint(1)), goethe, list},
+		{"without contributors", nil, ptr(uint(1)), goethe, []BookContributor{{AuthorID: 1, Author: goethe, Role: ContributorAuthor}}},
+		{"without author", []BookContributor{translator}, nil, nil, []BookContributor{translator}},
+		{"none", nil, nil, nil, nil},
+		{
+			"author changed",
+			list,
+			ptr(uint(2)),
+			schiller,
+			[]BookContributor{
+				{AuthorID: 2, Author: schiller, Role: ContributorAuthor},
+				{AuthorID: 3, Role: ContributorTranslator, Position: 1},
+			},
+		},
+		{
+			"author removed",
+			list,
+			nil,
+			nil,
+			[]BookContributor{{AuthorID: 3, Role: ContributorTranslator, Position: 0}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, contributors(tc.list, tc.authorID, tc.author))
+		})
+	}
+}
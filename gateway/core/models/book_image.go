@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/cover"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BookImage represents an additional photo of a book, e.g. of the spine,
+// damages or signatures.
+type BookImage struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	BookID    uuid.UUID `json:"book_id" gorm:"type:uuid;index;not null"`
+	Position  int       `json:"position" gorm:"default:0" validate:"gte=0"`
+	Caption   string    `json:"caption" validate:"max=255"`
+	CreatedAt time.Time `json:"-"`
+	ImageS    string    `json:"image_s" gorm:"-"`
+	ImageM    string    `json:"image_m" gorm:"-"`
+	ImageL    string    `json:"image_l" gorm:"-"`
+}
+
+// TableName overrides the default table name for BookImage model.
+func (BookImage) TableName() string {
+	return "book_image"
+}
+
+// Validate validates the BookImage model based on defined rules.
+func (i *BookImage) Validate(db *gorm.DB) bool {
+	validate := validator.New()
+	return validate.Struct(i) == nil
+}
+
+// AfterFind is a GORM hook that sets the image URLs.
+func (i *BookImage) AfterFind(tx *gorm.DB) (err error) {
+	i.SetURLs()
+	return nil
+}
+
+// SetURLs sets the public URLs of the image sizes.
+func (i *BookImage) SetURLs() {
+	i.ImageS = cover.ImageURL(i.BookID, i.ID, "s")
+	i.ImageM = cover.ImageURL(i.BookID, i.ID, "m")
+	i.ImageL = cover.ImageURL(i.BookID, i.ID, "l")
+}
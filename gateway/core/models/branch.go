@@ -1,21 +1,69 @@
 package models
 
 import (
+	"cmp"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
 // Branch represents a branch entity with various attributes.
 type Branch struct {
-	ID              uint    `json:"id" gorm:"primaryKey;autoIncrement;->"`
-	Name            string  `json:"name" validate:"required,max=255"`
-	Steps           float32 `json:"steps" validate:"gte=0.00" gorm:"default:0.00"`
-	Currency        string  `json:"currency" validate:"required,oneof=EUR USD" gorm:"default:'EUR'"`
-	Ordering        string  `json:"ordering" gorm:"type:text"`
-	Public          bool    `json:"public" gorm:"default:false"`
-	Pricelist       string  `json:"pricelist" gorm:"type:text"`
-	Cart            bool    `json:"cart" gorm:"default:false"`
-	Content         string  `json:"content" gorm:"type:text"`
-	MailReservation string  `json:"mail_reservation" gorm:"type:text"`
+	ID              uint            `json:"id" gorm:"primaryKey;autoIncrement;->"`
+	Name            string          `json:"name" validate:"required,max=255"`
+	Steps           Money           `json:"steps" validate:"gte=0" gorm:"default:0"`
+	Currency        string          `json:"currency" validate:"required,iso4217" gorm:"default:'EUR'"`
+	Ordering        BranchOrdering  `json:"ordering" gorm:"type:text"`
+	Public          bool            `json:"public" gorm:"default:false"`
+	Pricelist       BranchPricelist `json:"pricelist" gorm:"type:text"`
+	Cart            bool            `json:"cart" gorm:"default:false"`
+	Content         BranchContent   `json:"content" gorm:"type:text"`
+	Schedule        BranchSchedule  `json:"schedule" gorm:"type:text"`
+	MailReservation string          `json:"mail_reservation" gorm:"type:text"`
+	Archived        bool            `json:"archived" gorm:"default:false;index"`
+}
+
+// BranchCreate is the payload to create a branch. The genres, conditions and
+// formats of the template branch are copied to the new branch.
+type BranchCreate struct {
+	Branch
+	TemplateID *uint `json:"template_id"`
+}
+
+// BranchOrdering tells customers how to order books of the branch.
+type BranchOrdering struct {
+	Text  string `json:"text" validate:"max=2000"`
+	Email string `json:"email" validate:"omitempty,email,max=255"`
+	Phone string `json:"phone" validate:"max=64"`
+	URL   string `json:"url" validate:"omitempty,url,max=255"`
+}
+
+// BranchPricelist is the price list the branch publishes.
+type BranchPricelist struct {
+	Note  string                `json:"note" validate:"max=2000"`
+	Items []BranchPricelistItem `json:"items" validate:"max=100,dive"`
+}
+
+// BranchPricelistItem is a line of the price list.
+type BranchPricelistItem struct {
+	Label string `json:"label" validate:"required,max=255"`
+	Price Money  `json:"price" validate:"gte=0"`
+	Note  string `json:"note" validate:"max=255"`
+}
+
+// BranchContent is the page the branch publishes.
+type BranchContent struct {
+	Sections []BranchSection `json:"sections" validate:"max=50,dive"`
+}
+
+// BranchSection is a section of the page of the branch.
+type BranchSection struct {
+	Title string `json:"title" validate:"max=255"`
+	Body  string `json:"body" validate:"required,max=10000"`
 }
 
 // TableName returns the branch table name.
@@ -23,7 +71,116 @@ func (b *Branch) TableName() string {
 	return "branch"
 }
 
-// Validate validates the Branch struct based on defined validation tags.
+// AfterFind is a GORM hook that sets the currency of the amounts.
+func (b *Branch) AfterFind(tx *gorm.DB) error {
+	return b.setCurrency()
+}
+
+// BeforeSave is a GORM hook that sets the currency of the amounts read from
+// JSON.
+func (b *Branch) BeforeSave(tx *gorm.DB) error {
+	return b.setCurrency()
+}
+
+// setCurrency sets the currency of the branch on its amounts.
+func (b *Branch) setCurrency() (err error) {
+	code := cmp.Or(b.Currency, DefaultCurrency)
+	if b.Steps, err = b.Steps.In(code); err != nil {
+		return err
+	}
+	for i := range b.Pricelist.Items {
+		if b.Pricelist.Items[i].Price, err = b.Pricelist.Items[i].Price.In(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates the Branch struct based on defined validation tags and
+// checks its schedule.
 func (b *Branch) Validate(v *validator.Validate) error {
-	return v.Struct(b)
+	if err := v.Struct(b); err != nil {
+		return err
+	}
+	return b.Schedule.Validate()
+}
+
+// Value stores the ordering as JSON.
+func (o BranchOrdering) Value() (driver.Value, error) {
+	return marshalText(o)
+}
+
+// Scan reads the ordering. Free text stored before it was structured
+// becomes the text of the ordering.
+func (o *BranchOrdering) Scan(value any) error {
+	*o = BranchOrdering{}
+	text, err := scanText(value, o)
+	if text != "" {
+		*o = BranchOrdering{Text: text}
+	}
+	return err
+}
+
+// Value stores the price list as JSON.
+func (p BranchPricelist) Value() (driver.Value, error) {
+	return marshalText(p)
+}
+
+// Scan reads the price list. Free text stored before it was structured
+// becomes the note of the price list.
+func (p *BranchPricelist) Scan(value any) error {
+	*p = BranchPricelist{}
+	text, err := scanText(value, p)
+	if text != "" {
+		*p = BranchPricelist{Note: text}
+	}
+	return err
+}
+
+// Value stores the content as JSON.
+func (c BranchContent) Value() (driver.Value, error) {
+	return marshalText(c)
+}
+
+// Scan reads the content. Free text stored before it was structured
+// becomes the only section of the content.
+func (c *BranchContent) Scan(value any) error {
+	*c = BranchContent{}
+	text, err := scanText(value, c)
+	if text != "" {
+		*c = BranchContent{Sections: []BranchSection{{Body: text}}}
+	}
+	return err
+}
+
+func marshalText(v any) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// scanText decodes a JSON object into v. Any other text is returned to be
+// used as legacy free text.
+func scanText(value any, v any) (string, error) {
+	var text string
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	default:
+		return "", errors.New("unsupported type for branch text")
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(text), "{") && json.Unmarshal([]byte(text), v) == nil {
+		return "", nil
+	}
+	return text, nil
 }
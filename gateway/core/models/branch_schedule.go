@@ -0,0 +1,82 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// ErrInvalidSchedule is returned if opening hours end before they start or
+// a closure ends before it starts.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// BranchSchedule describes when a branch is open and how reserved books are
+// picked up.
+type BranchSchedule struct {
+	Timezone   string         `json:"timezone" validate:"omitempty,timezone"`
+	Hours      []OpeningHours `json:"hours" validate:"max=50,dive"`
+	Closures   []Closure      `json:"closures" validate:"max=200,dive"`
+	Pickup     string         `json:"pickup" validate:"max=2000"`
+	PickupDays int            `json:"pickup_days" validate:"gte=0,lte=60"`
+}
+
+// OpeningHours is a period the branch is open on a weekday. Weekdays count
+// from 0 for Sunday, times are given as 15:04.
+type OpeningHours struct {
+	Weekday time.Weekday `json:"weekday" validate:"gte=0,lte=6"`
+	Opens   string       `json:"opens" validate:"required,datetime=15:04"`
+	Closes  string       `json:"closes" validate:"required,datetime=15:04"`
+}
+
+// Closure closes the branch from one day to another, both included. Days
+// are given as 2006-01-02, without To the branch is closed on From only.
+type Closure struct {
+	From   string `json:"from" validate:"required,datetime=2006-01-02"`
+	To     string `json:"to" validate:"omitempty,datetime=2006-01-02"`
+	Reason string `json:"reason" validate:"max=255"`
+}
+
+// Validate checks that all periods end after they start. The fields must
+// have been validated already.
+func (s BranchSchedule) Validate() error {
+	for _, h := range s.Hours {
+		if h.Closes <= h.Opens {
+			return ErrInvalidSchedule
+		}
+	}
+	for _, c := range s.Closures {
+		if c.To != "" && c.To < c.From {
+			return ErrInvalidSchedule
+		}
+	}
+	return nil
+}
+
+// Location returns the timezone of the branch, the local timezone if none
+// is set.
+func (s BranchSchedule) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Value stores the schedule as JSON.
+func (s BranchSchedule) Value() (driver.Value, error) {
+	return marshalText(s)
+}
+
+// Scan reads the schedule, anything else than a JSON object is an empty
+// schedule.
+func (s *BranchSchedule) Scan(value any) error {
+	*s = BranchSchedule{}
+	text, err := scanText(value, s)
+	if text != "" {
+		*s = BranchSchedule{}
+	}
+	return err
+}
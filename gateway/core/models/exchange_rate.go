@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// ExchangeRate represents the rate for one unit of the base currency in the quote currency.
+type ExchangeRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement;->"`
+	Base      string    `json:"base" gorm:"type:varchar(3);not null;uniqueIndex:idx_base_quote" validate:"required,iso4217"`
+	Quote     string    `json:"quote" gorm:"type:varchar(3);not null;uniqueIndex:idx_base_quote" validate:"required,iso4217,nefield=Base"`
+	Rate      float64   `json:"rate" gorm:"type:decimal(18,8);not null" validate:"gt=0"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PublicPrice represents a book price converted into the currency of the visitor.
+type PublicPrice struct {
+	Currency       string  `json:"currency"`
+	Price          Money   `json:"price"`
+	PriceFormatted string  `json:"priceFormatted"`
+	Rate           float64 `json:"rate"`
+}
+
+// TableName overrides the default table name for ExchangeRate model.
+func (ExchangeRate) TableName() string {
+	return "exchange_rate"
+}
+
+// Validate validates the ExchangeRate model based on defined rules.
+func (e *ExchangeRate) Validate(db *gorm.DB) bool {
+	validate := validator.New()
+	return validate.Struct(e) == nil
+}
+
+// MarshalJSON customizes the JSON output for ExchangeRate.
+func (e ExchangeRate) MarshalJSON() ([]byte, error) {
+	type Alias ExchangeRate
+	return json.Marshal(&struct {
+		UpdatedAt int64 `json:"updatedAt"`
+		*Alias
+	}{
+		UpdatedAt: e.UpdatedAt.Unix(),
+		Alias:     (*Alias)(&e),
+	})
+}
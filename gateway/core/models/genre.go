@@ -11,6 +11,23 @@ type Genre struct {
 	Name     string `json:"name" gorm:"type:varchar(255);not null;uniqueIndex:idx_branch_name" validate:"required,min=1,max=255"`
 	BranchID uint   `json:"branch_id" gorm:"index;uniqueIndex:idx_branch_name"`
 	Branch   Branch `json:"branch" gorm:"foreignKey:BranchID"`
+	ParentID *uint  `json:"parent_id" gorm:"index;uniqueIndex:idx_branch_name;default:null"`
+}
+
+// GenreMove moves a genre below another one or, without a parent, to the
+// top.
+type GenreMove struct {
+	ParentID *uint `json:"parent_id"`
+}
+
+// GenreNode is a genre in the tree of genres. Books counts the books of the
+// genre and its subgenres.
+type GenreNode struct {
+	ID       uint         `json:"id"`
+	Name     string       `json:"name"`
+	ParentID *uint        `json:"parent_id"`
+	Books    int64        `json:"books"`
+	Children []*GenreNode `json:"children"`
 }
 
 // TableName overrides the default table name for Genre model.
@@ -18,12 +35,32 @@ func (Genre) TableName() string {
 	return "genre"
 }
 
-// Validate validates the Genre model based on defined rules.
+// Validate validates the Genre model based on defined rules. The name must
+// be unique among the genres with the same parent.
 func (g *Genre) Validate(db *gorm.DB) bool {
 	validate := validator.New()
 	if err := validate.StructExcept(g, "Branch", "BranchID"); err != nil {
 		return false
 	}
 
-	return true
+	exists, err := g.SiblingExists(db)
+	return err == nil && !exists
+}
+
+// SiblingExists checks whether another genre with the same parent in the
+// branch has the name. Unlike the unique index, it treats genres without a
+// parent as siblings too.
+func (g *Genre) SiblingExists(db *gorm.DB) (bool, error) {
+	query := db.Model(&Genre{}).Where("name = ? AND branch_id = ? AND id <> ?", g.Name, g.BranchID, g.ID)
+	if g.ParentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *g.ParentID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// InventoryReportItemNotFound marks a book that was scanned as not found.
+	InventoryReportItemNotFound = "not_found"
+	// InventoryReportItemUnscanned marks a book that was never scanned.
+	InventoryReportItemUnscanned = "unscanned"
+)
+
+// InventoryReport represents the reconciliation report stored when an inventory is closed.
+type InventoryReport struct {
+	ID                 uint                  `json:"id" gorm:"primaryKey;autoIncrement;->"`
+	InventoryID        uint                  `json:"inventory_id" gorm:"uniqueIndex"`
+	BranchID           uint                  `json:"branch_id" gorm:"index"`
+	CreatedAt          time.Time             `json:"createdAt"`
+	UnscannedAsMissing bool                  `json:"unscannedAsMissing" gorm:"default:false"`
+	ValueBefore        Money                 `json:"valueBefore" gorm:"default:0"`
+	ValueAfter         Money                 `json:"valueAfter" gorm:"default:0"`
+	Removed            int                   `json:"removed"`
+	Unscanned          int                   `json:"unscanned"`
+	Items              []InventoryReportItem `json:"items" gorm:"foreignKey:ReportID"`
+}
+
+// InventoryReportItem represents a single book listed in an inventory report.
+type InventoryReportItem struct {
+	ID       uint      `json:"id" gorm:"primaryKey;autoIncrement;->"`
+	ReportID uint      `json:"-" gorm:"index"`
+	BookID   uuid.UUID `json:"book_id" gorm:"type:uuid"`
+	Title    string    `json:"title" gorm:"type:varchar(255)"`
+	Author   string    `json:"author" gorm:"type:varchar(255)"`
+	Price    Money     `json:"price" gorm:"default:0"`
+	Status   string    `json:"status" gorm:"type:varchar(16)"`
+	Removed  bool      `json:"removed" gorm:"default:false"`
+}
+
+// TableName sets the table name for the InventoryReport model.
+func (InventoryReport) TableName() string {
+	return "inventory_report"
+}
+
+// TableName sets the table name for the InventoryReportItem model.
+func (InventoryReportItem) TableName() string {
+	return "inventory_report_item"
+}
+
+// AfterFind is a GORM hook that sets the currency of the branch on the
+// amounts of the report.
+func (r *InventoryReport) AfterFind(tx *gorm.DB) (err error) {
+	code, err := branchCurrency(tx, &r.BranchID)
+	if err != nil {
+		return err
+	}
+	r.ValueBefore.Currency = code
+	r.ValueAfter.Currency = code
+	for i := range r.Items {
+		r.Items[i].Price.Currency = code
+	}
+	return nil
+}
+
+// MarshalJSON customizes the JSON output for InventoryReport.
+func (r InventoryReport) MarshalJSON() ([]byte, error) {
+	type Alias InventoryReport
+	return json.Marshal(&struct {
+		CreatedAt int64 `json:"createdAt"`
+		*Alias
+	}{
+		CreatedAt: r.CreatedAt.Unix(),
+		Alias:     (*Alias)(&r),
+	})
+}
+
+// NewInventoryReportItem builds a report item from a book.
+func NewInventoryReportItem(book Book, status string, removed bool) InventoryReportItem {
+	item := InventoryReportItem{
+		BookID:  book.ID,
+		Title:   book.Title,
+		Price:   book.Price,
+		Status:  status,
+		Removed: removed,
+	}
+
+	if book.Author != nil {
+		item.Author = book.Author.Surname + ", " + book.Author.Firstname
+	}
+
+	return item
+}
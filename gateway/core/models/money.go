@@ -0,0 +1,282 @@
+package models
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/abaldeweg/warehouse-server/gateway/currency"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultCurrency is the currency of branches that don't set one. Amounts
+// whose currency isn't known are formatted in it.
+const DefaultCurrency = "EUR"
+
+// Money is an amount in the minor units of its currency, e.g. an amount of
+// 1250 is 12.50 EUR but 1,250 JPY. Only the amount is stored, the currency
+// is the one of the branch the amount belongs to and is set after loading.
+// In JSON it is a decimal number in the main unit of the currency.
+type Money struct {
+	Amount   int64
+	Currency string
+
+	// decimal holds an amount read from JSON until its currency is known.
+	decimal string
+}
+
+var (
+	// ErrInvalidMoney is returned if an amount can't be parsed.
+	ErrInvalidMoney = errors.New("invalid amount of money")
+	// ErrMoneyWithoutCurrency is returned if an amount read from JSON is
+	// stored before its currency is known.
+	ErrMoneyWithoutCurrency = errors.New("amount of money without currency")
+)
+
+// NewMoney returns the amount in minor units of the currency.
+func NewMoney(amount int64, code string) Money {
+	return Money{Amount: amount, Currency: code}
+}
+
+// ParseMoney parses a decimal amount like "12.50", "12,5" or "-3" in the
+// currency without going through floats. Amounts with more decimals than
+// the currency has minor units are rounded half away from zero.
+func ParseMoney(s, code string) (Money, error) {
+	negative, whole, fraction, err := parseDecimal(s)
+	if err != nil {
+		return Money{}, err
+	}
+
+	units := minorUnits(code)
+	scale := int64(math.Pow10(units))
+
+	main, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || main > math.MaxInt64/scale-1 {
+		return Money{}, ErrInvalidMoney
+	}
+
+	fraction += strings.Repeat("0", units+1)
+	var minor int64
+	if units > 0 {
+		minor, _ = strconv.ParseInt(fraction[:units], 10, 64)
+	}
+	if fraction[units] >= '5' {
+		minor++
+	}
+
+	amount := main*scale + minor
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: code}, nil
+}
+
+// parseDecimal splits a decimal number into its sign and the digits before
+// and after the decimal separator, which may be a point or a comma.
+func parseDecimal(s string) (negative bool, whole, fraction string, err error) {
+	s = strings.TrimSpace(s)
+	negative = strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	s = strings.ReplaceAll(s, ",", ".")
+
+	whole, fraction, _ = strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return false, "", "", ErrInvalidMoney
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return false, "", "", ErrInvalidMoney
+		}
+	}
+	return negative, whole, fraction, nil
+}
+
+// minorUnits returns the minor units of the currency, of the default
+// currency if it is unknown.
+func minorUnits(code string) int {
+	if c, err := currency.Parse(cmp.Or(code, DefaultCurrency)); err == nil {
+		return c.MinorUnits
+	}
+	c, _ := currency.Parse(DefaultCurrency)
+	return c.MinorUnits
+}
+
+// In returns the amount in the currency. An amount read from JSON is parsed
+// with the minor units of the currency, other amounts are in minor units of
+// the currency already, e.g. when loaded from the database.
+func (m Money) In(code string) (Money, error) {
+	if m.decimal != "" {
+		return ParseMoney(m.decimal, code)
+	}
+	m.Currency = code
+	return m, nil
+}
+
+// Add returns the sum of amounts in the same currency.
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, Currency: cmp.Or(m.Currency, other.Currency)}
+}
+
+// AddPercent changes the amount by the percentage and rounds it to the minor
+// units of the currency.
+func (m Money) AddPercent(percentage float64) Money {
+	m.Amount = int64(math.Round(float64(m.Amount) * (1 + percentage/100)))
+	return m
+}
+
+// RoundToSteps rounds the amount to the nearest multiple of steps. Steps of
+// zero or less leave the amount unchanged.
+func (m Money) RoundToSteps(steps Money) Money {
+	if steps.Amount <= 0 {
+		return m
+	}
+	m.Amount = int64(math.Round(float64(m.Amount)/float64(steps.Amount))) * steps.Amount
+	return m
+}
+
+// String returns the amount with the decimals of the currency, e.g. "12.50"
+// for EUR or "1250" for JPY.
+func (m Money) String() string {
+	if m.decimal != "" {
+		return m.decimal
+	}
+
+	sign := ""
+	v := m.Amount
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	units := minorUnits(m.Currency)
+	if units == 0 {
+		return sign + strconv.FormatInt(v, 10)
+	}
+	scale := int64(math.Pow10(units))
+	minor := strconv.FormatInt(v%scale, 10)
+	return sign + strconv.FormatInt(v/scale, 10) + "." + strings.Repeat("0", units-len(minor)) + minor
+}
+
+// MarshalJSON encodes the amount as a decimal number.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts decimal numbers and strings like "12,50". The amount
+// is known once the currency is set with In. null and empty strings leave
+// the amount unchanged.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(strings.TrimSpace(string(data)), "\"")
+	if strings.EqualFold(s, "null") || s == "" {
+		return nil
+	}
+
+	negative, whole, fraction, err := parseDecimal(s)
+	if err != nil {
+		return err
+	}
+	decimal := whole
+	if fraction != "" {
+		decimal += "." + fraction
+	}
+	if negative {
+		decimal = "-" + decimal
+	}
+
+	*m = Money{decimal: decimal}
+	return nil
+}
+
+// Scan reads the amount from the database.
+func (m *Money) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+	case int64:
+		*m = Money{Amount: v}
+	case float64:
+		*m = Money{Amount: int64(math.Round(v))}
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		amount, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidMoney, v)
+		}
+		*m = Money{Amount: amount}
+	default:
+		return fmt.Errorf("%w: %T", ErrInvalidMoney, value)
+	}
+	return nil
+}
+
+// Value stores the amount in the database.
+func (m Money) Value() (driver.Value, error) {
+	if m.decimal != "" {
+		return nil, ErrMoneyWithoutCurrency
+	}
+	return m.Amount, nil
+}
+
+// GormDBDataType stores the amount like an int64.
+func (Money) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "integer"
+	}
+	return "bigint"
+}
+
+// NewValidator returns a validator that checks Money by its amount, e.g.
+// with gte=0.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		m := field.Interface().(Money)
+		if m.decimal != "" {
+			m, _ = ParseMoney(m.decimal, m.Currency)
+		}
+		return m.Amount
+	}, Money{})
+	return v
+}
+
+// branchCurrency returns the currency of the branch. The currencies are
+// loaded once per statement, e.g. for all books found at once.
+func branchCurrency(tx *gorm.DB, branchID *uint) (string, error) {
+	if branchID == nil {
+		return DefaultCurrency, nil
+	}
+
+	const key = "models:branch_currencies"
+	cache := map[uint]string{}
+	if v, ok := tx.InstanceGet(key); ok {
+		cache = v.(map[uint]string)
+	} else {
+		tx.InstanceSet(key, cache)
+	}
+
+	if code, ok := cache[*branchID]; ok {
+		return code, nil
+	}
+
+	var codes []string
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(&Branch{}).Where("id = ?", *branchID).Pluck("currency", &codes).Error; err != nil {
+		return "", err
+	}
+	code := DefaultCurrency
+	if len(codes) > 0 && codes[0] != "" {
+		code = codes[0]
+	}
+	cache[*branchID] = code
+	return code, nil
+}
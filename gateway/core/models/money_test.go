@@ -0,0 +1,255 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		input    string
+		currency string
+		expected int64
+		valid    bool
+	}{
+		{"12.50", "EUR", 1250, true},
+		{"12,5", "EUR", 1250, true},
+		{"12", "EUR", 1200, true},
+		{",99", "EUR", 99, true},
+		{"12.345", "EUR", 1235, true},
+		{"12.344", "EUR", 1234, true},
+		{"-12.345", "EUR", -1235, true},
+		{"1234.5", "JPY", 1235, true},
+		{"1234", "JPY", 1234, true},
+		{"1.2345", "KWD", 1235, true},
+		{"1,5", "KWD", 1500, true},
+		{"12.50", "", 1250, true},
+		{"", "EUR", 0, false},
+		{"abc", "EUR", 0, false},
+		{"1.2.3", "EUR", 0, false},
+		{"1 000", "EUR", 0, false},
+		{"99999999999999999999", "EUR", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input+" "+tc.currency, func(t *testing.T) {
+			m, err := ParseMoney(tc.input, tc.currency)
+			if !tc.valid {
+				assert.ErrorIs(t, err, ErrInvalidMoney)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, NewMoney(tc.expected, tc.currency), m)
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	testCases := []struct {
+		money    Money
+		expected string
+	}{
+		{NewMoney(1250, "EUR"), "12.50"},
+		{NewMoney(5, "EUR"), "0.05"},
+		{NewMoney(-5, "EUR"), "-0.05"},
+		{NewMoney(0, "EUR"), "0.00"},
+		{NewMoney(1250, "JPY"), "1250"},
+		{NewMoney(1250, "KWD"), "1.250"},
+		{NewMoney(1250, ""), "12.50"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.money.String())
+		})
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]Money{"eur": NewMoney(1250, "EUR"), "jpy": NewMoney(1250, "JPY"), "kwd": NewMoney(-1, "KWD")})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"eur": 12.50, "jpy": 1250, "kwd": -0.001}`, string(data))
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		input    string
+		currency string
+		expected int64
+		valid    bool
+	}{
+		{`12.5`, "EUR", 1250, true},
+		{`"12.50"`, "EUR", 1250, true},
+		{`"12,50"`, "EUR", 1250, true},
+		{`" 12,5 "`, "EUR", 1250, true},
+		{`-3`, "EUR", -300, true},
+		{`0.005`, "EUR", 1, true},
+		{`1250`, "JPY", 1250, true},
+		{`"1250,4"`, "JPY", 1250, true},
+		{`1.25`, "KWD", 1250, true},
+		{`"abc"`, "EUR", 0, false},
+		{`"12,50 €"`, "EUR", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input+" "+tc.currency, func(t *testing.T) {
+			var m Money
+			err := json.Unmarshal([]byte(tc.input), &m)
+			if !tc.valid {
+				assert.ErrorIs(t, err, ErrInvalidMoney)
+				return
+			}
+			assert.NoError(t, err)
+
+			_, err = m.Value()
+			assert.ErrorIs(t, err, ErrMoneyWithoutCurrency)
+
+			m, err = m.In(tc.currency)
+			assert.NoError(t, err)
+			assert.Equal(t, NewMoney(tc.expected, tc.currency), m)
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSONKeepsValue(t *testing.T) {
+	for _, input := range []string{`null`, `""`} {
+		m := NewMoney(1250, "EUR")
+		assert.NoError(t, json.Unmarshal([]byte(input), &m))
+		assert.Equal(t, NewMoney(1250, "EUR"), m)
+	}
+}
+
+func TestMoneyIn(t *testing.T) {
+	m, err := NewMoney(1250, "").In("JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(1250, "JPY"), m)
+}
+
+func TestMoneyOrStringUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected *int64
+		valid    bool
+	}{
+		{`12.5`, ptr(int64(1250)), true},
+		{`"12,50"`, ptr(int64(1250)), true},
+		{`"7"`, ptr(int64(700)), true},
+		{`null`, nil, true},
+		{`""`, nil, true},
+		{`"-1,50"`, nil, false},
+		{`-1`, nil, false},
+		{`"abc"`, nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			var m MoneyOrString
+			err := json.Unmarshal([]byte(tc.input), &m)
+			if !tc.valid {
+				assert.ErrorIs(t, err, ErrInvalidMoney)
+				return
+			}
+			assert.NoError(t, err)
+
+			if tc.expected == nil {
+				assert.Nil(t, m.Val)
+				return
+			}
+			v, err := m.Val.In("EUR")
+			assert.NoError(t, err)
+			assert.Equal(t, *tc.expected, v.Amount)
+		})
+	}
+}
+
+func TestRoundToSteps(t *testing.T) {
+	testCases := []struct {
+		amount   int64
+		steps    int64
+		expected int64
+	}{
+		{123, 0, 123},
+		{123, -50, 123},
+		{126, 50, 150},
+		{124, 50, 100},
+		{125, 50, 150},
+		{204, 10, 200},
+		{206, 10, 210},
+		{749, 25, 750},
+		{300, 100, 300},
+		{-126, 50, -150},
+	}
+
+	for _, tc := range testCases {
+		m := NewMoney(tc.amount, "EUR").RoundToSteps(NewMoney(tc.steps, "EUR"))
+		assert.Equal(t, NewMoney(tc.expected, "EUR"), m)
+	}
+}
+
+func TestAddPercent(t *testing.T) {
+	testCases := []struct {
+		amount     int64
+		currency   string
+		percentage float64
+		expected   int64
+	}{
+		{1000, "EUR", 10, 1100},
+		{333, "EUR", 10, 366},
+		{335, "EUR", 10, 369},
+		{1000, "EUR", -50, 500},
+		{999, "JPY", 15, 1149},
+		{1001, "KWD", -33.3, 668},
+	}
+
+	for _, tc := range testCases {
+		m := NewMoney(tc.amount, tc.currency).AddPercent(tc.percentage)
+		assert.Equal(t, NewMoney(tc.expected, tc.currency), m)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	testCases := []struct {
+		value    any
+		expected int64
+		valid    bool
+	}{
+		{int64(1250), 1250, true},
+		{float64(1250), 1250, true},
+		{[]byte("1250"), 1250, true},
+		{"1250", 1250, true},
+		{nil, 0, true},
+		{"12.50", 0, false},
+		{true, 0, false},
+	}
+
+	for _, tc := range testCases {
+		var m Money
+		err := m.Scan(tc.value)
+		if !tc.valid {
+			assert.ErrorIs(t, err, ErrInvalidMoney)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, m.Amount)
+	}
+}
+
+func TestNewValidator(t *testing.T) {
+	type item struct {
+		Price Money `validate:"gte=0"`
+	}
+
+	v := NewValidator()
+	assert.NoError(t, v.Struct(item{Price: NewMoney(0, "EUR")}))
+	assert.Error(t, v.Struct(item{Price: NewMoney(-1, "EUR")}))
+
+	var pending item
+	assert.NoError(t, json.Unmarshal([]byte(`{"Price": "-0,01"}`), &pending))
+	assert.Error(t, v.Struct(pending))
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// PriceRule represents one rule of the structured price list of a branch.
+// Empty criteria match every book. A rule with a base price sets the starting
+// price, percentage and amount modify it.
+type PriceRule struct {
+	ID          uint    `json:"id" gorm:"primaryKey;autoIncrement;->"`
+	BranchID    uint    `json:"branch_id" gorm:"index"`
+	Branch      Branch  `json:"-" gorm:"foreignKey:BranchID"`
+	Name        string  `json:"name" gorm:"type:varchar(255)" validate:"required,min=1,max=255"`
+	Position    int     `json:"position" gorm:"default:0"`
+	FormatID    *uint   `json:"format_id" gorm:"default:null"`
+	ConditionID *uint   `json:"cond_id" gorm:"column:cond_id;default:null"`
+	GenreID     *uint   `json:"genre_id" gorm:"default:null"`
+	YearFrom    *int    `json:"yearFrom" gorm:"default:null" validate:"omitempty,gte=1000,lte=9999"`
+	YearTo      *int    `json:"yearTo" gorm:"default:null" validate:"omitempty,gte=1000,lte=9999"`
+	BasePrice   *Money  `json:"basePrice" gorm:"default:null" validate:"omitempty,gte=0"`
+	Percentage  float64 `json:"percentage" gorm:"type:decimal(10,2);default:0.00" validate:"gte=-100"`
+	Amount      Money   `json:"amount" gorm:"default:0"`
+}
+
+// TableName overrides the default table name for PriceRule model.
+func (PriceRule) TableName() string {
+	return "price_rule"
+}
+
+// AfterFind is a GORM hook that sets the currency of the amounts.
+func (p *PriceRule) AfterFind(tx *gorm.DB) error {
+	return p.setCurrency(tx)
+}
+
+// BeforeSave is a GORM hook that sets the currency of the amounts read from
+// JSON.
+func (p *PriceRule) BeforeSave(tx *gorm.DB) error {
+	return p.setCurrency(tx)
+}
+
+// setCurrency sets the currency of the branch on the amounts of the rule.
+func (p *PriceRule) setCurrency(tx *gorm.DB) error {
+	code, err := branchCurrency(tx, &p.BranchID)
+	if err != nil {
+		return err
+	}
+	if p.BasePrice != nil {
+		base, err := p.BasePrice.In(code)
+		if err != nil {
+			return err
+		}
+		p.BasePrice = &base
+	}
+	p.Amount, err = p.Amount.In(code)
+	return err
+}
+
+// Validate validates the PriceRule model based on defined rules.
+func (p *PriceRule) Validate(db *gorm.DB) bool {
+	validate := NewValidator()
+	if err := validate.StructExcept(p, "Branch"); err != nil {
+		return false
+	}
+
+	if p.YearFrom != nil && p.YearTo != nil && *p.YearFrom > *p.YearTo {
+		return false
+	}
+
+	return true
+}
+
+// Matches reports whether the rule applies to the book.
+func (p *PriceRule) Matches(book *Book) bool {
+	if p.FormatID != nil && *p.FormatID != book.FormatID {
+		return false
+	}
+	if p.ConditionID != nil && (book.ConditionID == nil || *p.ConditionID != *book.ConditionID) {
+		return false
+	}
+	if p.GenreID != nil && (book.GenreID == nil || *p.GenreID != *book.GenreID) {
+		return false
+	}
+	if p.YearFrom != nil && book.ReleaseYear < *p.YearFrom {
+		return false
+	}
+	if p.YearTo != nil && book.ReleaseYear > *p.YearTo {
+		return false
+	}
+	return true
+}
+
+// Specificity returns the number of criteria of the rule.
+func (p *PriceRule) Specificity() int {
+	n := 0
+	if p.FormatID != nil {
+		n++
+	}
+	if p.ConditionID != nil {
+		n++
+	}
+	if p.GenreID != nil {
+		n++
+	}
+	if p.YearFrom != nil || p.YearTo != nil {
+		n++
+	}
+	return n
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"cmp"
+
 	"github.com/abaldeweg/warehouse-server/gateway/cover"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -8,38 +10,52 @@ import (
 
 // PublicBook represents a public book entity.
 type PublicBook struct {
-	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;->"`
-	Currency         string     `json:"currency" gorm:"-"`
-	Title            string     `json:"title" binding:"required"`
-	Subtitle         string     `json:"subtitle" validate:"max=255"`
-	AuthorID         *uint      `json:"-" gorm:"index;default:null"`
-	Author           *Author    `json:"-" gorm:"foreignKey:AuthorID"`
-	AuthorFirstname  string     `json:"authorFirstname" gorm:"-"`
-	AuthorSurname    string     `json:"authorSurname" gorm:"-"`
-	BranchID         *int       `json:"-"`
-	Branch           *Branch    `json:"-" gorm:"foreignKey:BranchID"`
-	ShortDescription *string    `json:"shortDescription"`
-	GenreID          *uint      `json:"-" gorm:"index;default:null"`
-	Genre            *Genre     `json:"-" gorm:"foreignKey:GenreID"`
-	GenreName        string     `json:"genre" gorm:"-"`
-	BranchName       string     `json:"branchName" gorm:"-"`
-	BranchOrdering   string     `json:"branchOrdering" gorm:"-"`
-	Price            float32    `json:"price" gorm:"default:0.00"`
-	ReleaseYear      int        `json:"releaseYear"`
-	Condition        *Condition `json:"-" gorm:"foreignKey:ConditionID"`
-	ConditionID      *uint      `json:"-" gorm:"default:null"`
-	Cond             string     `json:"cond" binding:"required" gorm:"-"`
-	Format           *Format    `json:"-" gorm:"foreignKey:FormatID"`
-	FormatID         uint       `json:"-" gorm:""`
-	FormatName       string     `json:"format_name" gorm:"-"`
-	BranchCart       bool       `json:"branchCart" gorm:"-"`
-	Sold             bool       `json:"-" gorm:"default:false"`
-	Removed          bool       `json:"-" gorm:"default:false"`
-	Reserved         bool       `json:"-" gorm:"default:false"`
-	Recommendation   bool       `json:"-" gorm:"default:false"`
-	CoverS           string     `json:"cover_s" gorm:"default:null"`
-	CoverM           string     `json:"cover_m" gorm:"default:null"`
-	CoverL           string     `json:"cover_l" gorm:"default:null"`
+	ID                 uuid.UUID           `json:"id" gorm:"type:uuid;primaryKey;->"`
+	Currency           string              `json:"currency" gorm:"-"`
+	Title              string              `json:"title" binding:"required"`
+	Subtitle           string              `json:"subtitle" validate:"max=255"`
+	AuthorID           *uint               `json:"-" gorm:"index;default:null"`
+	Author             *Author             `json:"-" gorm:"foreignKey:AuthorID"`
+	AuthorFirstname    string              `json:"authorFirstname" gorm:"-"`
+	AuthorSurname      string              `json:"authorSurname" gorm:"-"`
+	Contributors       []BookContributor   `json:"-" gorm:"foreignKey:BookID"`
+	PublicContributors []PublicContributor `json:"contributors" gorm:"-"`
+	BranchID           *int                `json:"-"`
+	Branch             *Branch             `json:"-" gorm:"foreignKey:BranchID"`
+	ShortDescription   *string             `json:"shortDescription"`
+	GenreID            *uint               `json:"-" gorm:"index;default:null"`
+	Genre              *Genre              `json:"-" gorm:"foreignKey:GenreID"`
+	GenreName          string              `json:"genre" gorm:"-"`
+	BranchName         string              `json:"branchName" gorm:"-"`
+	BranchOrdering     string              `json:"branchOrdering" gorm:"-"`
+	Price              Money               `json:"price" gorm:"default:0"`
+	PriceFormatted     string              `json:"priceFormatted" gorm:"-"`
+	Converted          *PublicPrice        `json:"converted,omitempty" gorm:"-"`
+	ReleaseYear        int                 `json:"releaseYear"`
+	Condition          *Condition          `json:"-" gorm:"foreignKey:ConditionID"`
+	ConditionID        *uint               `json:"-" gorm:"default:null"`
+	Cond               string              `json:"cond" binding:"required" gorm:"-"`
+	Format             *Format             `json:"-" gorm:"foreignKey:FormatID"`
+	FormatID           uint                `json:"-" gorm:""`
+	FormatName         string              `json:"format_name" gorm:"-"`
+	BranchCart         bool                `json:"branchCart" gorm:"-"`
+	Sold               bool                `json:"-" gorm:"default:false"`
+	Removed            bool                `json:"-" gorm:"default:false"`
+	Reserved           bool                `json:"-" gorm:"default:false"`
+	Recommendation     bool                `json:"-" gorm:"default:false"`
+	CoverS             string              `json:"cover_s" gorm:"default:null"`
+	CoverM             string              `json:"cover_m" gorm:"default:null"`
+	CoverL             string              `json:"cover_l" gorm:"default:null"`
+	Images             []BookImage         `json:"images" gorm:"foreignKey:BookID"`
+}
+
+// PublicBranchBooks are the books of a branch found by a public search.
+type PublicBranchBooks struct {
+	BranchID   uint         `json:"branchId"`
+	BranchName string       `json:"branchName"`
+	Currency   string       `json:"currency"`
+	Counter    int64        `json:"counter"`
+	Books      []PublicBook `json:"books"`
 }
 
 // TableName overrides the default table name for PublicBook model.
@@ -58,14 +74,43 @@ func (book *PublicBook) AfterFind(tx *gorm.DB) (err error) {
 	if book.Branch != nil {
 		book.Currency = book.Branch.Currency
 		book.BranchName = book.Branch.Name
-		book.BranchOrdering = book.Branch.Ordering
+		book.BranchOrdering = book.Branch.Ordering.Text
 		book.BranchCart = book.Branch.Cart
+	} else if book.BranchID != nil {
+		branchID := uint(*book.BranchID)
+		if book.Currency, err = branchCurrency(tx, &branchID); err != nil {
+			return err
+		}
+	}
+	if book.Price, err = book.Price.In(cmp.Or(book.Currency, DefaultCurrency)); err != nil {
+		return err
 	}
 
+	book.PublicContributors = []PublicContributor{}
+	for _, c := range contributors(book.Contributors, book.AuthorID, book.Author) {
+		if c.Author == nil {
+			continue
+		}
+		book.PublicContributors = append(book.PublicContributors, PublicContributor{
+			Firstname: c.Author.Firstname,
+			Surname:   c.Author.Surname,
+			Role:      c.Role,
+		})
+	}
+
+	// The first author is kept in the fields of the single author books had
+	// before.
 	if book.Author != nil {
 		book.AuthorFirstname = book.Author.Firstname
 		book.AuthorSurname = book.Author.Surname
 	}
+	for _, c := range book.PublicContributors {
+		if c.Role == ContributorAuthor {
+			book.AuthorFirstname = c.Firstname
+			book.AuthorSurname = c.Surname
+			break
+		}
+	}
 
 	if book.Genre != nil {
 		book.GenreName = book.Genre.Name
@@ -79,8 +124,12 @@ func (book *PublicBook) AfterFind(tx *gorm.DB) (err error) {
 		book.FormatName = book.Format.Name
 	}
 
-	book.CoverS = cover.ShowCover("s", book.ID)
-	book.CoverM = cover.ShowCover("m", book.ID)
-	book.CoverL = cover.ShowCover("l", book.ID)
+	if book.Images == nil {
+		book.Images = []BookImage{}
+	}
+
+	book.CoverS = cover.URL(book.ID, "s")
+	book.CoverM = cover.URL(book.ID, "m")
+	book.CoverL = cover.URL(book.ID, "l")
 	return
 }
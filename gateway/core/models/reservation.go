@@ -10,18 +10,19 @@ import (
 
 // Reservation represents a reservation.
 type Reservation struct {
-	ID         string    `json:"id" gorm:"primaryKey"`
-	BranchID   uint      `json:"branch_id" gorm:"index"`
-	Branch     Branch    `json:"branch" gorm:"foreignKey:BranchID"`
-	CreatedAt  time.Time `json:"createdAt"`
-	Notes      string    `json:"notes"`
-	Books      []*Book   `json:"books" gorm:"foreignKey:ReservationID"`
-	Salutation string    `json:"salutation" validate:"required,oneof=m f d"`
-	Firstname  string    `json:"firstname" validate:"required,max=255"`
-	Surname    string    `json:"surname" validate:"required,max=255"`
-	Mail       string    `json:"mail" validate:"required,email,max=255"`
-	Phone      string    `json:"phone" validate:"max=255"`
-	Open       bool      `json:"open" gorm:"default:true"`
+	ID          string     `json:"id" gorm:"primaryKey"`
+	BranchID    uint       `json:"branch_id" gorm:"index"`
+	Branch      Branch     `json:"branch" gorm:"foreignKey:BranchID"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	PickupUntil *time.Time `json:"pickupUntil" gorm:"default:null"`
+	Notes       string     `json:"notes"`
+	Books       []*Book    `json:"books" gorm:"foreignKey:ReservationID"`
+	Salutation  string     `json:"salutation" validate:"required,oneof=m f d"`
+	Firstname   string     `json:"firstname" validate:"required,max=255"`
+	Surname     string     `json:"surname" validate:"required,max=255"`
+	Mail        string     `json:"mail" validate:"required,email,max=255"`
+	Phone       string     `json:"phone" validate:"max=255"`
+	Open        bool       `json:"open" gorm:"default:true"`
 }
 
 // ReservationForm represents a form for creating or updating a reservation.
@@ -61,11 +62,18 @@ func (r *Reservation) Validate(db *gorm.DB) bool {
 // MarshalJSON customizes the JSON output for Reservation.
 func (r Reservation) MarshalJSON() ([]byte, error) {
 	type Alias Reservation
+	var pickupUntil *int64
+	if r.PickupUntil != nil {
+		t := r.PickupUntil.Unix()
+		pickupUntil = &t
+	}
 	return json.Marshal(&struct {
-		CreatedAt int64 `json:"createdAt"`
+		CreatedAt   int64  `json:"createdAt"`
+		PickupUntil *int64 `json:"pickupUntil"`
 		*Alias
 	}{
-		CreatedAt: r.CreatedAt.Unix(),
-		Alias:     (*Alias)(&r),
+		CreatedAt:   r.CreatedAt.Unix(),
+		PickupUntil: pickupUntil,
+		Alias:       (*Alias)(&r),
 	})
 }
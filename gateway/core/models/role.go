@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Role is a set of permissions configured by a branch. A role replaces the
+// default role of the same name, e.g. ROLE_USER, in its branch.
+type Role struct {
+	ID          uint     `json:"id" gorm:"primaryKey;autoIncrement;->"`
+	BranchID    uint     `json:"branch_id" gorm:"uniqueIndex:idx_role_branch_name"`
+	Name        string   `json:"name" validate:"required,max=64" gorm:"uniqueIndex:idx_role_branch_name;size:64"`
+	Permissions []string `json:"permissions" validate:"dive,required" gorm:"serializer:json;type:text"`
+}
+
+// TableName overrides the default table name.
+func (Role) TableName() string {
+	return "role"
+}
+
+// Validate validates the Role model and checks that the permissions exist.
+func (r *Role) Validate(db *gorm.DB) bool {
+	validate := validator.New()
+	if err := validate.Struct(r); err != nil {
+		return false
+	}
+
+	for _, p := range r.Permissions {
+		if !auth.IsPermission(p) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// States of a transfer.
+const (
+	TransferDraft     = "draft"
+	TransferShipped   = "shipped"
+	TransferReceived  = "received"
+	TransferCancelled = "cancelled"
+)
+
+// Transfer moves books from one branch to another. While shipped, the books
+// belong to no branch.
+type Transfer struct {
+	ID             uint           `json:"id" gorm:"primaryKey;autoIncrement;->"`
+	SourceBranchID uint           `json:"source_branch_id" gorm:"index"`
+	SourceBranch   *Branch        `json:"source_branch,omitempty" gorm:"foreignKey:SourceBranchID"`
+	TargetBranchID uint           `json:"target_branch_id" gorm:"index" validate:"required"`
+	TargetBranch   *Branch        `json:"target_branch,omitempty" gorm:"foreignKey:TargetBranchID"`
+	Status         string         `json:"status" gorm:"type:varchar(16);index"`
+	Note           string         `json:"note" gorm:"type:varchar(255)" validate:"max=255"`
+	CreatedBy      int            `json:"created_by"`
+	CreatedAt      time.Time      `json:"created_at"`
+	ShippedBy      *int           `json:"shipped_by"`
+	ShippedAt      *time.Time     `json:"shipped_at"`
+	ReceivedBy     *int           `json:"received_by"`
+	ReceivedAt     *time.Time     `json:"received_at"`
+	CancelledAt    *time.Time     `json:"cancelled_at"`
+	Items          []TransferItem `json:"items" gorm:"foreignKey:TransferID"`
+}
+
+// TransferItem is a book of a transfer. The references of the book in the
+// source branch are kept when shipping, the ones in the target branch when
+// receiving.
+type TransferItem struct {
+	ID                uint      `json:"-" gorm:"primaryKey;autoIncrement;->"`
+	TransferID        uint      `json:"-" gorm:"index"`
+	BookID            uuid.UUID `json:"book_id" gorm:"type:uuid;index"`
+	Title             string    `json:"title" gorm:"type:varchar(255)"`
+	Price             Money     `json:"price"`
+	SourceGenreID     *uint     `json:"source_genre_id"`
+	SourceConditionID *uint     `json:"source_cond_id"`
+	SourceFormatID    uint      `json:"source_format_id"`
+	SourceTagIDs      []uint    `json:"source_tag_ids" gorm:"serializer:json;type:text"`
+	TargetGenreID     *uint     `json:"target_genre_id"`
+	TargetConditionID *uint     `json:"target_cond_id"`
+	TargetFormatID    *uint     `json:"target_format_id"`
+	TargetTagIDs      []uint    `json:"target_tag_ids" gorm:"serializer:json;type:text"`
+}
+
+// TransferCreate is the payload to create or update a draft transfer.
+type TransferCreate struct {
+	TargetBranchID uint     `json:"target_branch_id" validate:"required"`
+	Note           string   `json:"note" validate:"max=255"`
+	Books          []string `json:"books" validate:"required,min=1,max=1000,dive,uuid"`
+}
+
+// TransferMapping maps the genres, conditions, formats and tags of the
+// source branch, by ID, to the ones of the target branch.
+type TransferMapping struct {
+	Genres     map[uint]uint `json:"genres"`
+	Conditions map[uint]uint `json:"conditions"`
+	Formats    map[uint]uint `json:"formats"`
+	Tags       map[uint]uint `json:"tags"`
+}
+
+// TransferReference is a genre, condition, format or tag of the source branch
+// with the proposed counterpart in the target branch.
+type TransferReference struct {
+	ID         uint    `json:"id"`
+	Name       string  `json:"name"`
+	TargetID   *uint   `json:"target_id"`
+	TargetName *string `json:"target_name"`
+}
+
+// TransferReferences lists the references of the books of a transfer.
+type TransferReferences struct {
+	Genres     []TransferReference `json:"genres"`
+	Conditions []TransferReference `json:"conditions"`
+	Formats    []TransferReference `json:"formats"`
+	Tags       []TransferReference `json:"tags"`
+}
+
+// TableName overrides the default table name.
+func (Transfer) TableName() string {
+	return "transfer"
+}
+
+// TableName overrides the default table name.
+func (TransferItem) TableName() string {
+	return "transfer_item"
+}
+
+// AfterFind is a GORM hook that sets the currency of the source branch on
+// the prices of the items.
+func (t *Transfer) AfterFind(tx *gorm.DB) error {
+	code, err := branchCurrency(tx, &t.SourceBranchID)
+	if err != nil {
+		return err
+	}
+	for i := range t.Items {
+		t.Items[i].Price.Currency = code
+	}
+	return nil
+}
+
+// Validate validates the Transfer model.
+func (t *Transfer) Validate(db *gorm.DB) bool {
+	validate := validator.New()
+	if err := validate.StructExcept(t, "SourceBranch", "TargetBranch", "Items"); err != nil {
+		return false
+	}
+
+	return t.SourceBranchID != t.TargetBranchID
+}
+
+// Validate validates the payload.
+func (t *TransferCreate) Validate(v *validator.Validate) error {
+	return v.Struct(t)
+}
@@ -1,10 +1,23 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/names"
+	"github.com/abaldeweg/warehouse-server/gateway/search"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// ErrInvalidMerge is returned if the target of a merge is one of its sources.
+var ErrInvalidMerge = errors.New("the target can't be merged into itself")
+
+// maxNameDistance is the number of edits names of duplicates may differ by.
+const maxNameDistance = 2
+
 // AuthorRepository struct for author repository.
 type AuthorRepository struct {
 	db *gorm.DB
@@ -17,9 +30,18 @@ func NewAuthorRepository(db *gorm.DB) *AuthorRepository {
 	return &AuthorRepository{db: db}
 }
 
-// FindAllByTerm returns all authors by term.
+// FindAllByTerm returns all authors by term, best matches first.
 func (r *AuthorRepository) FindAllByTerm(term string) ([]models.Author, error) {
 	var authors []models.Author
+	if ids, _, ok := searchIndex(search.Query{Kind: search.Authors, Text: term, Limit: limit}); ok {
+		if len(ids) == 0 {
+			return []models.Author{}, nil
+		}
+		result := r.db.Where("id IN ?", ids).Find(&authors)
+		sortByRank(authors, ids, func(a models.Author) string { return fmt.Sprint(a.ID) })
+		return authors, result.Error
+	}
+
 	result := r.db.Where("firstname LIKE ? OR surname LIKE ? OR CONCAT(firstname, ' ', surname) LIKE ? OR CONCAT(surname, ' ', firstname) LIKE ? OR CONCAT(firstname, ',', surname) LIKE ? OR CONCAT(firstname, ', ', surname) LIKE ?", "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%").Limit(limit).Find(&authors)
 	return authors, result.Error
 }
@@ -43,8 +65,155 @@ func (r *AuthorRepository) Update(author *models.Author) error {
 	return result.Error
 }
 
-// Delete an author.
+// Delete an author and remove it from the contributors of books.
 func (r *AuthorRepository) Delete(id uint64) error {
-	result := r.db.Delete(&models.Author{}, id)
-	return result.Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("author_id = ?", id).Delete(&models.BookContributor{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Author{}, id).Error
+	})
+}
+
+// FindByName returns the author with the same name, regardless of the order
+// of the words, case and diacritics.
+func (r *AuthorRepository) FindByName(firstname, surname string) (*models.Author, error) {
+	key := names.Key(firstname, surname)
+	if key == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	// The index finds names with other diacritics, the database the authors
+	// created since the index was updated.
+	query := r.db.Where("surname LIKE ? OR firstname LIKE ?", "%"+surname+"%", "%"+surname+"%")
+	if ids, _, ok := searchIndex(search.Query{Kind: search.Authors, Text: key, Limit: limit}); ok && len(ids) > 0 {
+		query = query.Or("id IN ?", ids)
+	}
+
+	var candidates []models.Author
+	if err := query.Order("id").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	for _, a := range candidates {
+		if names.AuthorKey(a) == key {
+			return &a, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// FindOrCreate returns the author with the same name or creates it.
+func (r *AuthorRepository) FindOrCreate(firstname, surname string) (*models.Author, error) {
+	author, err := r.FindByName(firstname, surname)
+	if err == nil {
+		return author, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	author = &models.Author{Firstname: firstname, Surname: surname}
+	author.Normalize()
+	if err := r.db.Create(author).Error; err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+// FindDuplicates returns groups of authors whose names are the same or
+// nearly the same.
+func (r *AuthorRepository) FindDuplicates() ([][]models.Author, error) {
+	var authors []models.Author
+	if err := r.db.Order("id").Find(&authors).Error; err != nil {
+		return nil, err
+	}
+	return names.Duplicates(authors, maxNameDistance), nil
+}
+
+// Merge moves the books of the sources to the target and deletes the
+// sources. It returns the number of books moved.
+func (r *AuthorRepository) Merge(target uint64, sources []uint64) (int, error) {
+	if slices.Contains(sources, target) {
+		return 0, ErrInvalidMerge
+	}
+
+	var moved int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var found int64
+		if err := tx.Model(&models.Author{}).Where("id IN ?", append([]uint64{target}, sources...)).Count(&found).Error; err != nil {
+			return err
+		}
+		if int(found) != len(sources)+1 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var books []uuid.UUID
+		if err := tx.Model(&models.Book{}).Where("author_id IN ?", sources).Pluck("id", &books).Error; err != nil {
+			return err
+		}
+		if len(books) > 0 {
+			if err := tx.Model(&models.Book{}).Where("id IN ?", books).Update("author_id", target).Error; err != nil {
+				return err
+			}
+		}
+
+		contributed, err := r.mergeContributors(tx, target, sources)
+		if err != nil {
+			return err
+		}
+		for _, id := range contributed {
+			if !slices.Contains(books, id) {
+				books = append(books, id)
+			}
+		}
+		moved = len(books)
+
+		return tx.Delete(&models.Author{}, sources).Error
+	})
+
+	return moved, err
+}
+
+// mergeContributors replaces the sources by the target in the contributors
+// of books. A book keeps the target once per role. It returns the books
+// changed.
+func (r *AuthorRepository) mergeContributors(tx *gorm.DB, target uint64, sources []uint64) ([]uuid.UUID, error) {
+	var contributors []models.BookContributor
+	if err := tx.Where("author_id IN ?", append([]uint64{target}, sources...)).Order("position").Find(&contributors).Error; err != nil {
+		return nil, err
+	}
+
+	type role struct {
+		book uuid.UUID
+		role string
+	}
+	taken := map[role]bool{}
+	for _, c := range contributors {
+		if c.AuthorID == target {
+			taken[role{c.BookID, c.Role}] = true
+		}
+	}
+
+	books := []uuid.UUID{}
+	for _, c := range contributors {
+		if c.AuthorID == target {
+			continue
+		}
+		books = append(books, c.BookID)
+
+		query := tx.Model(&models.BookContributor{}).Where("book_id = ? AND author_id = ? AND role = ?", c.BookID, c.AuthorID, c.Role)
+		if taken[role{c.BookID, c.Role}] {
+			if err := query.Delete(&models.BookContributor{}).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := query.Update("author_id", target).Error; err != nil {
+			return nil, err
+		}
+		taken[role{c.BookID, c.Role}] = true
+	}
+
+	return books, nil
 }
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestAuthorRepositoryMerge(t *testing.T) {
+	db := testDB(t)
+	repo := NewAuthorRepository(db)
+
+	branch := create(t, db, &models.Branch{Name: "Branch"})
+	goethe := create(t, db, &models.Author{Firstname: "Johann Wolfgang", Surname: "Goethe"})
+	goethe2 := create(t, db, &models.Author{Firstname: "Johann Wolfgang", Surname: "Göthe"})
+	goethe3 := create(t, db, &models.Author{Firstname: "J. W.", Surname: "Goethe"})
+	schiller := create(t, db, &models.Author{Firstname: "Friedrich", Surname: "Schiller"})
+
+	book := func(title string, authorID uint64, contributors ...models.BookContributor) *models.Book {
+		id := uint(authorID)
+		b := create(t, db, &models.Book{ID: uuid.New(), BranchID: &branch.ID, Title: title, AuthorID: &id, Price: models.NewMoney(100, "EUR")})
+		for i, c := range contributors {
+			c.BookID = b.ID
+			c.Position = i
+			create(t, db, &c)
+		}
+		return b
+	}
+
+	// written by a duplicate only
+	faust := book("Faust", goethe2.ID,
+		models.BookContributor{AuthorID: goethe2.ID, Role: models.ContributorAuthor})
+	// written by the target and a duplicate, edited by another duplicate
+	briefe := book("Briefwechsel", goethe.ID,
+		models.BookContributor{AuthorID: goethe.ID, Role: models.ContributorAuthor},
+		models.BookContributor{AuthorID: schiller.ID, Role: models.ContributorAuthor},
+		models.BookContributor{AuthorID: goethe3.ID, Role: models.ContributorAuthor},
+		models.BookContributor{AuthorID: goethe3.ID, Role: models.ContributorEditor})
+	// not affected
+	book("Die Räuber", schiller.ID,
+		models.BookContributor{AuthorID: schiller.ID, Role: models.ContributorAuthor})
+
+	_, err := repo.Merge(goethe.ID, []uint64{goethe.ID})
+	assert.ErrorIs(t, err, ErrInvalidMerge)
+	_, err = repo.Merge(goethe.ID, []uint64{42})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	moved, err := repo.Merge(goethe.ID, []uint64{goethe2.ID, goethe3.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+
+	var authorID uint
+	require.NoError(t, db.Model(&models.Book{}).Where("id = ?", faust.ID).Pluck("author_id", &authorID).Error)
+	assert.Equal(t, uint(goethe.ID), authorID)
+
+	contributors := func(b *models.Book) []models.BookContributor {
+		var list []models.BookContributor
+		require.NoError(t, db.Where("book_id = ?", b.ID).Order("position").Find(&list).Error)
+		for i := range list {
+			list[i].BookID = uuid.Nil
+		}
+		return list
+	}
+	assert.Equal(t, []models.BookContributor{
+		{AuthorID: goethe.ID, Role: models.ContributorAuthor, Position: 0},
+	}, contributors(faust))
+	assert.Equal(t, []models.BookContributor{
+		{AuthorID: goethe.ID, Role: models.ContributorAuthor, Position: 0},
+		{AuthorID: schiller.ID, Role: models.ContributorAuthor, Position: 1},
+		{AuthorID: goethe.ID, Role: models.ContributorEditor, Position: 3},
+	}, contributors(briefe))
+
+	var count int64
+	require.NoError(t, db.Model(&models.Author{}).Where("id IN ?", []uint64{goethe2.ID, goethe3.ID}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestBookRepositoryUpdateContributors(t *testing.T) {
+	db := testDB(t)
+
+	branch := create(t, db, &models.Branch{Name: "Branch"})
+	goethe := create(t, db, &models.Author{Firstname: "Johann Wolfgang", Surname: "Goethe"})
+	book := create(t, db, &models.Book{ID: uuid.New(), BranchID: &branch.ID, Title: "Briefwechsel", Price: models.NewMoney(100, "EUR")})
+
+	book.Contributors = []models.BookContributor{
+		{Author: &models.Author{Firstname: "Friedrich", Surname: "Schiller"}, Role: models.ContributorAuthor},
+		{Author: &models.Author{Firstname: "Johann Wolfgang", Surname: "Goethe"}, Role: models.ContributorAuthor},
+		{AuthorID: goethe.ID, Role: models.ContributorAuthor},
+		{AuthorID: goethe.ID, Role: models.ContributorEditor},
+	}
+	require.NoError(t, NewBookRepository(db).Update(book))
+
+	schiller, err := NewAuthorRepository(db).FindByName("Friedrich", "Schiller")
+	require.NoError(t, err)
+	require.NotNil(t, book.AuthorID)
+	assert.Equal(t, uint(schiller.ID), *book.AuthorID)
+
+	var list []models.BookContributor
+	require.NoError(t, db.Where("book_id = ?", book.ID).Order("position").Find(&list).Error)
+	require.Len(t, list, 3)
+	assert.Equal(t, []uint64{schiller.ID, goethe.ID, goethe.ID}, []uint64{list[0].AuthorID, list[1].AuthorID, list[2].AuthorID})
+	assert.Equal(t, models.ContributorEditor, list[2].Role)
+	assert.Equal(t, 2, list[2].Position)
+}
@@ -1,10 +1,16 @@
 package repository
 
 import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
 	"github.com/abaldeweg/warehouse-server/gateway/cover"
+	"github.com/abaldeweg/warehouse-server/gateway/search"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -33,15 +39,19 @@ func (r *BookRepository) DeleteBooksByBranch(branchID uint) error {
 
 	tx := r.DB.Begin()
 	for _, b := range books {
-		cover.DeleteCover(b.ID)
-
 		if err := tx.Delete(&b).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	deleteCovers(books)
+
+	return nil
 }
 
 // FindByID retrieves a book by UUID.
@@ -53,35 +63,99 @@ func (r *BookRepository) FindByID(id interface{}) (*models.Book, error) {
 	return &book, nil
 }
 
-// Update saves the provided book.
+// Update saves the provided book. Contributors given by name are linked to
+// the author of that name, which is created if needed.
 func (r *BookRepository) Update(book *models.Book) error {
-	tx := r.DB.Begin()
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if book.Contributors != nil {
+			contributors, err := resolveContributors(tx, book.ID, book.Contributors)
+			if err != nil {
+				return err
+			}
 
-	if err := tx.Omit("Tags").Save(book).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+			// The authors are linked by ID only, saving the association
+			// would insert them again.
+			book.Contributors = contributors
+			book.Author = nil
+			book.AuthorID = nil
+			for _, c := range contributors {
+				if c.Role == models.ContributorAuthor {
+					authorID := uint(c.AuthorID)
+					book.AuthorID = &authorID
+					break
+				}
+			}
+		}
 
-	if book.Tags != nil {
-		if err := tx.Exec("DELETE FROM book_tag WHERE book_id = ?", book.ID).Error; err != nil {
-			tx.Rollback()
+		if err := tx.Omit("Tags", "Contributors").Save(book).Error; err != nil {
 			return err
 		}
 
-		if len(book.Tags) > 0 {
-			vals := make([]map[string]any, 0, len(book.Tags))
-			for _, t := range book.Tags {
-				vals = append(vals, map[string]any{"book_id": book.ID, "tag_id": t.ID})
+		if book.Tags != nil {
+			if err := tx.Exec("DELETE FROM book_tag WHERE book_id = ?", book.ID).Error; err != nil {
+				return err
+			}
+
+			if len(book.Tags) > 0 {
+				vals := make([]map[string]any, 0, len(book.Tags))
+				for _, t := range book.Tags {
+					vals = append(vals, map[string]any{"book_id": book.ID, "tag_id": t.ID})
+				}
+
+				if err := tx.Table("book_tag").Create(vals).Error; err != nil {
+					return err
+				}
 			}
+		}
 
-			if err := tx.Table("book_tag").Create(vals).Error; err != nil {
-				tx.Rollback()
+		if book.Contributors != nil {
+			if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookContributor{}).Error; err != nil {
 				return err
 			}
+
+			if len(book.Contributors) > 0 {
+				if err := tx.Omit("Author").Create(&book.Contributors).Error; err != nil {
+					return err
+				}
+			}
 		}
+
+		return nil
+	})
+}
+
+// resolveContributors finds or creates the authors of the contributors
+// given by name only, in the transaction of the update, so a merge can't
+// delete them in between. An author is kept once per role and the
+// contributors are numbered in order.
+func resolveContributors(tx *gorm.DB, bookID uuid.UUID, list []models.BookContributor) ([]models.BookContributor, error) {
+	authors := NewAuthorRepository(tx)
+
+	contributors := []models.BookContributor{}
+	seen := map[string]bool{}
+	for _, c := range list {
+		if c.AuthorID == 0 && c.Author != nil {
+			author, err := authors.FindOrCreate(c.Author.Firstname, c.Author.Surname)
+			if err != nil {
+				return nil, err
+			}
+			c.AuthorID = author.ID
+		}
+
+		key := fmt.Sprint(c.AuthorID, c.Role)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		contributors = append(contributors, models.BookContributor{
+			BookID:   bookID,
+			AuthorID: c.AuthorID,
+			Role:     c.Role,
+			Position: len(contributors),
+		})
 	}
 
-	return tx.Commit().Error
+	return contributors, nil
 }
 
 // FindDuplicate searches for an existing book that would be considered a duplicate
@@ -124,24 +198,126 @@ func (r *BookRepository) FindDuplicate(b *models.Book) (*models.Book, error) {
 // FindByID retrieves a book by UUID.
 func (r *BookRepository) FindByIDAndPreload(id interface{}) (*models.Book, error) {
 	var book models.Book
-	if err := r.DB.Preload("Branch").Preload("Author").Preload("Genre").Preload("Condition").Preload("Format").Preload("Reservation").Preload("Tags").First(&book, "id = ?", id).Error; err != nil {
+	if err := r.DB.Preload("Branch").Preload("Author").Scopes(models.PreloadContributors).Preload("Genre").Preload("Condition").Preload("Format").Preload("Reservation").Preload("Tags").First(&book, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
+// Search finds the books of the branch matching every word of the term and
+// counts all matches. The best matches of the search index come first,
+// without an index the title, subtitle and author are searched and the books
+// ordered by title.
+func (r *BookRepository) Search(branchID uint, term string, genreIDs []uint, offset, limit int) ([]models.Book, int64, error) {
+	preload := func(q *gorm.DB) *gorm.DB {
+		return q.Preload("Author").Scopes(models.PreloadContributors).Preload("Genre").Preload("Condition").Preload("Format").Preload("Reservation").Preload("Tags")
+	}
+
+	q := search.Query{Kind: search.Books, Text: term, BranchID: branchID, Offset: offset, Limit: limit}
+	if len(genreIDs) > 0 {
+		q.Offset, q.Limit = 0, maxHits
+	}
+
+	books := []models.Book{}
+	if ids, total, ok := searchIndex(q); ok {
+		if len(genreIDs) > 0 && len(ids) > 0 {
+			var found []uuid.UUID
+			if err := r.DB.Model(&models.Book{}).Where("id IN ? AND genre_id IN ?", ids, genreIDs).Pluck("id", &found).Error; err != nil {
+				return nil, 0, err
+			}
+			ids = filterHits(ids, found, len(ids))
+			total = len(ids)
+			ids = ids[min(offset, total):min(offset+limit, total)]
+		}
+		if len(ids) == 0 {
+			return books, int64(total), nil
+		}
+		if err := preload(r.DB).Where("book.id IN ?", ids).Find(&books).Error; err != nil {
+			return nil, 0, err
+		}
+		sortByRank(books, ids, func(b models.Book) string { return b.ID.String() })
+		return books, int64(total), nil
+	}
+
+	query := r.DB.Model(&models.Book{}).Where("book.branch_id = ?", branchID)
+	if len(genreIDs) > 0 {
+		query = query.Where("book.genre_id IN ?", genreIDs)
+	}
+	for _, word := range strings.Fields(term) {
+		query = whereWord(r.DB, query, word)
+	}
+
+	var counter int64
+	if err := query.Count(&counter).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := preload(query).Order("title").Offset(offset).Limit(limit).Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	return books, counter, nil
+}
+
+// likeEscaper escapes the wildcards of a word in a LIKE pattern with "!".
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// whereWord restricts the query to the books with the word in the title,
+// the subtitle or the name of an author or contributor.
+func whereWord(db *gorm.DB, query *gorm.DB, word string) *gorm.DB {
+	like := "%" + likeEscaper.Replace(word) + "%"
+	return query.Where("title LIKE ? ESCAPE '!' OR subtitle LIKE ? ESCAPE '!' OR author_id IN (?) OR book.id IN (?)", like, like,
+		authorsLike(db, like), contributorsLike(db, like))
+}
+
+// authorsLike selects the IDs of the authors whose name is like the pattern.
+func authorsLike(db *gorm.DB, like string) *gorm.DB {
+	return db.Table("author").Select("id").Where("firstname LIKE ? ESCAPE '!' OR surname LIKE ? ESCAPE '!'", like, like)
+}
+
+// contributorsLike selects the IDs of the books with a contributor whose
+// name is like the pattern.
+func contributorsLike(db *gorm.DB, like string) *gorm.DB {
+	return db.Table("book_contributor").Select("book_id").Where("author_id IN (?)", authorsLike(db, like))
+}
+
 // Delete removes the given book from the database and deletes its cover files.
 func (r *BookRepository) Delete(book *models.Book) error {
-	tx := r.DB.Begin()
+	if err := r.DB.Delete(book).Error; err != nil {
+		return err
+	}
 
-	cover.DeleteCover(book.ID)
+	deleteCovers([]models.Book{*book})
 
-	if err := tx.Delete(book).Error; err != nil {
-		tx.Rollback()
-		return err
+	return nil
+}
+
+// deleteCovers removes the cover and image files of deleted books. Failures
+// are only logged, the files are left to the cover garbage collection.
+func deleteCovers(books []models.Book) {
+	for _, b := range books {
+		if err := cover.DeleteCover(b.ID); err != nil {
+			log.Printf("failed to delete cover of book %s: %v", b.ID, err)
+		}
+		if err := cover.DeleteGallery(b.ID); err != nil {
+			log.Printf("failed to delete images of book %s: %v", b.ID, err)
+		}
+	}
+}
+
+// ExistingIDs returns which of the given book IDs exist.
+func (r *BookRepository) ExistingIDs(ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	existing := make(map[uuid.UUID]bool, len(ids))
+
+	for chunk := range slices.Chunk(ids, 500) {
+		var found []uuid.UUID
+		if err := r.DB.Model(&models.Book{}).Where("id IN ?", chunk).Pluck("id", &found).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			existing[id] = true
+		}
 	}
 
-	return tx.Commit().Error
+	return existing, nil
 }
 
 // DeleteBooks deletes books whose `sold_on` or `removed_on` timestamp is
@@ -161,15 +337,19 @@ func (r *BookRepository) DeleteBooks(clearLimit int) error {
 
 	tx := r.DB.Begin()
 	for _, b := range books {
-		cover.DeleteCover(b.ID)
-
 		if err := tx.Delete(&b).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	deleteCovers(books)
+
+	return nil
 }
 
 // RemoveNotFoundBooks marks books as removed for the given branch,
@@ -188,3 +368,112 @@ func (r *BookRepository) ResetInventory(branchID uint) error {
 	return r.DB.Model(&models.Book{}).
 		Where("branch_id = ?", branchID).UpdateColumn("inventory", gorm.Expr("NULL")).Error
 }
+
+// FindNotFoundBooks returns all books of the branch that were marked as not
+// found in an inventory.
+func (r *BookRepository) FindNotFoundBooks(branchID uint) ([]models.Book, error) {
+	var books []models.Book
+	if err := r.DB.Preload("Author").
+		Where("branch_id = ? AND inventory IS NOT NULL AND inventory = ?", branchID, false).
+		Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// FindUnscannedBooks returns all books of the branch that are in stock but
+// were never scanned during an inventory.
+func (r *BookRepository) FindUnscannedBooks(branchID uint) ([]models.Book, error) {
+	var books []models.Book
+	if err := r.DB.Preload("Author").
+		Where("branch_id = ? AND inventory IS NULL AND sold = ? AND removed = ?", branchID, false, false).
+		Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// RemoveUnscannedBooks marks books as removed for the given branch,
+// that are in stock but were never scanned during an inventory.
+func (r *BookRepository) RemoveUnscannedBooks(branchID uint) error {
+	return r.DB.Model(&models.Book{}).
+		Where("branch_id = ? AND inventory IS NULL AND sold = ? AND removed = ?", branchID, false, false).
+		Updates(map[string]interface{}{
+			"removed":    true,
+			"removed_on": time.Now(),
+		}).Error
+}
+
+// StockValue returns the summed price of all books of the branch that are
+// neither sold nor removed in the currency of the branch.
+func (r *BookRepository) StockValue(branchID uint) (models.Money, error) {
+	var value models.Money
+	err := r.DB.Model(&models.Book{}).
+		Select("COALESCE(SUM(price), 0)").
+		Where("branch_id = ? AND sold = ? AND removed = ?", branchID, false, false).
+		Scan(&value).Error
+	if err != nil {
+		return value, err
+	}
+
+	branch, err := NewBranchRepository(r.DB).FindOne(branchID)
+	if err != nil {
+		return value, err
+	}
+	return value.In(branch.Currency)
+}
+
+// FindByIDs retrieves all books with the given UUIDs including their tags.
+func (r *BookRepository) FindByIDs(ids []uuid.UUID) ([]models.Book, error) {
+	var books []models.Book
+	if err := r.DB.Preload("Tags").Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// FindByFilter retrieves up to limit books of the branch matching the filter including their tags.
+func (r *BookRepository) FindByFilter(branchID uint, filter models.BookBulkFilter, limit int) ([]models.Book, error) {
+	query := r.DB.Preload("Tags").Where("branch_id = ?", branchID)
+
+	if filter.GenreID != nil {
+		genreIDs, err := NewGenreRepository(r.DB).FindDescendantIDs(*filter.GenreID)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("genre_id IN ?", genreIDs)
+	}
+	if filter.ConditionID != nil {
+		query = query.Where("cond_id = ?", *filter.ConditionID)
+	}
+	if filter.FormatID != nil {
+		query = query.Where("format_id = ?", *filter.FormatID)
+	}
+	if filter.TagID != nil {
+		query = query.Where("id IN (?)", r.DB.Table("book_tag").Select("book_id").Where("tag_id = ?", *filter.TagID))
+	}
+	if filter.Sold != nil {
+		query = query.Where("sold = ?", *filter.Sold)
+	}
+	if filter.Removed != nil {
+		query = query.Where("removed = ?", *filter.Removed)
+	}
+	if filter.Reserved != nil {
+		query = query.Where("reserved = ?", *filter.Reserved)
+	}
+	if filter.Recommendation != nil {
+		query = query.Where("recommendation = ?", *filter.Recommendation)
+	}
+	if filter.AddedBefore != nil {
+		query = query.Where("added < ?", time.Unix(*filter.AddedBefore, 0))
+	}
+	if filter.AddedAfter != nil {
+		query = query.Where("added > ?", time.Unix(*filter.AddedAfter, 0))
+	}
+
+	var books []models.Book
+	if err := query.Order("added asc").Limit(limit).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return books, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BookImageRepository struct for book image repository.
+type BookImageRepository struct {
+	db *gorm.DB
+}
+
+// NewBookImageRepository creates a new book image repository.
+func NewBookImageRepository(db *gorm.DB) *BookImageRepository {
+	return &BookImageRepository{db: db}
+}
+
+// FindByBook returns the images of the book in their order.
+func (r *BookImageRepository) FindByBook(bookID uuid.UUID) ([]models.BookImage, error) {
+	images := []models.BookImage{}
+	result := r.db.Where("book_id = ?", bookID).Order("position asc, created_at asc").Find(&images)
+	return images, result.Error
+}
+
+// FindOne returns an image of the book.
+func (r *BookImageRepository) FindOne(bookID, id uuid.UUID) (*models.BookImage, error) {
+	var image models.BookImage
+	if err := r.db.Where("book_id = ?", bookID).First(&image, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// NextPosition returns the position after the last image of the book.
+func (r *BookImageRepository) NextPosition(bookID uuid.UUID) (int, error) {
+	var position *int
+	if err := r.db.Model(&models.BookImage{}).Where("book_id = ?", bookID).Select("MAX(position)").Scan(&position).Error; err != nil {
+		return 0, err
+	}
+	if position == nil {
+		return 0, nil
+	}
+	return *position + 1, nil
+}
+
+// Create creates a new image.
+func (r *BookImageRepository) Create(image *models.BookImage) error {
+	return r.db.Create(image).Error
+}
+
+// Update updates the caption of an image.
+func (r *BookImageRepository) Update(image *models.BookImage) error {
+	return r.db.Model(image).Update("caption", image.Caption).Error
+}
+
+// Reorder sets the positions of the images of the book to the order of ids.
+func (r *BookImageRepository) Reorder(bookID uuid.UUID, ids []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			if err := tx.Model(&models.BookImage{}).Where("book_id = ? AND id = ?", bookID, id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete deletes an image.
+func (r *BookImageRepository) Delete(image *models.BookImage) error {
+	return r.db.Delete(image).Error
+}
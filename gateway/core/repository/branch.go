@@ -25,7 +25,7 @@ func (r *BranchRepository) FindAll() ([]models.Branch, error) {
 // FindAllByPublic returns all branches where public is true.
 func (r *BranchRepository) FindAllByPublic() ([]models.Branch, error) {
 	var branches []models.Branch
-	result := r.db.Where("public = ?", true).Find(&branches)
+	result := r.db.Where("public = ? AND archived = ?", true, false).Find(&branches)
 	return branches, result.Error
 }
 
@@ -41,3 +41,73 @@ func (r *BranchRepository) Update(branch *models.Branch) error {
 	result := r.db.Save(branch)
 	return result.Error
 }
+
+// Create creates a branch. If a template is given, its genres with their
+// hierarchy, conditions and formats are copied to the new branch.
+func (r *BranchRepository) Create(branch *models.Branch, templateID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(branch).Error; err != nil {
+			return err
+		}
+		if templateID == nil {
+			return nil
+		}
+
+		var genres []models.Genre
+		if err := tx.Where("branch_id = ?", *templateID).Order("id").Find(&genres).Error; err != nil {
+			return err
+		}
+		copies := make(map[uint]uint, len(genres))
+		for _, g := range genres {
+			genre := models.Genre{Name: g.Name, BranchID: branch.ID}
+			if err := tx.Create(&genre).Error; err != nil {
+				return err
+			}
+			copies[g.ID] = genre.ID
+		}
+		for _, g := range genres {
+			if g.ParentID == nil {
+				continue
+			}
+			parent, ok := copies[*g.ParentID]
+			if !ok {
+				continue
+			}
+			if err := tx.Model(&models.Genre{}).Where("id = ?", copies[g.ID]).Update("parent_id", parent).Error; err != nil {
+				return err
+			}
+		}
+
+		var conditions []models.Condition
+		if err := tx.Where("branch_id = ?", *templateID).Order("id").Find(&conditions).Error; err != nil {
+			return err
+		}
+		for _, c := range conditions {
+			if err := tx.Create(&models.Condition{Name: c.Name, BranchID: branch.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		var formats []models.Format
+		if err := tx.Where("branch_id = ?", *templateID).Order("id").Find(&formats).Error; err != nil {
+			return err
+		}
+		for _, f := range formats {
+			if err := tx.Create(&models.Format{Name: f.Name, BranchID: branch.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SetArchived archives or restores a branch. An archived branch is not
+// public anymore.
+func (r *BranchRepository) SetArchived(branch *models.Branch, archived bool) error {
+	branch.Archived = archived
+	if archived {
+		branch.Public = false
+	}
+	return r.db.Model(branch).Select("archived", "public").Updates(branch).Error
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExchangeRateRepository struct for exchange rate repository.
+type ExchangeRateRepository struct {
+	db *gorm.DB
+}
+
+// NewExchangeRateRepository creates a new exchange rate repository.
+func NewExchangeRateRepository(db *gorm.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// FindAll returns all exchange rates.
+func (r *ExchangeRateRepository) FindAll() ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	result := r.db.Order("base asc, quote asc").Find(&rates)
+	return rates, result.Error
+}
+
+// FindOne returns one exchange rate by id.
+func (r *ExchangeRateRepository) FindOne(id uint) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	result := r.db.First(&rate, id)
+	return rate, result.Error
+}
+
+// Save creates the exchange rate or updates the rate of the existing currency pair.
+func (r *ExchangeRateRepository) Save(rate *models.ExchangeRate) error {
+	rate.UpdatedAt = time.Now()
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(rate).Error; err != nil {
+		return err
+	}
+	return r.db.Where("base = ? AND quote = ?", rate.Base, rate.Quote).First(rate).Error
+}
+
+// SaveAll saves several exchange rates in one transaction.
+func (r *ExchangeRateRepository) SaveAll(rates []models.ExchangeRate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		repo := NewExchangeRateRepository(tx)
+		for i := range rates {
+			if err := repo.Save(&rates[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete deletes an exchange rate.
+func (r *ExchangeRateRepository) Delete(id uint) error {
+	return r.db.Delete(&models.ExchangeRate{}, id).Error
+}
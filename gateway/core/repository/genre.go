@@ -1,8 +1,21 @@
 package repository
 
 import (
+	"errors"
+
 	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/genres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrGenreCycle is returned if a genre would be moved below itself or
+	// one of its subgenres.
+	ErrGenreCycle = errors.New("genre can't be moved below itself")
+	// ErrGenreExists is returned if the new parent has a subgenre of the
+	// same name.
+	ErrGenreExists = errors.New("genre already exists")
 )
 
 // GenreRepository defines the interface for interacting with Genre data.
@@ -11,7 +24,10 @@ type GenreRepository interface {
 	FindOne(id uint) (models.Genre, error)
 	Create(genre *models.Genre) error
 	Update(id uint, genre *models.Genre) error
+	Move(id uint, parentID *uint) error
 	Delete(id uint) error
+	FindTree(branchID uint, available bool) ([]*models.GenreNode, error)
+	FindDescendantIDs(id uint) ([]uint, error)
 }
 
 type genreRepository struct {
@@ -51,7 +67,123 @@ func (r *genreRepository) Update(id uint, genre *models.Genre) error {
 	return r.db.Model(&models.Genre{}).Where("id = ?", id).Updates(genre).Error
 }
 
-// Delete deletes a genre by ID.
+// Move sets the parent of a genre, nil moves it to the top. The genres of
+// the branch are locked, so the check for cycles and names still holds when
+// the parent is saved.
+func (r *genreRepository) Move(id uint, parentID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var genre models.Genre
+		if err := tx.First(&genre, id).Error; err != nil {
+			return err
+		}
+
+		var list []models.Genre
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("branch_id = ?", genre.BranchID).Find(&list).Error; err != nil {
+			return err
+		}
+		if !genres.CanMove(list, id, parentID) {
+			return ErrGenreCycle
+		}
+
+		genre.ParentID = parentID
+		exists, err := genre.SiblingExists(tx)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrGenreExists
+		}
+
+		return tx.Model(&models.Genre{}).Where("id = ?", id).Update("parent_id", parentID).Error
+	})
+}
+
+// Delete deletes a genre by ID. Its subgenres and books move to the parent
+// of the genre, or to the top and to no genre. If a subgenre has the name of
+// a genre there, ErrGenreExists is returned.
 func (r *genreRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Genre{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var genre models.Genre
+		if err := tx.First(&genre, id).Error; err != nil {
+			return err
+		}
+
+		var list []models.Genre
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("branch_id = ?", genre.BranchID).Find(&list).Error; err != nil {
+			return err
+		}
+		for _, child := range list {
+			if child.ParentID == nil || *child.ParentID != id {
+				continue
+			}
+			// the genre itself is deleted before its subgenres move up, so
+			// it is no sibling
+			child.ParentID = genre.ParentID
+			exists, err := child.SiblingExists(tx.Where("id <> ?", id))
+			if err != nil {
+				return err
+			}
+			if exists {
+				return ErrGenreExists
+			}
+		}
+
+		var bookIDs []string
+		if err := tx.Model(&models.Book{}).Where("genre_id = ?", id).Pluck("id", &bookIDs).Error; err != nil {
+			return err
+		}
+		if len(bookIDs) > 0 {
+			if err := tx.Model(&models.Book{}).Where("id IN ?", bookIDs).Update("genre_id", genre.ParentID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&models.Genre{}, id).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Genre{}).Where("parent_id = ?", id).Update("parent_id", genre.ParentID).Error
+	})
+}
+
+// FindTree retrieves the genres of a branch as a tree with the number of
+// books in stock or, if available, of the books that can be ordered.
+func (r *genreRepository) FindTree(branchID uint, available bool) ([]*models.GenreNode, error) {
+	var list []models.Genre
+	if err := r.db.Where("branch_id = ?", branchID).Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	query := r.db.Model(&models.Book{}).Where("branch_id = ? AND genre_id IS NOT NULL AND sold = ? AND removed = ?", branchID, false, false)
+	if available {
+		query = query.Where("reserved = ?", false)
+	}
+	var rows []struct {
+		GenreID uint
+		Counter int64
+	}
+	if err := query.Select("genre_id, COUNT(*) AS counter").Group("genre_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.GenreID] = row.Counter
+	}
+
+	return genres.Tree(list, counts), nil
+}
+
+// FindDescendantIDs retrieves the ID of a genre and the IDs of all its
+// subgenres.
+func (r *genreRepository) FindDescendantIDs(id uint) ([]uint, error) {
+	genre, err := r.FindOne(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []models.Genre
+	if err := r.db.Where("branch_id = ?", genre.BranchID).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return genres.Descendants(list, id), nil
 }
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenreRepositoryMove(t *testing.T) {
+	db := testDB(t)
+	repo := NewGenreRepository(db)
+
+	fiction := create(t, db, &models.Genre{Name: "Fiction", BranchID: 1})
+	nonFiction := create(t, db, &models.Genre{Name: "Non-fiction", BranchID: 1})
+	crime := create(t, db, &models.Genre{Name: "Crime", BranchID: 1, ParentID: &fiction.ID})
+	trueCrime := create(t, db, &models.Genre{Name: "Crime", BranchID: 1, ParentID: &nonFiction.ID})
+
+	// the same name below another parent is fine, at the top too
+	assert.True(t, (&models.Genre{Name: "Crime", BranchID: 1, ParentID: &nonFiction.ID, ID: trueCrime.ID}).Validate(db))
+	assert.True(t, (&models.Genre{Name: "Crime", BranchID: 1}).Validate(db))
+	assert.False(t, (&models.Genre{Name: "Crime", BranchID: 1, ParentID: &fiction.ID}).Validate(db))
+	assert.False(t, (&models.Genre{Name: "Fiction", BranchID: 1}).Validate(db))
+	assert.True(t, (&models.Genre{Name: "Fiction", BranchID: 2}).Validate(db))
+
+	assert.ErrorIs(t, repo.Move(fiction.ID, &crime.ID), ErrGenreCycle)
+	assert.ErrorIs(t, repo.Move(fiction.ID, &fiction.ID), ErrGenreCycle)
+	assert.ErrorIs(t, repo.Move(trueCrime.ID, &fiction.ID), ErrGenreExists)
+
+	require.NoError(t, repo.Move(trueCrime.ID, nil))
+	moved, err := repo.FindOne(trueCrime.ID)
+	require.NoError(t, err)
+	assert.Nil(t, moved.ParentID)
+
+	require.NoError(t, repo.Move(crime.ID, &nonFiction.ID))
+	moved, err = repo.FindOne(crime.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &nonFiction.ID, moved.ParentID)
+
+	// the other crime genre is at the top already
+	assert.ErrorIs(t, repo.Move(crime.ID, nil), ErrGenreExists)
+}
+
+func TestGenreRepositoryDelete(t *testing.T) {
+	db := testDB(t)
+	repo := NewGenreRepository(db)
+
+	fiction := create(t, db, &models.Genre{Name: "Fiction", BranchID: 1})
+	crime := create(t, db, &models.Genre{Name: "Crime", BranchID: 1})
+	fictionCrime := create(t, db, &models.Genre{Name: "Crime", BranchID: 1, ParentID: &fiction.ID})
+	novels := create(t, db, &models.Genre{Name: "Novels", BranchID: 1, ParentID: &fiction.ID})
+
+	// the subgenre Crime would clash with the genre Crime at the top
+	assert.ErrorIs(t, repo.Delete(fiction.ID), ErrGenreExists)
+	_, err := repo.FindOne(fiction.ID)
+	require.NoError(t, err)
+	moved, err := repo.FindOne(novels.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &fiction.ID, moved.ParentID)
+
+	require.NoError(t, repo.Delete(crime.ID))
+	require.NoError(t, repo.Delete(fiction.ID))
+	for _, id := range []uint{fictionCrime.ID, novels.ID} {
+		moved, err := repo.FindOne(id)
+		require.NoError(t, err)
+		assert.Nil(t, moved.ParentID)
+	}
+
+	// a subgenre may have the name of the deleted genre
+	parent := create(t, db, &models.Genre{Name: "Poetry", BranchID: 1})
+	child := create(t, db, &models.Genre{Name: "Poetry", BranchID: 1, ParentID: &parent.ID})
+	require.NoError(t, repo.Delete(parent.ID))
+	moved, err = repo.FindOne(child.ID)
+	require.NoError(t, err)
+	assert.Nil(t, moved.ParentID)
+}
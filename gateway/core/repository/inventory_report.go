@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInventoryClosed is returned if an inventory was closed already.
+var ErrInventoryClosed = errors.New("inventory: closed already")
+
+// InventoryReportRepository handles the reconciliation reports of closed inventories.
+type InventoryReportRepository struct {
+	DB *gorm.DB
+}
+
+// NewInventoryReportRepository creates a new InventoryReportRepository.
+func NewInventoryReportRepository(db *gorm.DB) *InventoryReportRepository {
+	return &InventoryReportRepository{DB: db}
+}
+
+// FindByInventoryID retrieves the report of an inventory including its items.
+func (repo *InventoryReportRepository) FindByInventoryID(inventoryID uint) (*models.InventoryReport, error) {
+	var report models.InventoryReport
+	if err := repo.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("status asc, title asc")
+	}).Where("inventory_id = ?", inventoryID).First(&report).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Reconcile saves the end of the inventory, removes the books that were not
+// found (and optionally the books that were never scanned), resets the
+// inventory flags of the branch and stores a report about it. Everything
+// runs in one transaction. An inventory is closed only once, as the flags
+// are reset afterwards, otherwise ErrInventoryClosed is returned.
+func (repo *InventoryReportRepository) Reconcile(inventory *models.Inventory, unscannedAsMissing bool) (*models.InventoryReport, error) {
+	report := &models.InventoryReport{
+		InventoryID:        inventory.ID,
+		BranchID:           inventory.BranchID,
+		CreatedAt:          time.Now(),
+		UnscannedAsMissing: unscannedAsMissing,
+	}
+
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Inventory
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "ended_at").First(&current, inventory.ID).Error; err != nil {
+			return err
+		}
+		if current.EndedAtTimestamp != nil {
+			return ErrInventoryClosed
+		}
+
+		if err := NewInventoryRepository(tx).Update(inventory); err != nil {
+			return err
+		}
+
+		br := NewBookRepository(tx)
+
+		valueBefore, err := br.StockValue(inventory.BranchID)
+		if err != nil {
+			return err
+		}
+
+		notFound, err := br.FindNotFoundBooks(inventory.BranchID)
+		if err != nil {
+			return err
+		}
+
+		unscanned, err := br.FindUnscannedBooks(inventory.BranchID)
+		if err != nil {
+			return err
+		}
+
+		if err := br.RemoveNotFoundBooks(inventory.BranchID); err != nil {
+			return err
+		}
+
+		if unscannedAsMissing {
+			if err := br.RemoveUnscannedBooks(inventory.BranchID); err != nil {
+				return err
+			}
+		}
+
+		if err := br.ResetInventory(inventory.BranchID); err != nil {
+			return err
+		}
+
+		valueAfter, err := br.StockValue(inventory.BranchID)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range notFound {
+			report.Items = append(report.Items, models.NewInventoryReportItem(b, models.InventoryReportItemNotFound, true))
+		}
+		for _, b := range unscanned {
+			report.Items = append(report.Items, models.NewInventoryReportItem(b, models.InventoryReportItemUnscanned, unscannedAsMissing))
+		}
+
+		report.ValueBefore = valueBefore
+		report.ValueAfter = valueAfter
+		report.Removed = len(notFound)
+		report.Unscanned = len(unscanned)
+		if unscannedAsMissing {
+			report.Removed += len(unscanned)
+		}
+
+		return tx.Create(report).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryReportRepositoryReconcile(t *testing.T) {
+	db := testDB(t)
+	repo := NewInventoryReportRepository(db)
+
+	branch := create(t, db, &models.Branch{Name: "Branch"})
+	missing := create(t, db, &models.Book{ID: uuid.New(), BranchID: &branch.ID, Title: "Faust", Inventory: ptr(false), Price: models.NewMoney(1250, "EUR")})
+	inventory := models.NewInventory()
+	inventory.BranchID = branch.ID
+	create(t, db, inventory)
+
+	endedAt := inventory.StartedAtTimestamp + 60
+	inventory.EndedAtTimestamp = &endedAt
+
+	// the inventory stays open if the report can't be stored
+	require.NoError(t, db.Migrator().DropTable(&models.InventoryReportItem{}))
+	_, err := repo.Reconcile(inventory, false)
+	assert.Error(t, err)
+
+	open, err := NewInventoryRepository(db).FindByID(inventory.ID)
+	require.NoError(t, err)
+	assert.Nil(t, open.EndedAtTimestamp)
+	var book models.Book
+	require.NoError(t, db.First(&book, "id = ?", missing.ID).Error)
+	assert.False(t, book.Removed)
+
+	require.NoError(t, db.AutoMigrate(&models.InventoryReportItem{}))
+	report, err := repo.Reconcile(inventory, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Removed)
+
+	closed, err := NewInventoryRepository(db).FindByID(inventory.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &endedAt, closed.EndedAtTimestamp)
+	require.NoError(t, db.First(&book, "id = ?", missing.ID).Error)
+	assert.True(t, book.Removed)
+}
+
+func TestInventoryReportRepositoryReconcileClosed(t *testing.T) {
+	db := testDB(t)
+	repo := NewInventoryReportRepository(db)
+
+	branch := create(t, db, &models.Branch{Name: "Branch"})
+	missing := create(t, db, &models.Book{ID: uuid.New(), BranchID: &branch.ID, Title: "Faust", Inventory: ptr(false), Price: models.NewMoney(1250, "EUR")})
+	unscanned := create(t, db, &models.Book{ID: uuid.New(), BranchID: &branch.ID, Title: "Woyzeck", Inventory: ptr(true), Price: models.NewMoney(800, "EUR")})
+	inventory := models.NewInventory()
+	inventory.BranchID = branch.ID
+	create(t, db, inventory)
+
+	endedAt := inventory.StartedAtTimestamp + 60
+	inventory.EndedAtTimestamp = &endedAt
+	first, err := repo.Reconcile(inventory, false)
+	require.NoError(t, err)
+
+	// the flags are reset, a second close would remove the books in stock
+	again := inventory.StartedAtTimestamp + 120
+	inventory.EndedAtTimestamp = &again
+	_, err = repo.Reconcile(inventory, true)
+	assert.ErrorIs(t, err, ErrInventoryClosed)
+
+	var book models.Book
+	require.NoError(t, db.First(&book, "id = ?", unscanned.ID).Error)
+	assert.False(t, book.Removed)
+
+	closed, err := NewInventoryRepository(db).FindByID(inventory.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &endedAt, closed.EndedAtTimestamp)
+
+	report, err := repo.FindByInventoryID(inventory.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, report.ID)
+	require.Len(t, report.Items, 1)
+	assert.Equal(t, missing.ID, report.Items[0].BookID)
+}
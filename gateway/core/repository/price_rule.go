@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"gorm.io/gorm"
+)
+
+// PriceRuleRepository struct for price rule repository.
+type PriceRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewPriceRuleRepository creates a new price rule repository.
+func NewPriceRuleRepository(db *gorm.DB) *PriceRuleRepository {
+	return &PriceRuleRepository{db: db}
+}
+
+// FindAllByBranchID returns all price rules for a given branch ID, ordered by position.
+func (r *PriceRuleRepository) FindAllByBranchID(branchID uint) ([]models.PriceRule, error) {
+	var rules []models.PriceRule
+	result := r.db.Where("branch_id = ?", branchID).Order("position asc, id asc").Find(&rules)
+	return rules, result.Error
+}
+
+// FindOne returns one price rule by id.
+func (r *PriceRuleRepository) FindOne(id uint) (models.PriceRule, error) {
+	var rule models.PriceRule
+	result := r.db.First(&rule, id)
+	return rule, result.Error
+}
+
+// Create creates a new price rule.
+func (r *PriceRuleRepository) Create(rule *models.PriceRule) error {
+	return r.db.Omit("Branch").Create(rule).Error
+}
+
+// Update updates a price rule.
+func (r *PriceRuleRepository) Update(rule *models.PriceRule) error {
+	return r.db.Omit("Branch").Save(rule).Error
+}
+
+// Delete deletes a price rule.
+func (r *PriceRuleRepository) Delete(id uint) error {
+	return r.db.Delete(&models.PriceRule{}, id).Error
+}
@@ -1,6 +1,12 @@
 package repository
 
 import (
+	"sort"
+	"strings"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/search"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -13,3 +19,184 @@ type PublicBookRepository struct {
 func NewPublicBookRepository(db *gorm.DB) *PublicBookRepository {
 	return &PublicBookRepository{DB: db}
 }
+
+// Search finds the available books of all public branches, or of the given
+// ones, matching every word of the term and, if any, in one of the genres.
+// The books are grouped by branch, branches with most books first, and
+// limited per branch. The best matches of the search index come first,
+// without an index the title, subtitle and author are searched and the books
+// ordered by title.
+func (r *PublicBookRepository) Search(term string, branchIDs []uint, genreIDs []uint, limit int) ([]models.PublicBranchBooks, error) {
+	query := r.DB.Where("public = ? AND archived = ?", true, false)
+	if len(branchIDs) > 0 {
+		query = query.Where("id IN ?", branchIDs)
+	}
+	var branches []models.Branch
+	if err := query.Find(&branches).Error; err != nil {
+		return nil, err
+	}
+	if len(branches) == 0 {
+		return []models.PublicBranchBooks{}, nil
+	}
+
+	ids := make([]uint, 0, len(branches))
+	for _, b := range branches {
+		ids = append(ids, b.ID)
+	}
+
+	ranked, indexed := rankBooks(term, ids)
+
+	available := func() *gorm.DB {
+		q := r.DB.Model(&models.PublicBook{}).Where("book.branch_id IN ? AND sold = ? AND removed = ? AND reserved = ?", ids, false, false, false)
+		if len(genreIDs) > 0 {
+			q = q.Where("book.genre_id IN ?", genreIDs)
+		}
+		if indexed {
+			var hits []string
+			for _, id := range ids {
+				hits = append(hits, ranked[id]...)
+			}
+			return q.Where("book.id IN ?", hits)
+		}
+		for _, word := range strings.Fields(term) {
+			q = whereWord(r.DB, q, word)
+		}
+		return q
+	}
+
+	var counts []struct {
+		BranchID uint
+		Counter  int64
+	}
+	if err := available().Select("branch_id, COUNT(*) AS counter").Group("branch_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	counters := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		counters[c.BranchID] = c.Counter
+	}
+
+	pages, err := r.pages(available(), ranked, indexed, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var page []string
+	for _, ids := range pages {
+		page = append(page, ids...)
+	}
+	var books []models.PublicBook
+	if len(page) > 0 {
+		if err := r.DB.Model(&models.PublicBook{}).Where("book.id IN ?", page).Order("title").
+			Preload("Branch").Preload("Genre").Preload("Condition").Preload("Format").Preload("Author").Scopes(models.PreloadContributors).
+			Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, created_at asc") }).
+			Find(&books).Error; err != nil {
+			return nil, err
+		}
+	}
+	byBranch := map[uint][]models.PublicBook{}
+	for _, b := range books {
+		id := uint(*b.BranchID)
+		byBranch[id] = append(byBranch[id], b)
+	}
+
+	results := []models.PublicBranchBooks{}
+	for _, b := range branches {
+		if counters[b.ID] == 0 {
+			continue
+		}
+
+		found := byBranch[b.ID]
+		if found == nil {
+			found = []models.PublicBook{}
+		}
+		if indexed {
+			sortByRank(found, pages[b.ID], func(b models.PublicBook) string { return b.ID.String() })
+		}
+
+		results = append(results, models.PublicBranchBooks{
+			BranchID:   b.ID,
+			BranchName: b.Name,
+			Currency:   b.Currency,
+			Counter:    counters[b.ID],
+			Books:      found,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Counter != results[j].Counter {
+			return results[i].Counter > results[j].Counter
+		}
+		return results[i].BranchName < results[j].BranchName
+	})
+
+	return results, nil
+}
+
+// rankBooks finds the books of each branch matching the term in the search
+// index, best first. It returns false if the database has to be searched.
+func rankBooks(term string, branchIDs []uint) (map[uint][]string, bool) {
+	ranked := make(map[uint][]string, len(branchIDs))
+	for _, id := range branchIDs {
+		hits, _, ok := searchIndex(search.Query{Kind: search.Books, Text: term, BranchID: id, Limit: maxHits})
+		if !ok {
+			return nil, false
+		}
+		ranked[id] = hits
+	}
+	return ranked, true
+}
+
+// pages returns the IDs of the first books of each branch found by the
+// query, up to limit per branch. With the index the best hits come first,
+// otherwise the books are ordered by title.
+func (r *PublicBookRepository) pages(query *gorm.DB, ranked map[uint][]string, indexed bool, limit int) (map[uint][]string, error) {
+	var found []struct {
+		ID       uuid.UUID
+		BranchID uint
+	}
+
+	if indexed {
+		if err := query.Select("book.id, book.branch_id").Scan(&found).Error; err != nil {
+			return nil, err
+		}
+		ids := map[uint][]uuid.UUID{}
+		for _, f := range found {
+			ids[f.BranchID] = append(ids[f.BranchID], f.ID)
+		}
+		pages := make(map[uint][]string, len(ids))
+		for branchID, hits := range ranked {
+			pages[branchID] = filterHits(hits, ids[branchID], limit)
+		}
+		return pages, nil
+	}
+
+	numbered := query.Select("book.id, book.branch_id, ROW_NUMBER() OVER (PARTITION BY book.branch_id ORDER BY title) AS position")
+	if err := r.DB.Table("(?) AS numbered", numbered).Select("id, branch_id").Where("position <= ?", limit).Scan(&found).Error; err != nil {
+		return nil, err
+	}
+	pages := map[uint][]string{}
+	for _, f := range found {
+		pages[f.BranchID] = append(pages[f.BranchID], f.ID.String())
+	}
+	return pages, nil
+}
+
+// filterHits returns up to limit hits, keeping only the books found.
+func filterHits(hits []string, found []uuid.UUID, limit int) []string {
+	ok := make(map[string]bool, len(found))
+	for _, id := range found {
+		ok[id.String()] = true
+	}
+
+	page := []string{}
+	for _, id := range hits {
+		if len(page) == limit {
+			break
+		}
+		if ok[id] {
+			page = append(page, id)
+		}
+	}
+	return page
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestPublicBookRepositorySearch(t *testing.T) {
+	db := testDB(t)
+	repo := NewPublicBookRepository(db)
+
+	small := create(t, db, &models.Branch{Name: "Small", Public: true})
+	large := create(t, db, &models.Branch{Name: "Large", Public: true})
+	private := create(t, db, &models.Branch{Name: "Private"})
+	archived := create(t, db, &models.Branch{Name: "Archived", Public: true})
+	require.NoError(t, db.Model(archived).Update("archived", true).Error)
+
+	genre := create(t, db, &models.Genre{Name: "Krimi", BranchID: large.ID})
+
+	book := func(branch *models.Branch, title string, fn ...func(*models.Book)) {
+		b := &models.Book{ID: uuid.New(), BranchID: &branch.ID, Title: title, Price: models.NewMoney(100, "EUR")}
+		for _, f := range fn {
+			f(b)
+		}
+		create(t, db, b)
+	}
+	book(small, "Faust")
+	book(large, "Faust II", func(b *models.Book) { b.GenreID = &genre.ID })
+	book(large, "Faust I", func(b *models.Book) { b.GenreID = &genre.ID })
+	book(large, "Urfaust")
+	book(large, "Faust verkauft", func(b *models.Book) { b.Sold = true })
+	book(private, "Faust privat")
+	book(archived, "Faust archiviert")
+	book(small, "100% Faust")
+
+	titles := func(books []models.PublicBook) []string {
+		list := []string{}
+		for _, b := range books {
+			list = append(list, b.Title)
+		}
+		return list
+	}
+
+	results, err := repo.Search("faust", nil, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, large.ID, results[0].BranchID)
+	assert.Equal(t, int64(3), results[0].Counter)
+	assert.Equal(t, []string{"Faust I", "Faust II"}, titles(results[0].Books))
+	assert.Equal(t, small.ID, results[1].BranchID)
+	assert.Equal(t, int64(2), results[1].Counter)
+	assert.Equal(t, []string{"100% Faust", "Faust"}, titles(results[1].Books))
+
+	results, err = repo.Search("faust", []uint{small.ID, private.ID, archived.ID}, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, small.ID, results[0].BranchID)
+
+	results, err = repo.Search("faust", nil, []uint{genre.ID}, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{"Faust I", "Faust II"}, titles(results[0].Books))
+
+	// wildcards match literally
+	results, err = repo.Search("100%", nil, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{"100% Faust"}, titles(results[0].Books))
+
+	results, err = repo.Search("_aust", nil, nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	results, err = repo.Search("faust", []uint{private.ID}, nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestPublicBookRepositorySearchQueries(t *testing.T) {
+	db := testDB(t)
+	repo := NewPublicBookRepository(db)
+
+	var queries int
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("count", func(*gorm.DB) { queries++ }))
+	require.NoError(t, db.Callback().Row().Before("gorm:row").Register("count", func(*gorm.DB) { queries++ }))
+
+	search := func(branches int) int {
+		for i := range branches {
+			branch := create(t, db, &models.Branch{Name: string(rune('A' + i)), Public: true})
+			for range 3 {
+				create(t, db, &models.Book{ID: uuid.New(), BranchID: &branch.ID, Title: "Faust", Price: models.NewMoney(100, "EUR")})
+			}
+		}
+
+		queries = 0
+		results, err := repo.Search("faust", nil, nil, 10)
+		require.NoError(t, err)
+		require.NotEmpty(t, results)
+		return queries
+	}
+
+	// the queries don't grow with the number of branches
+	assert.Equal(t, search(1), search(4))
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testDB returns an empty SQLite database with all tables.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/warehouse.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Author{},
+		&models.Branch{},
+		&models.Condition{},
+		&models.Tag{},
+		&models.Genre{},
+		&models.Format{},
+		&models.Reservation{},
+		&models.Book{},
+		&models.BookImage{},
+		&models.BookContributor{},
+		&models.Transfer{},
+		&models.TransferItem{},
+		&models.Role{},
+		&models.Inventory{},
+		&models.InventoryReport{},
+		&models.InventoryReportItem{},
+	))
+	return db
+}
+
+func create[T any](t *testing.T, db *gorm.DB, v *T) *T {
+	t.Helper()
+	require.NoError(t, db.Create(v).Error)
+	return v
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
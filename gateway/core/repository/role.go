@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"gorm.io/gorm"
+)
+
+// ErrLastRoleEditor is returned if a change would leave a branch without a
+// role that can edit roles.
+var ErrLastRoleEditor = errors.New("no role of the branch could edit roles")
+
+// RoleRepository represents a role repository.
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new role repository.
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db}
+}
+
+// FindAllByBranchID finds all roles of a branch.
+func (r *RoleRepository) FindAllByBranchID(branchID uint) ([]models.Role, error) {
+	var roles []models.Role
+	result := r.db.Where("branch_id = ?", branchID).Order("name").Find(&roles)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return roles, nil
+}
+
+// FindOne finds a role by ID.
+func (r *RoleRepository) FindOne(id uint) (*models.Role, error) {
+	var role models.Role
+	result := r.db.First(&role, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &role, nil
+}
+
+// Permissions returns the permissions of the roles of a branch by role name.
+func (r *RoleRepository) Permissions(branchID uint) (map[string][]string, error) {
+	roles, err := r.FindAllByBranchID(branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		permissions[role.Name] = role.Permissions
+	}
+	return permissions, nil
+}
+
+// ExistsByName checks whether the branch has another role with the name.
+func (r *RoleRepository) ExistsByName(branchID uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	result := r.db.Model(&models.Role{}).Where("branch_id = ? AND name = ? AND id <> ?", branchID, name, exceptID).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// Create creates a new role.
+func (r *RoleRepository) Create(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		return keepRoleEditor(tx, role.BranchID)
+	})
+}
+
+// Update updates a role.
+func (r *RoleRepository) Update(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(role).Error; err != nil {
+			return err
+		}
+		return keepRoleEditor(tx, role.BranchID)
+	})
+}
+
+// Delete deletes a role by ID.
+func (r *RoleRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.First(&role, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&role).Error; err != nil {
+			return err
+		}
+		return keepRoleEditor(tx, role.BranchID)
+	})
+}
+
+// keepRoleEditor returns ErrLastRoleEditor if none of the roles of the
+// branch, including the default roles it doesn't replace, has role.edit.
+func keepRoleEditor(tx *gorm.DB, branchID uint) error {
+	roles, err := NewRoleRepository(tx).Permissions(branchID)
+	if err != nil {
+		return err
+	}
+
+	for name, permissions := range auth.DefaultRoles {
+		if _, ok := roles[name]; !ok {
+			roles[name] = permissions
+		}
+	}
+	for _, permissions := range roles {
+		if slices.Contains(permissions, auth.PermRoleEdit) {
+			return nil
+		}
+	}
+
+	return ErrLastRoleEditor
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleRepositoryKeepsRoleEditor(t *testing.T) {
+	db := testDB(t)
+	repo := NewRoleRepository(db)
+
+	// ROLE_ADMIN loses role.edit, no other role has it
+	admin := &models.Role{BranchID: 1, Name: "ROLE_ADMIN", Permissions: []string{auth.PermBookView}}
+	assert.ErrorIs(t, repo.Create(admin), ErrLastRoleEditor)
+	roles, err := repo.FindAllByBranchID(1)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	// another role has role.edit
+	editor := &models.Role{BranchID: 1, Name: "ROLE_EDITOR", Permissions: []string{auth.PermRoleEdit}}
+	require.NoError(t, repo.Create(editor))
+	admin = &models.Role{BranchID: 1, Name: "ROLE_ADMIN", Permissions: []string{auth.PermBookView}}
+	require.NoError(t, repo.Create(admin))
+
+	// the editor is the last role with role.edit
+	editor.Permissions = []string{auth.PermBookView}
+	assert.ErrorIs(t, repo.Update(editor), ErrLastRoleEditor)
+	assert.ErrorIs(t, repo.Delete(editor.ID), ErrLastRoleEditor)
+
+	saved, err := repo.FindOne(editor.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.PermRoleEdit}, saved.Permissions)
+
+	// other branches are independent
+	require.NoError(t, repo.Create(&models.Role{BranchID: 2, Name: "ROLE_USER", Permissions: []string{}}))
+
+	// removing the override of ROLE_ADMIN restores the default
+	require.NoError(t, repo.Delete(admin.ID))
+	require.NoError(t, repo.Delete(editor.ID))
+}
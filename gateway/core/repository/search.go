@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"slices"
+
+	"github.com/abaldeweg/warehouse-server/gateway/search"
+)
+
+// maxHits is the number of books taken from the index per branch when the
+// books are filtered further in the database.
+const maxHits = 1000
+
+// searchIndex returns the IDs of the documents matching the query, best
+// first, and the number of all matches. It returns false if the index can't
+// answer, e.g. while it is built, and the database has to be searched.
+func searchIndex(q search.Query) ([]string, int, bool) {
+	if len(search.Tokenize(q.Text)) == 0 {
+		return nil, 0, false
+	}
+
+	index, err := search.Default()
+	if err != nil {
+		return nil, 0, false
+	}
+
+	result, err := index.Search(q)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	return result.IDs(), result.Total, true
+}
+
+// sortByRank orders the items like the ids.
+func sortByRank[T any](items []T, ids []string, id func(T) string) {
+	rank := make(map[string]int, len(ids))
+	for i, id := range ids {
+		rank[id] = i
+	}
+	slices.SortStableFunc(items, func(a, b T) int {
+		return rank[id(a)] - rank[id(b)]
+	})
+}
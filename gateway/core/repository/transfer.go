@@ -0,0 +1,493 @@
+package repository
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTransferState is returned if the transfer can't change to the state.
+	ErrTransferState = errors.New("transfer: invalid state")
+	// ErrTransferBook is returned if a book can't be transferred.
+	ErrTransferBook = errors.New("transfer: book not available")
+	// ErrTransferMapping is returned if the mapping is incomplete or refers
+	// to another branch.
+	ErrTransferMapping = errors.New("transfer: invalid mapping")
+	// ErrTransferCurrency is returned if the branches have different
+	// currencies, as prices are kept in minor units of the branch.
+	ErrTransferCurrency = errors.New("transfer: branches have different currencies")
+)
+
+// TransferRepository represents a transfer repository.
+type TransferRepository struct {
+	db *gorm.DB
+}
+
+// NewTransferRepository creates a new transfer repository.
+func NewTransferRepository(db *gorm.DB) *TransferRepository {
+	return &TransferRepository{db}
+}
+
+// FindAllByBranchID finds the transfers from or to a branch, optionally only
+// the ones with the status.
+func (r *TransferRepository) FindAllByBranchID(branchID uint, status string) ([]models.Transfer, error) {
+	query := r.db.Preload("SourceBranch").Preload("TargetBranch").
+		Where("source_branch_id = ? OR target_branch_id = ?", branchID, branchID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var transfers []models.Transfer
+	if err := query.Order("created_at DESC").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// FindOne finds a transfer by ID with its items.
+func (r *TransferRepository) FindOne(id uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	err := r.db.Preload("SourceBranch").Preload("TargetBranch").
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("title") }).
+		First(&transfer, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// Create creates a transfer with its items.
+func (r *TransferRepository) Create(transfer *models.Transfer) error {
+	if err := checkCurrencies(r.db, transfer); err != nil {
+		return err
+	}
+	return r.db.Create(transfer).Error
+}
+
+// Update saves the target and note of a draft transfer and replaces its
+// items.
+func (r *TransferRepository) Update(transfer *models.Transfer) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockTransfer(tx, transfer, models.TransferDraft); err != nil {
+			return err
+		}
+		if err := checkCurrencies(tx, transfer); err != nil {
+			return err
+		}
+		if err := tx.Model(transfer).Select("target_branch_id", "note").Updates(transfer).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("transfer_id = ?", transfer.ID).Delete(&models.TransferItem{}).Error; err != nil {
+			return err
+		}
+		for i := range transfer.Items {
+			transfer.Items[i].TransferID = transfer.ID
+		}
+		if len(transfer.Items) == 0 {
+			return nil
+		}
+		return tx.Create(&transfer.Items).Error
+	})
+}
+
+// CheckBooks returns the IDs of the books that can't be transferred from the
+// branch, because they belong to another branch, are sold, removed, reserved
+// or in transit.
+func (r *TransferRepository) CheckBooks(branchID uint, ids []uuid.UUID) ([]models.Book, []uuid.UUID, error) {
+	var books []models.Book
+	if err := r.db.Preload("Tags").Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, nil, err
+	}
+
+	available := map[uuid.UUID]bool{}
+	valid := make([]models.Book, 0, len(books))
+	for _, b := range books {
+		if b.BranchID == nil || *b.BranchID != branchID || b.Sold || b.Removed || b.Reserved || b.TransferID != nil {
+			continue
+		}
+		available[b.ID] = true
+		valid = append(valid, b)
+	}
+
+	invalid := []uuid.UUID{}
+	for _, id := range ids {
+		if !available[id] {
+			invalid = append(invalid, id)
+		}
+	}
+
+	return valid, invalid, nil
+}
+
+// Ship sends a draft transfer. The books leave the source branch and their
+// references in it are kept in the items.
+func (r *TransferRepository) Ship(transfer *models.Transfer, userID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockTransfer(tx, transfer, models.TransferDraft); err != nil {
+			return err
+		}
+		// the items may have changed since the transfer was loaded
+		if err := tx.Where("transfer_id = ?", transfer.ID).Find(&transfer.Items).Error; err != nil {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(transfer.Items))
+		for _, item := range transfer.Items {
+			ids = append(ids, item.BookID)
+		}
+
+		books, invalid, err := NewTransferRepository(tx).CheckBooks(transfer.SourceBranchID, ids)
+		if err != nil {
+			return err
+		}
+		if len(invalid) > 0 {
+			return fmt.Errorf("%w: %s", ErrTransferBook, invalid[0])
+		}
+
+		byID := make(map[uuid.UUID]models.Book, len(books))
+		for _, b := range books {
+			byID[b.ID] = b
+		}
+
+		for i := range transfer.Items {
+			item := &transfer.Items[i]
+			book := byID[item.BookID]
+
+			item.Title = book.Title
+			item.Price = book.Price
+			item.SourceGenreID = book.GenreID
+			item.SourceConditionID = book.ConditionID
+			item.SourceFormatID = book.FormatID
+			item.SourceTagIDs = make([]uint, 0, len(book.Tags))
+			for _, tag := range book.Tags {
+				item.SourceTagIDs = append(item.SourceTagIDs, tag.ID)
+			}
+
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.Book{}).Where("id IN ?", ids).
+			Updates(map[string]any{"branch_id": nil, "transfer_id": transfer.ID}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transfer.ShippedAt = &now
+		transfer.ShippedBy = &userID
+		return setTransferStatus(tx, transfer, models.TransferShipped, "shipped_at", "shipped_by")
+	})
+}
+
+// Cancel cancels a draft or shipped transfer. Shipped books return to the
+// source branch unchanged.
+func (r *TransferRepository) Cancel(transfer *models.Transfer) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockTransfer(tx, transfer, models.TransferDraft, models.TransferShipped); err != nil {
+			return err
+		}
+
+		if transfer.Status == models.TransferShipped {
+			if err := tx.Model(&models.Book{}).Where("transfer_id = ?", transfer.ID).
+				Updates(map[string]any{"branch_id": transfer.SourceBranchID, "transfer_id": nil}).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		transfer.CancelledAt = &now
+		return setTransferStatus(tx, transfer, models.TransferCancelled, "cancelled_at")
+	})
+}
+
+// lockTransfer locks the row of the transfer until the end of the
+// transaction and checks that it is in one of the states. Its status is
+// read again, as another request may have changed it since it was loaded.
+func lockTransfer(tx *gorm.DB, transfer *models.Transfer, statuses ...string) error {
+	var current models.Transfer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&current, transfer.ID).Error; err != nil {
+		return err
+	}
+	if !slices.Contains(statuses, current.Status) {
+		transfer.Status = current.Status
+		return ErrTransferState
+	}
+	transfer.Status = current.Status
+	return nil
+}
+
+// checkCurrencies checks that the source and target branch of the transfer
+// have the same currency, the prices of the books are kept unchanged.
+func checkCurrencies(tx *gorm.DB, transfer *models.Transfer) error {
+	var branches []models.Branch
+	if err := tx.Select("id", "currency").Where("id IN ?", []uint{transfer.SourceBranchID, transfer.TargetBranchID}).Find(&branches).Error; err != nil {
+		return err
+	}
+
+	codes := map[string]bool{}
+	for _, b := range branches {
+		codes[cmp.Or(b.Currency, models.DefaultCurrency)] = true
+	}
+	if len(codes) > 1 {
+		return ErrTransferCurrency
+	}
+	return nil
+}
+
+// setTransferStatus changes the status of the transfer along with the
+// columns if it still has the status read by lockTransfer.
+func setTransferStatus(tx *gorm.DB, transfer *models.Transfer, status string, columns ...string) error {
+	previous := transfer.Status
+	transfer.Status = status
+	result := tx.Model(transfer).Where("status = ?", previous).Select(append([]string{"status"}, columns...)).Updates(transfer)
+	if result.Error != nil {
+		transfer.Status = previous
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		transfer.Status = previous
+		return ErrTransferState
+	}
+	return nil
+}
+
+// Mapping lists the genres, conditions, formats and tags the books of a
+// shipped transfer used in the source branch. The target is proposed by an
+// equal name in the target branch.
+func (r *TransferRepository) Mapping(transfer *models.Transfer) (models.TransferReferences, error) {
+	var genres, conditions, formats, tags []uint
+	for _, item := range transfer.Items {
+		if item.SourceGenreID != nil {
+			genres = append(genres, *item.SourceGenreID)
+		}
+		if item.SourceConditionID != nil {
+			conditions = append(conditions, *item.SourceConditionID)
+		}
+		formats = append(formats, item.SourceFormatID)
+		tags = append(tags, item.SourceTagIDs...)
+	}
+
+	var refs models.TransferReferences
+	var err error
+	if refs.Genres, err = r.references("genre", genres, transfer.TargetBranchID); err != nil {
+		return refs, err
+	}
+	if refs.Conditions, err = r.references("cond", conditions, transfer.TargetBranchID); err != nil {
+		return refs, err
+	}
+	if refs.Formats, err = r.references("format", formats, transfer.TargetBranchID); err != nil {
+		return refs, err
+	}
+	if refs.Tags, err = r.references("tag", tags, transfer.TargetBranchID); err != nil {
+		return refs, err
+	}
+
+	return refs, nil
+}
+
+type namedRow struct {
+	ID   uint
+	Name string
+}
+
+func (r *TransferRepository) references(table string, ids []uint, targetBranchID uint) ([]models.TransferReference, error) {
+	refs := []models.TransferReference{}
+	if len(ids) == 0 {
+		return refs, nil
+	}
+
+	var sources, targets []namedRow
+	if err := r.db.Table(table).Select("id, name").Where("id IN ?", ids).Order("name").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Table(table).Select("id, name").Where("branch_id = ?", targetBranchID).Find(&targets).Error; err != nil {
+		return nil, err
+	}
+
+	found := map[uint]bool{}
+	for _, s := range sources {
+		found[s.ID] = true
+		ref := models.TransferReference{ID: s.ID, Name: s.Name}
+		for _, t := range targets {
+			if strings.EqualFold(strings.TrimSpace(t.Name), strings.TrimSpace(s.Name)) {
+				ref.TargetID = &t.ID
+				ref.TargetName = &t.Name
+				break
+			}
+		}
+		refs = append(refs, ref)
+	}
+
+	// references deleted in the source branch since shipping
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		if !found[id] && !seen[id] {
+			seen[id] = true
+			refs = append(refs, models.TransferReference{ID: id})
+		}
+	}
+
+	return refs, nil
+}
+
+// Receive completes a shipped transfer. The books join the target branch
+// and get the genre, condition, format and tags of the mapping. References
+// missing in the mapping use the proposal of Mapping, a mapping to 0 drops
+// the reference. Every format must be mapped, as books require one.
+func (r *TransferRepository) Receive(transfer *models.Transfer, mapping models.TransferMapping, userID int) error {
+	if transfer.Status != models.TransferShipped {
+		return ErrTransferState
+	}
+
+	refs, err := r.Mapping(transfer)
+	if err != nil {
+		return err
+	}
+
+	genres, err := r.resolve("genre", refs.Genres, mapping.Genres, transfer.TargetBranchID)
+	if err != nil {
+		return err
+	}
+	conditions, err := r.resolve("cond", refs.Conditions, mapping.Conditions, transfer.TargetBranchID)
+	if err != nil {
+		return err
+	}
+	formats, err := r.resolve("format", refs.Formats, mapping.Formats, transfer.TargetBranchID)
+	if err != nil {
+		return err
+	}
+	tags, err := r.resolve("tag", refs.Tags, mapping.Tags, transfer.TargetBranchID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range transfer.Items {
+		if _, ok := formats[item.SourceFormatID]; !ok {
+			return fmt.Errorf("%w: format %d is not mapped", ErrTransferMapping, item.SourceFormatID)
+		}
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockTransfer(tx, transfer, models.TransferShipped); err != nil {
+			return err
+		}
+		// a branch may have changed its currency since shipping
+		if err := checkCurrencies(tx, transfer); err != nil {
+			return err
+		}
+
+		for i := range transfer.Items {
+			item := &transfer.Items[i]
+
+			item.TargetGenreID = mapRef(genres, item.SourceGenreID)
+			item.TargetConditionID = mapRef(conditions, item.SourceConditionID)
+			format := formats[item.SourceFormatID]
+			item.TargetFormatID = &format
+			item.TargetTagIDs = []uint{}
+			// several source tags may map to the same target, e.g. by name
+			for _, id := range item.SourceTagIDs {
+				if target, ok := tags[id]; ok && !slices.Contains(item.TargetTagIDs, target) {
+					item.TargetTagIDs = append(item.TargetTagIDs, target)
+				}
+			}
+
+			result := tx.Model(&models.Book{}).Where("id = ? AND transfer_id = ?", item.BookID, transfer.ID).Updates(map[string]any{
+				"branch_id":   transfer.TargetBranchID,
+				"transfer_id": nil,
+				"genre_id":    item.TargetGenreID,
+				"cond_id":     item.TargetConditionID,
+				"format_id":   format,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				// the book was deleted in transit
+				continue
+			}
+
+			if err := tx.Exec("DELETE FROM book_tag WHERE book_id = ?", item.BookID).Error; err != nil {
+				return err
+			}
+			if len(item.TargetTagIDs) > 0 {
+				vals := make([]map[string]any, 0, len(item.TargetTagIDs))
+				for _, id := range item.TargetTagIDs {
+					vals = append(vals, map[string]any{"book_id": item.BookID, "tag_id": id})
+				}
+				if err := tx.Table("book_tag").Create(vals).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		transfer.ReceivedAt = &now
+		transfer.ReceivedBy = &userID
+		return setTransferStatus(tx, transfer, models.TransferReceived, "received_at", "received_by")
+	})
+}
+
+// resolve merges the proposed and the requested mapping and checks that the
+// targets belong to the target branch.
+func (r *TransferRepository) resolve(table string, refs []models.TransferReference, requested map[uint]uint, targetBranchID uint) (map[uint]uint, error) {
+	resolved := map[uint]uint{}
+	for _, ref := range refs {
+		if ref.TargetID != nil {
+			resolved[ref.ID] = *ref.TargetID
+		}
+	}
+
+	targets := []uint{}
+	for source, target := range requested {
+		if target == 0 {
+			delete(resolved, source)
+			continue
+		}
+		resolved[source] = target
+		targets = append(targets, target)
+	}
+
+	if len(targets) > 0 {
+		var count int64
+		if err := r.db.Table(table).Where("id IN ? AND branch_id = ?", targets, targetBranchID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if int(count) != len(distinct(targets)) {
+			return nil, fmt.Errorf("%w: unknown %s in target branch", ErrTransferMapping, table)
+		}
+	}
+
+	return resolved, nil
+}
+
+func mapRef(mapping map[uint]uint, id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	if target, ok := mapping[*id]; ok {
+		return &target
+	}
+	return nil
+}
+
+func distinct(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type transferFixture struct {
+	db       *gorm.DB
+	repo     *TransferRepository
+	source   *models.Branch
+	target   *models.Branch
+	book     *models.Book
+	genre    *models.Genre
+	format   *models.Format
+	tag      *models.Tag
+	krimi    *models.Genre
+	hardback *models.Format
+	roman    *models.Tag
+}
+
+func newTransferFixture(t *testing.T) *transferFixture {
+	db := testDB(t)
+	f := &transferFixture{db: db, repo: NewTransferRepository(db)}
+
+	f.source = create(t, db, &models.Branch{Name: "Source"})
+	f.target = create(t, db, &models.Branch{Name: "Target"})
+
+	f.genre = create(t, db, &models.Genre{Name: "Krimi", BranchID: f.source.ID})
+	f.format = create(t, db, &models.Format{Name: "Hardcover", BranchID: f.source.ID})
+	f.tag = create(t, db, &models.Tag{Name: "Roman", BranchID: f.source.ID})
+	create(t, db, &models.Condition{Name: "Neu", BranchID: f.source.ID})
+
+	f.krimi = create(t, db, &models.Genre{Name: " krimi", BranchID: f.target.ID})
+	f.hardback = create(t, db, &models.Format{Name: "Gebunden", BranchID: f.target.ID})
+	f.roman = create(t, db, &models.Tag{Name: "ROMAN", BranchID: f.target.ID})
+
+	f.book = create(t, db, &models.Book{
+		ID:          uuid.New(),
+		BranchID:    &f.source.ID,
+		Title:       "Der Richter und sein Henker",
+		GenreID:     &f.genre.ID,
+		FormatID:    f.format.ID,
+		ReleaseYear: 1950,
+		Price:       models.NewMoney(1250, "EUR"),
+	})
+	require.NoError(t, db.Table("book_tag").Create(map[string]any{"book_id": f.book.ID, "tag_id": f.tag.ID}).Error)
+
+	return f
+}
+
+// draft creates a draft transfer of the book and loads it like the
+// controller does.
+func (f *transferFixture) draft(t *testing.T) *models.Transfer {
+	t.Helper()
+	transfer := &models.Transfer{
+		SourceBranchID: f.source.ID,
+		TargetBranchID: f.target.ID,
+		Status:         models.TransferDraft,
+		Items:          []models.TransferItem{{BookID: f.book.ID, Title: f.book.Title, SourceFormatID: f.book.FormatID}},
+	}
+	require.NoError(t, f.repo.Create(transfer))
+	return f.load(t, transfer.ID)
+}
+
+func (f *transferFixture) load(t *testing.T, id uint) *models.Transfer {
+	t.Helper()
+	transfer, err := f.repo.FindOne(id)
+	require.NoError(t, err)
+	return transfer
+}
+
+func (f *transferFixture) loadBook(t *testing.T) models.Book {
+	t.Helper()
+	var book models.Book
+	require.NoError(t, f.db.Preload("Tags").First(&book, "id = ?", f.book.ID).Error)
+	return book
+}
+
+func TestTransferShip(t *testing.T) {
+	f := newTransferFixture(t)
+	transfer := f.draft(t)
+
+	require.NoError(t, f.repo.Ship(transfer, 7))
+	assert.Equal(t, models.TransferShipped, transfer.Status)
+
+	shipped := f.load(t, transfer.ID)
+	assert.Equal(t, models.TransferShipped, shipped.Status)
+	assert.Equal(t, 7, *shipped.ShippedBy)
+	require.Len(t, shipped.Items, 1)
+	assert.Equal(t, f.genre.ID, *shipped.Items[0].SourceGenreID)
+	assert.Equal(t, []uint{f.tag.ID}, shipped.Items[0].SourceTagIDs)
+	assert.Equal(t, int64(1250), shipped.Items[0].Price.Amount)
+
+	book := f.loadBook(t)
+	assert.Nil(t, book.BranchID)
+	assert.Equal(t, transfer.ID, *book.TransferID)
+
+	// a second request with the transfer loaded as draft
+	stale := *transfer
+	stale.Status = models.TransferDraft
+	assert.ErrorIs(t, f.repo.Ship(&stale, 7), ErrTransferState)
+	assert.ErrorIs(t, f.repo.Update(&stale), ErrTransferState)
+}
+
+func TestTransferShipUnavailableBook(t *testing.T) {
+	f := newTransferFixture(t)
+	transfer := f.draft(t)
+
+	require.NoError(t, f.db.Model(f.book).Update("sold", true).Error)
+	assert.ErrorIs(t, f.repo.Ship(transfer, 7), ErrTransferBook)
+	assert.Equal(t, models.TransferDraft, f.load(t, transfer.ID).Status)
+}
+
+func TestTransferUpdate(t *testing.T) {
+	f := newTransferFixture(t)
+	transfer := f.draft(t)
+
+	transfer.Note = "Spende"
+	transfer.Items = nil
+	require.NoError(t, f.repo.Update(transfer))
+
+	updated := f.load(t, transfer.ID)
+	assert.Equal(t, "Spende", updated.Note)
+	assert.Equal(t, models.TransferDraft, updated.Status)
+	assert.Empty(t, updated.Items)
+}
+
+func TestTransferCancel(t *testing.T) {
+	f := newTransferFixture(t)
+
+	draft := f.draft(t)
+	require.NoError(t, f.repo.Cancel(draft))
+	assert.Equal(t, models.TransferCancelled, f.load(t, draft.ID).Status)
+	assert.ErrorIs(t, f.repo.Cancel(draft), ErrTransferState)
+
+	shipped := f.draft(t)
+	require.NoError(t, f.repo.Ship(shipped, 7))
+	require.NoError(t, f.repo.Cancel(shipped))
+	assert.Equal(t, models.TransferCancelled, f.load(t, shipped.ID).Status)
+
+	book := f.loadBook(t)
+	assert.Equal(t, f.source.ID, *book.BranchID)
+	assert.Nil(t, book.TransferID)
+}
+
+func TestTransferMapping(t *testing.T) {
+	f := newTransferFixture(t)
+	transfer := f.draft(t)
+	require.NoError(t, f.repo.Ship(transfer, 7))
+
+	refs, err := f.repo.Mapping(f.load(t, transfer.ID))
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.TransferReference{{ID: f.genre.ID, Name: "Krimi", TargetID: &f.krimi.ID, TargetName: ptr(" krimi")}}, refs.Genres)
+	assert.Equal(t, []models.TransferReference{{ID: f.format.ID, Name: "Hardcover"}}, refs.Formats)
+	assert.Equal(t, []models.TransferReference{{ID: f.tag.ID, Name: "Roman", TargetID: &f.roman.ID, TargetName: ptr("ROMAN")}}, refs.Tags)
+	assert.Empty(t, refs.Conditions)
+
+	// references deleted since shipping are listed without a name
+	require.NoError(t, f.db.Delete(&models.Tag{}, f.tag.ID).Error)
+	refs, err = f.repo.Mapping(f.load(t, transfer.ID))
+	require.NoError(t, err)
+	assert.Equal(t, []models.TransferReference{{ID: f.tag.ID}}, refs.Tags)
+}
+
+func TestTransferReceive(t *testing.T) {
+	f := newTransferFixture(t)
+	transfer := f.draft(t)
+	require.NoError(t, f.repo.Ship(transfer, 7))
+	transfer = f.load(t, transfer.ID)
+
+	// the format has no proposal and must be mapped
+	assert.ErrorIs(t, f.repo.Receive(transfer, models.TransferMapping{}, 8), ErrTransferMapping)
+
+	// targets must belong to the target branch
+	assert.ErrorIs(t, f.repo.Receive(transfer, models.TransferMapping{Formats: map[uint]uint{f.format.ID: f.format.ID}}, 8), ErrTransferMapping)
+
+	mapping := models.TransferMapping{
+		Formats: map[uint]uint{f.format.ID: f.hardback.ID},
+		Tags:    map[uint]uint{f.tag.ID: 0},
+	}
+	require.NoError(t, f.repo.Receive(transfer, mapping, 8))
+
+	received := f.load(t, transfer.ID)
+	assert.Equal(t, models.TransferReceived, received.Status)
+	assert.Equal(t, 8, *received.ReceivedBy)
+
+	book := f.loadBook(t)
+	assert.Equal(t, f.target.ID, *book.BranchID)
+	assert.Nil(t, book.TransferID)
+	assert.Equal(t, f.krimi.ID, *book.GenreID)
+	assert.Equal(t, f.hardback.ID, book.FormatID)
+	assert.Empty(t, book.Tags)
+
+	// a cancel or a second receive with the transfer loaded as shipped
+	assert.ErrorIs(t, f.repo.Cancel(transfer), ErrTransferState)
+	transfer.Status = models.TransferShipped
+	assert.ErrorIs(t, f.repo.Receive(transfer, mapping, 8), ErrTransferState)
+
+	book = f.loadBook(t)
+	assert.Equal(t, f.target.ID, *book.BranchID)
+	assert.Equal(t, models.TransferReceived, f.load(t, transfer.ID).Status)
+}
+
+func TestTransferCurrency(t *testing.T) {
+	f := newTransferFixture(t)
+	transfer := f.draft(t)
+	require.NoError(t, f.repo.Ship(transfer, 7))
+	transfer = f.load(t, transfer.ID)
+
+	// the price is in minor units of the branch, 1250 JPY aren't 12.50 EUR
+	require.NoError(t, f.db.Model(f.target).Update("currency", "JPY").Error)
+
+	assert.ErrorIs(t, f.repo.Create(&models.Transfer{SourceBranchID: f.source.ID, TargetBranchID: f.target.ID, Status: models.TransferDraft}), ErrTransferCurrency)
+	assert.ErrorIs(t, f.repo.Receive(transfer, models.TransferMapping{Formats: map[uint]uint{f.format.ID: f.hardback.ID}}, 8), ErrTransferCurrency)
+	assert.Equal(t, models.TransferShipped, f.load(t, transfer.ID).Status)
+
+	// same currency
+	require.NoError(t, f.db.Model(f.source).Update("currency", "JPY").Error)
+	require.NoError(t, f.repo.Receive(transfer, models.TransferMapping{Formats: map[uint]uint{f.format.ID: f.hardback.ID}}, 8))
+
+	book := f.loadBook(t)
+	assert.Equal(t, f.target.ID, *book.BranchID)
+	assert.Equal(t, int64(1250), book.Price.Amount)
+}
+
+func TestTransferReceiveMergedTags(t *testing.T) {
+	f := newTransferFixture(t)
+	krimi := create(t, f.db, &models.Tag{Name: "krimi", BranchID: f.source.ID})
+	require.NoError(t, f.db.Table("book_tag").Create(map[string]any{"book_id": f.book.ID, "tag_id": krimi.ID}).Error)
+
+	transfer := f.draft(t)
+	require.NoError(t, f.repo.Ship(transfer, 7))
+	transfer = f.load(t, transfer.ID)
+
+	mapping := models.TransferMapping{
+		Formats: map[uint]uint{f.format.ID: f.hardback.ID},
+		Tags:    map[uint]uint{krimi.ID: f.roman.ID},
+	}
+	require.NoError(t, f.repo.Receive(transfer, mapping, 8))
+
+	book := f.loadBook(t)
+	require.Len(t, book.Tags, 1)
+	assert.Equal(t, f.roman.ID, book.Tags[0].ID)
+	assert.Equal(t, []uint{f.roman.ID}, f.load(t, transfer.ID).Items[0].TargetTagIDs)
+}
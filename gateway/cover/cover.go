@@ -1,11 +1,5 @@
 package cover
 
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-)
-
 const (
 	Quality = 75
 )
@@ -16,13 +10,7 @@ var Sizes = map[string]int{
 	"s": 100,
 }
 
-func getPath() (string, error) {
-	currentDir, _ := os.Getwd()
-	uploadsDirPath := filepath.Join(currentDir, "uploads")
-
-	if err := os.MkdirAll(uploadsDirPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create uploads directory")
-	}
-
-	return uploadsDirPath, nil
+// Key returns the key of the cover of the book in the given size.
+func Key(bookID string, size string) string {
+	return bookID + "-" + size + ".jpg"
 }
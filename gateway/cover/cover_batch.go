@@ -0,0 +1,56 @@
+package cover
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a cover in a batch.
+const (
+	StatusOK       = "ok"
+	StatusMissing  = "missing"
+	StatusNotFound = "not_found"
+)
+
+// BatchCover is the cover of one book of a batch. Missing covers have no
+// URL and no data, they aren't replaced by the placeholder.
+type BatchCover struct {
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+	URL    string    `json:"url,omitempty"`
+	Data   string    `json:"data,omitempty"`
+}
+
+// Batch returns the covers of the books in the given size. If inline is
+// set, the covers are returned as base64 data URIs instead of URLs.
+func Batch(bookIDs []uuid.UUID, size string, inline bool) ([]BatchCover, error) {
+	s, err := Store()
+	if err != nil {
+		return nil, err
+	}
+
+	covers := make([]BatchCover, 0, len(bookIDs))
+	for _, id := range bookIDs {
+		c := BatchCover{ID: id, Status: StatusOK}
+		key := Key(id.String(), size)
+
+		if inline {
+			c.Data, err = DataURI(key)
+		} else {
+			_, err = s.Stat(key)
+			c.URL = URL(id, size)
+		}
+
+		switch {
+		case errors.Is(err, ErrNotExist):
+			c = BatchCover{ID: id, Status: StatusMissing}
+		case err != nil:
+			return nil, err
+		}
+
+		covers = append(covers, c)
+	}
+
+	return covers, nil
+}
@@ -0,0 +1,36 @@
+package cover
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	s, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	SetStore(s)
+
+	withCover, withoutCover := uuid.New(), uuid.New()
+	assert.NoError(t, s.Put(Key(withCover.String(), "s"), strings.NewReader("cover")))
+	assert.NoError(t, s.Put("none.jpg", strings.NewReader("none")))
+
+	covers, err := Batch([]uuid.UUID{withCover, withoutCover}, "s", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchCover{
+		{ID: withCover, Status: StatusOK, URL: URL(withCover, "s")},
+		{ID: withoutCover, Status: StatusMissing},
+	}, covers)
+
+	covers, err = Batch([]uuid.UUID{withCover, withoutCover}, "s", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "data:image/jpeg;base64,Y292ZXI=", covers[0].Data)
+	assert.Empty(t, covers[0].URL)
+	assert.Equal(t, StatusMissing, covers[1].Status)
+	assert.Empty(t, covers[1].Data)
+}
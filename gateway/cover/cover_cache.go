@@ -0,0 +1,135 @@
+package cover
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskCache keeps generated cover derivatives in a local directory. If the
+// cache grows beyond maxBytes, the least recently used files are evicted.
+type diskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	total   int64
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	size     int64
+	lastUsed time.Time
+}
+
+// newDiskCache creates the cache directory and indexes the files already in it.
+func newDiskCache(dir string, maxBytes int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &diskCache{dir: dir, maxBytes: maxBytes, entries: map[string]*cacheEntry{}}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		c.entries[f.Name()] = &cacheEntry{size: info.Size(), lastUsed: info.ModTime()}
+		c.total += info.Size()
+	}
+
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+
+	return c, nil
+}
+
+// Get returns the cached file and marks it as recently used.
+func (c *diskCache) Get(name string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		c.remove(name)
+		return nil, false
+	}
+
+	entry.lastUsed = time.Now()
+	return data, true
+}
+
+// Put adds the file to the cache and evicts old files if necessary.
+func (c *diskCache) Put(name string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(c.dir, name), data, 0644); err != nil {
+		return err
+	}
+
+	if old, ok := c.entries[name]; ok {
+		c.total -= old.size
+	}
+	c.entries[name] = &cacheEntry{size: int64(len(data)), lastUsed: time.Now()}
+	c.total += int64(len(data))
+
+	c.evict()
+	return nil
+}
+
+// Purge removes all files whose name starts with prefix.
+func (c *diskCache) Purge(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name := range c.entries {
+		if strings.HasPrefix(name, prefix) {
+			c.remove(name)
+		}
+	}
+}
+
+// evict removes the least recently used files until the cache fits into maxBytes.
+func (c *diskCache) evict() {
+	if c.total <= c.maxBytes {
+		return
+	}
+
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return c.entries[names[i]].lastUsed.Before(c.entries[names[j]].lastUsed)
+	})
+
+	for _, name := range names {
+		if c.total <= c.maxBytes {
+			return
+		}
+		c.remove(name)
+	}
+}
+
+func (c *diskCache) remove(name string) {
+	if entry, ok := c.entries[name]; ok {
+		c.total -= entry.size
+		delete(c.entries, name)
+	}
+	os.Remove(filepath.Join(c.dir, name))
+}
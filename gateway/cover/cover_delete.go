@@ -1,21 +1,31 @@
 package cover
 
 import (
+	"errors"
+	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 
 	"github.com/google/uuid"
 )
 
 // DeleteCover removes the cover images associated with the given book ID.
-func DeleteCover(bookID uuid.UUID) {
-	path, _ := getPath()
-	sizes := []string{"l", "m", "s"}
+// Missing files are ignored.
+func DeleteCover(bookID uuid.UUID) error {
+	s, err := Store()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	sizes := []string{OriginalSize, "l", "m", "s"}
 	for _, size := range sizes {
-		filename := filepath.Join(path, bookID.String()+"-"+size+".jpg")
-		if err := os.Remove(filename); err == nil {
-			log.Printf("failed to delete file: %s", filename)
+		key := Key(bookID.String(), size)
+		if err := s.Delete(key); err != nil && !errors.Is(err, ErrNotExist) {
+			log.Printf("failed to delete file: %s: %v", key, err)
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", key, err))
 		}
 	}
+	purgeCache(bookID.String())
+
+	return errors.Join(errs...)
 }
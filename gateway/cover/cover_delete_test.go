@@ -1,8 +1,7 @@
 package cover
 
 import (
-	"os"
-	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -10,22 +9,27 @@ import (
 
 func TestDeleteCover(t *testing.T) {
 	bookID := uuid.New()
-	path, _ := getPath()
+	s, _ := Store()
 	sizes := []string{"l", "m", "s"}
 
 	for _, size := range sizes {
-		filename := filepath.Join(path, bookID.String()+"-"+size+".jpg")
-		if err := os.WriteFile(filename, []byte("test"), 0644); err != nil {
+		if err := s.Put(Key(bookID.String(), size), strings.NewReader("test")); err != nil {
 			t.Fatalf("failed to create test file: %v", err)
 		}
 	}
 
-	DeleteCover(bookID)
+	if err := DeleteCover(bookID); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// deleting a missing cover is no error
+	if err := DeleteCover(bookID); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
 
 	for _, size := range sizes {
-		filename := filepath.Join(path, bookID.String()+"-"+size+".jpg")
-		if _, err := os.Stat(filename); !os.IsNotExist(err) {
-			t.Errorf("file %s was not deleted", filename)
+		if _, err := s.Stat(Key(bookID.String(), size)); err != ErrNotExist {
+			t.Errorf("file %s was not deleted", Key(bookID.String(), size))
 		}
 	}
 }
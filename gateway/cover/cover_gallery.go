@@ -0,0 +1,107 @@
+package cover
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// ImageKey returns the key of an additional image of the book in the given size.
+func ImageKey(bookID, imageID string, size string) string {
+	return bookID + "-" + imageID + "-" + size + ".jpg"
+}
+
+// ParseImageKey splits the key of an additional image into the book ID,
+// the image ID and the size.
+func ParseImageKey(key string) (uuid.UUID, uuid.UUID, string, bool) {
+	bookID, rest, ok := ParseKey(key)
+	if !ok {
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	i := strings.LastIndex(rest, "-")
+	if i < 0 {
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	imageID, err := uuid.Parse(rest[:i])
+	size := rest[i+1:]
+	if _, known := Sizes[size]; err != nil || !known {
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	return bookID, imageID, size, true
+}
+
+// ImageURL returns the public URL of an additional image of the book. The
+// base URL can be changed with COVER_IMAGE_URL.
+func ImageURL(bookID, imageID uuid.UUID, size string) string {
+	viper.SetDefault("COVER_IMAGE_URL", "/apis/core/1/api/public/book/image")
+
+	base := strings.TrimRight(viper.GetString("COVER_IMAGE_URL"), "/")
+	return base + "/" + ImageKey(bookID.String(), imageID.String(), size)
+}
+
+// SaveImage validates the upload like a cover and stores it in all Sizes
+// as an additional image of the book.
+func SaveImage(data []byte, bookID, imageID uuid.UUID) error {
+	img, err := decodeImage(data)
+	if err != nil {
+		return err
+	}
+
+	s, err := Store()
+	if err != nil {
+		return err
+	}
+
+	return putSizes(s, img, func(size string) string {
+		return ImageKey(bookID.String(), imageID.String(), size)
+	})
+}
+
+// DeleteImage removes an additional image of the book. Missing files are ignored.
+func DeleteImage(bookID, imageID uuid.UUID) error {
+	s, err := Store()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for size := range Sizes {
+		key := ImageKey(bookID.String(), imageID.String(), size)
+		if err := s.Delete(key); err != nil && !errors.Is(err, ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DeleteGallery removes all additional images of the book.
+func DeleteGallery(bookID uuid.UUID) error {
+	s, err := Store()
+	if err != nil {
+		return err
+	}
+
+	files, err := s.List(bookID.String() + "-")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, f := range files {
+		if _, _, _, ok := ParseImageKey(f.Key); !ok {
+			continue
+		}
+		if err := s.Delete(f.Key); err != nil && !errors.Is(err, ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", f.Key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package cover
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageKey(t *testing.T) {
+	bookID, imageID := uuid.New(), uuid.New()
+
+	parsedBook, parsedImage, size, ok := ParseImageKey(ImageKey(bookID.String(), imageID.String(), "m"))
+	assert.True(t, ok)
+	assert.Equal(t, bookID, parsedBook)
+	assert.Equal(t, imageID, parsedImage)
+	assert.Equal(t, "m", size)
+
+	_, _, _, ok = ParseImageKey(Key(bookID.String(), "l"))
+	assert.False(t, ok)
+	_, _, _, ok = ParseImageKey(ImageKey(bookID.String(), imageID.String(), "o"))
+	assert.False(t, ok)
+	_, _, _, ok = ParseImageKey("../" + ImageKey(bookID.String(), imageID.String(), "m"))
+	assert.False(t, ok)
+}
+
+func TestGallery(t *testing.T) {
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	s, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	SetStore(s)
+
+	bookID, first, second := uuid.New(), uuid.New(), uuid.New()
+	assert.NoError(t, SaveImage(testJPEG(t, 400, 600), bookID, first))
+	assert.NoError(t, SaveImage(testJPEG(t, 400, 600), bookID, second))
+	assert.ErrorIs(t, SaveImage([]byte("text"), bookID, uuid.New()), ErrUnsupportedType)
+
+	for size := range Sizes {
+		_, err := s.Stat(ImageKey(bookID.String(), first.String(), size))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, DeleteImage(bookID, first))
+	_, err = s.Stat(ImageKey(bookID.String(), first.String(), "l"))
+	assert.ErrorIs(t, err, ErrNotExist)
+
+	assert.NoError(t, s.Put(Key(bookID.String(), "l"), strings.NewReader("cover")))
+	assert.NoError(t, DeleteGallery(bookID))
+
+	files, err := s.List("")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, Key(bookID.String(), "l"), files[0].Key)
+}
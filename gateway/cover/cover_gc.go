@@ -0,0 +1,204 @@
+package cover
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookLookup returns which of the given book IDs still exist.
+type BookLookup func(ids []uuid.UUID) (map[uuid.UUID]bool, error)
+
+// gcGracePeriod protects recently written files, e.g. covers uploaded while
+// the book is still being created.
+const gcGracePeriod = time.Hour
+
+// Report is the result of a scan of the cover store.
+type Report struct {
+	Orphans     []FileInfo  `json:"orphans"`
+	Incomplete  []uuid.UUID `json:"incomplete"`
+	UsedBytes   int64       `json:"used_bytes"`
+	OrphanBytes int64       `json:"orphan_bytes"`
+	Deleted     int         `json:"deleted"`
+}
+
+// Usage is the space taken by the files of existing books and by orphans at
+// the last scan.
+type Usage struct {
+	UsedBytes   int64
+	OrphanBytes int64
+	ScannedAt   time.Time
+}
+
+var (
+	usageMu sync.Mutex
+	usage   *Usage
+)
+
+// LastUsage returns the usage found by the last scan, false if the store
+// wasn't scanned yet.
+func LastUsage() (Usage, bool) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	if usage == nil {
+		return Usage{}, false
+	}
+	return *usage, true
+}
+
+func setUsage(used, orphan int64) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	usage = &Usage{UsedBytes: used, OrphanBytes: orphan, ScannedAt: time.Now()}
+}
+
+// ParseKey splits a key into the book ID and the rest of the name, which is
+// the size of a cover or the image ID and size of an additional image.
+func ParseKey(key string) (uuid.UUID, string, bool) {
+	name, ok := strings.CutSuffix(key, ".jpg")
+	if !ok || len(name) < 38 || name[36] != '-' {
+		return uuid.Nil, "", false
+	}
+
+	id, err := uuid.Parse(name[:36])
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+
+	return id, name[37:], true
+}
+
+// Scan lists the files of the cover store. Covers and additional images of
+// books the lookup doesn't know are orphans, books that have some but not
+// all cover Sizes are incomplete. Other files, like the placeholder, count as
+// used.
+func Scan(lookup BookLookup) (Report, error) {
+	s, err := Store()
+	if err != nil {
+		return Report{}, err
+	}
+
+	files, err := s.List("")
+	if err != nil {
+		return Report{}, err
+	}
+
+	byBook := map[uuid.UUID][]FileInfo{}
+	coverSizes := map[uuid.UUID]map[string]bool{}
+	report := Report{Orphans: []FileInfo{}, Incomplete: []uuid.UUID{}}
+	for _, f := range files {
+		id, size, ok := ParseKey(f.Key)
+		if !ok {
+			report.UsedBytes += f.Size
+			continue
+		}
+		byBook[id] = append(byBook[id], f)
+
+		if _, known := Sizes[size]; known {
+			if coverSizes[id] == nil {
+				coverSizes[id] = map[string]bool{}
+			}
+			coverSizes[id][size] = true
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(byBook))
+	for id := range byBook {
+		ids = append(ids, id)
+	}
+
+	existing, err := lookup(ids)
+	if err != nil {
+		return Report{}, err
+	}
+
+	for _, id := range ids {
+		if !existing[id] {
+			report.Orphans = append(report.Orphans, byBook[id]...)
+			for _, f := range byBook[id] {
+				report.OrphanBytes += f.Size
+			}
+			continue
+		}
+
+		for _, f := range byBook[id] {
+			report.UsedBytes += f.Size
+		}
+		if n := len(coverSizes[id]); n > 0 && n < len(Sizes) {
+			report.Incomplete = append(report.Incomplete, id)
+		}
+	}
+
+	slices.SortFunc(report.Orphans, func(a, b FileInfo) int { return strings.Compare(a.Key, b.Key) })
+	slices.SortFunc(report.Incomplete, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+
+	setUsage(report.UsedBytes, report.OrphanBytes)
+	return report, nil
+}
+
+// CollectGarbage scans the cover store and deletes the orphans that are
+// older than the grace period.
+func CollectGarbage(lookup BookLookup) (Report, error) {
+	report, err := Scan(lookup)
+	if err != nil {
+		return report, err
+	}
+
+	s, err := Store()
+	if err != nil {
+		return report, err
+	}
+
+	var errs []error
+	var deleted int64
+	purged := map[uuid.UUID]bool{}
+	for _, f := range report.Orphans {
+		if time.Since(f.ModTime) < gcGracePeriod {
+			continue
+		}
+		if err := s.Delete(f.Key); err != nil && !errors.Is(err, ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		report.Deleted++
+		deleted += f.Size
+
+		if id, _, ok := ParseKey(f.Key); ok && !purged[id] {
+			purgeCache(id.String())
+			purged[id] = true
+		}
+	}
+
+	setUsage(report.UsedBytes, report.OrphanBytes-deleted)
+	return report, errors.Join(errs...)
+}
+
+// StartGC runs CollectGarbage in the given interval until stop is closed.
+// The store is scanned at the start, so the usage is known before the first
+// run.
+func StartGC(lookup BookLookup, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	if _, err := Scan(lookup); err != nil {
+		log.Printf("cover gc: scan failed: %v", err)
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			report, err := CollectGarbage(lookup)
+			if err != nil {
+				log.Printf("cover gc failed: %v", err)
+			}
+			log.Printf("cover gc: deleted %d of %d orphaned files", report.Deleted, len(report.Orphans))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cover
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKey(t *testing.T) {
+	id := uuid.MustParse("36ee6d5c-820b-4f0c-9637-73b63dacc2a7")
+
+	parsed, size, ok := ParseKey(Key(id.String(), "l"))
+	assert.True(t, ok)
+	assert.Equal(t, id, parsed)
+	assert.Equal(t, "l", size)
+
+	_, _, ok = ParseKey("none.jpg")
+	assert.False(t, ok)
+	_, _, ok = ParseKey(id.String() + "-l.png")
+	assert.False(t, ok)
+}
+
+func TestCollectGarbage(t *testing.T) {
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	root := t.TempDir()
+	s, err := NewFSStore(root)
+	assert.NoError(t, err)
+	SetStore(s)
+
+	book, orphan, incomplete := uuid.New(), uuid.New(), uuid.New()
+	for _, size := range []string{"l", "m", "s"} {
+		assert.NoError(t, s.Put(Key(book.String(), size), strings.NewReader("book")))
+		assert.NoError(t, s.Put(Key(orphan.String(), size), strings.NewReader("orphan")))
+	}
+	assert.NoError(t, s.Put(Key(incomplete.String(), "l"), strings.NewReader("cover")))
+	assert.NoError(t, s.Put(ImageKey(orphan.String(), uuid.NewString(), "l"), strings.NewReader("image")))
+
+	// books with additional images only have no incomplete cover
+	galleryOnly := uuid.New()
+	assert.NoError(t, s.Put(ImageKey(galleryOnly.String(), uuid.NewString(), "l"), strings.NewReader("image")))
+	assert.NoError(t, s.Put("none.jpg", strings.NewReader("none")))
+
+	lookup := func(ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+		return map[uuid.UUID]bool{book: true, incomplete: true, galleryOnly: true}, nil
+	}
+
+	report, err := Scan(lookup)
+	assert.NoError(t, err)
+	assert.Len(t, report.Orphans, 4)
+	assert.Equal(t, []uuid.UUID{incomplete}, report.Incomplete)
+	assert.Equal(t, int64(18+5), report.OrphanBytes)
+	assert.Equal(t, int64(12+5+4+5), report.UsedBytes)
+
+	// recently written orphans are kept
+	report, err = CollectGarbage(lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Deleted)
+
+	past := time.Now().Add(-2 * gcGracePeriod)
+	for _, f := range report.Orphans {
+		assert.NoError(t, os.Chtimes(filepath.Join(root, f.Key), past, past))
+	}
+
+	report, err = CollectGarbage(lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Deleted)
+
+	usage, ok := LastUsage()
+	assert.True(t, ok)
+	assert.Equal(t, int64(12+5+4+5), usage.UsedBytes)
+	assert.Equal(t, int64(0), usage.OrphanBytes)
+
+	report, err = Scan(lookup)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Orphans)
+	_, err = s.Stat(Key(book.String(), "l"))
+	assert.NoError(t, err)
+}
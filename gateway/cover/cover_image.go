@@ -0,0 +1,244 @@
+package cover
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chai2010/webp"
+	"github.com/disintegration/imaging"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// Output formats of generated covers.
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// OriginalSize is the size suffix of the largest stored version of a cover.
+// Derivatives are generated from it.
+const OriginalSize = "o"
+
+// Widths lists the widths covers are generated in. Requested widths are
+// rounded up to the next width of the list.
+var Widths = []int{100, 200, 300, 400, 600, 800}
+
+// maxAspectRatio limits the height of a cropped cover to a multiple of its width.
+const maxAspectRatio = 3
+
+// Image is a generated version of a cover.
+type Image struct {
+	Data        []byte
+	ContentType string
+	ModTime     time.Time
+}
+
+var (
+	cacheOnce sync.Once
+	cache     *diskCache
+)
+
+// getCache returns the derivative cache configured by COVER_CACHE_DIR and
+// COVER_CACHE_SIZE (megabytes). Nil is returned if it can't be created.
+func getCache() *diskCache {
+	cacheOnce.Do(func() {
+		viper.SetDefault("COVER_CACHE_DIR", filepath.Join(os.TempDir(), "warehouse-covers"))
+		viper.SetDefault("COVER_CACHE_SIZE", 256)
+
+		c, err := newDiskCache(viper.GetString("COVER_CACHE_DIR"), viper.GetInt64("COVER_CACHE_SIZE")*1000000)
+		if err == nil {
+			cache = c
+		}
+	})
+	return cache
+}
+
+// purgeCache removes all cached derivatives of the book.
+func purgeCache(bookID string) {
+	if c := getCache(); c != nil {
+		c.Purge(bookID + "_")
+	}
+}
+
+// ClampSize rounds the width up to the next allowed width and scales the
+// height accordingly. A height of zero keeps the aspect ratio of the cover.
+func ClampSize(width, height int) (int, int) {
+	clamped := Widths[len(Widths)-1]
+	for _, w := range Widths {
+		if w >= width {
+			clamped = w
+			break
+		}
+	}
+
+	if height <= 0 || width <= 0 {
+		return clamped, 0
+	}
+
+	h := int(math.Round(float64(height) * float64(clamped) / float64(width)))
+	return clamped, min(max(h, 1), clamped*maxAspectRatio)
+}
+
+// NegotiateFormat picks the output format from an Accept header. WebP is
+// used if the client accepts it, JPEG otherwise.
+func NegotiateFormat(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != "image/webp" {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+			continue
+		}
+		return FormatWebP
+	}
+	return FormatJPEG
+}
+
+// Resize returns the cover of the book in the requested size and format.
+// Sizes are clamped with ClampSize, covers are never upscaled. If a height
+// is given, the cover is cropped to the aspect ratio. Books without a cover
+// get the placeholder none.jpg.
+func Resize(bookID string, width, height int, format string) (Image, error) {
+	s, err := Store()
+	if err != nil {
+		return Image{}, err
+	}
+
+	key, info, err := findSource(s, bookID)
+	if err != nil {
+		return Image{}, err
+	}
+
+	width, height = ClampSize(width, height)
+
+	prefix := bookID
+	if key == "none.jpg" {
+		prefix = "none"
+	}
+	name := fmt.Sprintf("%s_%dx%d_%d.%s", prefix, width, height, info.ModTime.UnixNano(), format)
+
+	img := Image{ContentType: "image/" + format, ModTime: info.ModTime}
+
+	c := getCache()
+	if c != nil {
+		if data, ok := c.Get(name); ok {
+			img.Data = data
+			return img, nil
+		}
+	}
+
+	file, err := s.Get(key)
+	if err != nil {
+		return Image{}, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return Image{}, err
+	}
+
+	src, err := decodeImage(data)
+	if err != nil {
+		return Image{}, err
+	}
+
+	img.Data, err = encodeImage(transform(src, width, height), format)
+	if err != nil {
+		return Image{}, err
+	}
+
+	if c != nil {
+		c.Put(name, img.Data)
+	}
+
+	return img, nil
+}
+
+// ServeResized writes the cover in the requested size. The format is
+// negotiated with the Accept header.
+func ServeResized(c *gin.Context, bookID string, width, height int) {
+	format := NegotiateFormat(c.GetHeader("Accept"))
+
+	img, err := Resize(bookID, width, height, format)
+	if err == ErrNotExist {
+		c.JSON(http.StatusNotFound, gin.H{"msg": "Cover not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		return
+	}
+
+	ext := format
+	if format == FormatJPEG {
+		ext = "jpg"
+	}
+
+	c.Header("Vary", "Accept")
+	serveData(c, fmt.Sprintf("%s_%dx%d.%s", bookID, width, height, ext), img.ModTime, img.Data)
+}
+
+// findSource returns the largest stored version of the cover.
+func findSource(s CoverStore, bookID string) (string, FileInfo, error) {
+	for _, size := range []string{OriginalSize, "l", "m", "s"} {
+		key := Key(bookID, size)
+		if info, err := s.Stat(key); err == nil {
+			return key, info, nil
+		}
+	}
+
+	info, err := s.Stat("none.jpg")
+	if err != nil {
+		return "", FileInfo{}, ErrNotExist
+	}
+	return "none.jpg", info, nil
+}
+
+// transform fits the image to the width or, if height is set, crops it to
+// width x height. Images smaller than requested are not upscaled.
+func transform(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	if width > bounds.Dx() {
+		if height > 0 {
+			height = int(math.Round(float64(height) * float64(bounds.Dx()) / float64(width)))
+		}
+		width = bounds.Dx()
+	}
+
+	if height <= 0 {
+		return imaging.Resize(img, width, 0, imaging.Lanczos)
+	}
+	return imaging.Fill(img, width, max(height, 1), imaging.Center, imaging.Lanczos)
+}
+
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	var err error
+	switch format {
+	case FormatWebP:
+		err = webp.Encode(buf, img, &webp.Options{Quality: Quality})
+	default:
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: Quality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,146 @@
+package cover
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/webp"
+)
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	return buf.Bytes()
+}
+
+func TestClampSize(t *testing.T) {
+	testCases := []struct {
+		width, height  int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{100, 0, 100, 0},
+		{150, 0, 200, 0},
+		{5000, 0, 800, 0},
+		{150, 150, 200, 200},
+		{400, 600, 400, 600},
+		{100, 1000, 100, 300},
+		{0, 100, 100, 0},
+	}
+
+	for _, tc := range testCases {
+		w, h := ClampSize(tc.width, tc.height)
+		assert.Equal(t, tc.expectedWidth, w)
+		assert.Equal(t, tc.expectedHeight, h)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	assert.Equal(t, FormatWebP, NegotiateFormat("image/avif,image/webp,*/*"))
+	assert.Equal(t, FormatWebP, NegotiateFormat("image/webp;q=0.8, image/jpeg"))
+	assert.Equal(t, FormatJPEG, NegotiateFormat("image/webp;q=0, image/jpeg"))
+	assert.Equal(t, FormatJPEG, NegotiateFormat("image/jpeg,*/*"))
+	assert.Equal(t, FormatJPEG, NegotiateFormat(""))
+}
+
+func TestResize(t *testing.T) {
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	s, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	SetStore(s)
+
+	data := testJPEG(t, 400, 600)
+	assert.NoError(t, s.Put(Key("book", "l"), bytes.NewReader(data)))
+
+	img, err := Resize("book", 150, 0, FormatJPEG)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", img.ContentType)
+	decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, decoded.Bounds().Dx())
+
+	img, err = Resize("book", 200, 100, FormatWebP)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/webp", img.ContentType)
+	decoded, err = webp.Decode(bytes.NewReader(img.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, decoded.Bounds().Dx())
+	assert.Equal(t, 100, decoded.Bounds().Dy())
+
+	// no upscaling beyond the source
+	img, err = Resize("book", 800, 0, FormatJPEG)
+	assert.NoError(t, err)
+	decoded, err = jpeg.Decode(bytes.NewReader(img.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 400, decoded.Bounds().Dx())
+
+	_, err = Resize("missing", 100, 0, FormatJPEG)
+	assert.ErrorIs(t, err, ErrNotExist)
+
+	assert.NoError(t, s.Put("none.jpg", bytes.NewReader(data)))
+	_, err = Resize("missing", 100, 0, FormatJPEG)
+	assert.NoError(t, err)
+}
+
+func TestServeResized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	s, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	SetStore(s)
+
+	assert.NoError(t, s.Put(Key("book", "l"), bytes.NewReader(testJPEG(t, 400, 600))))
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		ServeResized(c, "book", 100, 0)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "image/webp,*/*")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	c, err := newDiskCache(t.TempDir(), 10)
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Put("a", []byte("1234")))
+	assert.NoError(t, c.Put("b", []byte("1234")))
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	// b is the least recently used file
+	assert.NoError(t, c.Put("c", []byte("1234")))
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+
+	c.Purge("a")
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+}
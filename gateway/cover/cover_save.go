@@ -2,155 +2,232 @@ package cover
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
-	"image/png"
-	"mime/multipart"
+	_ "image/png"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 
 	"github.com/disintegration/imaging"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/image/webp"
+	"github.com/spf13/viper"
+	_ "golang.org/x/image/webp"
 )
 
-const uploadsDir = "uploads"
+// UploadError describes why an uploaded cover was rejected.
+type UploadError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *UploadError) Error() string {
+	return e.Message
+}
+
+// Errors returned for rejected uploads.
+var (
+	ErrMissingFile     = &UploadError{http.StatusBadRequest, "missing_file", "Image upload required"}
+	ErrTooLarge        = &UploadError{http.StatusRequestEntityTooLarge, "too_large", "Image is too large"}
+	ErrUnsupportedType = &UploadError{http.StatusUnsupportedMediaType, "unsupported_type", "Unsupported image format"}
+	ErrDimensions      = &UploadError{http.StatusUnprocessableEntity, "invalid_dimensions", "Image dimensions are out of range"}
+	ErrInvalidImage    = &UploadError{http.StatusUnprocessableEntity, "invalid_image", "Image could not be decoded"}
+)
+
+// supportedTypes lists the sniffed content types accepted for uploads.
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
 
-// SaveCover saves the uploaded cover image in different sizes.
+// maxBytes returns the upload limit in bytes, configured by COVER_MAX_BYTES.
+func maxBytes() int64 {
+	viper.SetDefault("COVER_MAX_BYTES", 10<<20)
+	return viper.GetInt64("COVER_MAX_BYTES")
+}
+
+// maxDimension returns the largest accepted width or height in pixels,
+// configured by COVER_MAX_DIMENSION.
+func maxDimension() int {
+	viper.SetDefault("COVER_MAX_DIMENSION", 6000)
+	return viper.GetInt("COVER_MAX_DIMENSION")
+}
+
+// SaveCover saves the uploaded cover image in different sizes. The upload
+// is read into memory, the client's filename and content type are ignored.
 func SaveCover(c *gin.Context, imageUUID string) {
-	imageData, err := c.FormFile("cover")
+	data, _, err := ReadUpload(c, "cover")
+	if err == nil {
+		err = saveResizedImages(data, imageUUID)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Image upload required"})
+		WriteError(c, err)
 		return
 	}
 
-	if err := saveResizedImages(c, imageData, imageUUID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	c.JSON(http.StatusOK, gin.H{"message": "Image uploaded successfully"})
+}
+
+// WriteError responds with the status and code of an UploadError or with
+// an internal error.
+func WriteError(c *gin.Context, err error) {
+	var uploadErr *UploadError
+	if errors.As(err, &uploadErr) {
+		c.JSON(uploadErr.Status, gin.H{"error": uploadErr.Message, "code": uploadErr.Code})
 		return
 	}
 
-	c.Status(http.StatusOK)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error", "code": "internal"})
 }
 
-func saveResizedImages(c *gin.Context, imageData *multipart.FileHeader, imageUUID string) error {
-	imagePath, err := saveUploadedImage(c, imageData, imageUUID)
-	if err != nil {
-		return fmt.Errorf("failed to save uploaded image: %w", err)
-	}
-	defer os.Remove(imagePath)
+// maxFieldBytes limits the other fields of an upload form.
+const maxFieldBytes = 1 << 10
 
-	sizes := []struct {
-		width  int
-		suffix string
-	}{
-		{400, "l"},
-		{200, "m"},
-		{100, "s"},
-	}
+// ReadUpload reads the file of the given field of the multipart request
+// and returns the values of the other fields. Requests larger than the
+// limit are cut off before they are read completely.
+func ReadUpload(c *gin.Context, field string) ([]byte, map[string]string, error) {
+	limit := maxBytes()
 
-	for _, size := range sizes {
-		resizedImagePath := filepath.Join(uploadsDir, fmt.Sprintf("%s-%s%s", imageUUID, size.suffix, ".jpg"))
+	// leave room for the multipart headers and other fields of the form
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+64<<10)
 
-		if err := resizeAndSaveImage(imagePath, resizedImagePath, size.width); err != nil {
-			return fmt.Errorf("failed to resize image: %w", err)
-		}
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, nil, ErrMissingFile
 	}
 
-	return nil
-}
+	var data []byte
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, uploadReadError(err)
+		}
 
-func saveUploadedImage(c *gin.Context, imageData *multipart.FileHeader, imageUUID string) (string, error) {
-	imageFilename := fmt.Sprintf("%s%s", imageUUID, filepath.Ext(imageData.Filename))
-	currentDir, _ := os.Getwd()
-	uploadsDirPath := filepath.Join(currentDir, uploadsDir)
+		if part.FormName() != field {
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes))
+			part.Close()
+			if err != nil {
+				return nil, nil, uploadReadError(err)
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
 
-	if err := os.MkdirAll(uploadsDirPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create uploads directory")
+		data, err = io.ReadAll(io.LimitReader(part, limit+1))
+		part.Close()
+		if err != nil {
+			return nil, nil, uploadReadError(err)
+		}
+		if int64(len(data)) > limit {
+			return nil, nil, ErrTooLarge
+		}
 	}
 
-	imagePath := filepath.Join(uploadsDirPath, imageFilename)
-	if err := c.SaveUploadedFile(imageData, imagePath); err != nil {
-		return "", fmt.Errorf("failed to save image")
+	if len(data) == 0 {
+		return nil, nil, ErrMissingFile
 	}
 
-	return imagePath, nil
+	return data, fields, nil
 }
 
-func resizeAndSaveImage(imagePath string, resizedImagePath string, width int) error {
-	file, err := os.Open(imagePath)
-	if err != nil {
-		return fmt.Errorf("failed to open image: %w", err)
+func uploadReadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return ErrTooLarge
 	}
-	defer file.Close()
-
-	var img image.Image
+	return fmt.Errorf("failed to read uploaded image: %w", err)
+}
 
-	fileHeader := make([]byte, 512)
-	if _, err := file.Read(fileHeader); err != nil {
-		return fmt.Errorf("failed to read file header: %w", err)
+func saveResizedImages(data []byte, imageUUID string) error {
+	img, err := decodeImage(data)
+	if err != nil {
+		return err
 	}
 
-	mimeType := http.DetectContentType(fileHeader)
-	_, err = file.Seek(0, 0)
+	s, err := Store()
 	if err != nil {
-		return fmt.Errorf("failed to reset file pointer: %w", err)
+		return err
 	}
 
-	switch mimeType {
-	case "image/jpeg":
-		img, err = jpeg.Decode(file)
-	case "image/png":
-		img, err = png.Decode(file)
-	case "image/webp":
-		img, err = convertWebp(file)
-	default:
-		return fmt.Errorf("unsupported image format")
+	if err := putSizes(s, img, func(size string) string { return Key(imageUUID, size) }); err != nil {
+		return err
 	}
 
+	// keep the largest version for the on-demand sizes
+	original, err := encodeImage(transform(img, Widths[len(Widths)-1], 0), FormatJPEG)
 	if err != nil {
-		return fmt.Errorf("failed to decode image: %w", err)
+		return err
+	}
+	if err := s.Put(Key(imageUUID, OriginalSize), bytes.NewReader(original)); err != nil {
+		return fmt.Errorf("failed to save original image: %w", err)
 	}
 
-	originalBounds := img.Bounds()
-	aspectRatio := float64(originalBounds.Dx()) / float64(originalBounds.Dy())
-	height := int(float64(width) / aspectRatio)
+	purgeCache(imageUUID)
 
-	resizedImage := imaging.Resize(img, width, height, imaging.Lanczos)
+	return nil
+}
 
-	outFile, err := os.Create(resizedImagePath)
-	if err != nil {
-		return fmt.Errorf("failed to create resized image file: %w", err)
-	}
-	defer outFile.Close()
+// putSizes stores the image in all Sizes under the keys returned by key.
+func putSizes(s CoverStore, img image.Image, key func(size string) string) error {
+	for size, width := range Sizes {
+		buf, err := resizeImage(img, width)
+		if err != nil {
+			return fmt.Errorf("failed to resize image: %w", err)
+		}
 
-	err = jpeg.Encode(outFile, resizedImage, nil)
-	if err != nil {
-		return fmt.Errorf("failed to encode resized image: %w", err)
+		if err := s.Put(key(size), buf); err != nil {
+			return fmt.Errorf("failed to save resized image: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func convertWebp(file *os.File) (image.Image, error) {
-  var err error
-  var img image.Image
-	img, err = webp.Decode(file)
-	if err != nil {
-		return nil, err
+// decodeImage sniffs the content type and checks the dimensions before the
+// image is decoded. The EXIF orientation is applied, all other metadata is
+// dropped when the image is encoded again.
+func decodeImage(data []byte) (image.Image, error) {
+	if !supportedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedType
 	}
 
-	buf := new(bytes.Buffer)
-	err = jpeg.Encode(buf, img, nil)
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidImage
+	}
+
+	limit := maxDimension()
+	if config.Width <= 0 || config.Height <= 0 || config.Width > limit || config.Height > limit {
+		return nil, ErrDimensions
 	}
 
-	img, err = jpeg.Decode(buf)
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidImage
 	}
 
 	return img, nil
 }
+
+func resizeImage(img image.Image, width int) (*bytes.Buffer, error) {
+	originalBounds := img.Bounds()
+	aspectRatio := float64(originalBounds.Dx()) / float64(originalBounds.Dy())
+	height := int(float64(width) / aspectRatio)
+
+	resizedImage := imaging.Resize(img, width, height, imaging.Lanczos)
+
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, resizedImage, &jpeg.Options{Quality: Quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode resized image: %w", err)
+	}
+
+	return buf, nil
+}
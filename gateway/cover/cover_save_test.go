@@ -2,6 +2,9 @@ package cover
 
 import (
 	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -11,9 +14,43 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
+func uploadRequest(t *testing.T, field string, data []byte) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(field, "cover.jpg")
+	assert.NoError(t, err)
+	_, _ = part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// withOrientation inserts an EXIF segment with the orientation tag into the JPEG.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := new(bytes.Buffer)
+	tiff.WriteString("II*\x00")
+	binary.Write(tiff, binary.LittleEndian, uint32(8))
+	binary.Write(tiff, binary.LittleEndian, uint16(1))
+	binary.Write(tiff, binary.LittleEndian, []uint16{0x0112, 3})
+	binary.Write(tiff, binary.LittleEndian, uint32(1))
+	binary.Write(tiff, binary.LittleEndian, []uint16{orientation, 0})
+	binary.Write(tiff, binary.LittleEndian, uint32(0))
+
+	exif := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(exif)+2))
+	segment = append(segment, exif...)
+
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
 func TestSaveCover(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -72,7 +109,7 @@ func TestSaveCover(t *testing.T) {
 			currentDir, _ := os.Getwd()
 
 			for _, expectedImage := range tc.expectedImages {
-				expectedFilePath := filepath.Join(currentDir, uploadsDir, expectedImage)
+				expectedFilePath := filepath.Join(currentDir, "uploads", expectedImage)
 				if _, err := os.Stat(expectedFilePath); os.IsNotExist(err) {
 					t.Errorf("Expected file %s to exist", expectedFilePath)
 				} else {
@@ -80,11 +117,11 @@ func TestSaveCover(t *testing.T) {
 				}
 			}
 
-			assert.NoFileExists(t, filepath.Join(currentDir, uploadsDir, "36ee6d5c-820b-4f0c-9637-73b63dacc2a7.jpg"))
-			assert.NoFileExists(t, filepath.Join(currentDir, uploadsDir, "36ee6d5c-820b-4f0c-9637-73b63dacc2a7.png"))
-			assert.NoFileExists(t, filepath.Join(currentDir, uploadsDir, "36ee6d5c-820b-4f0c-9637-73b63dacc2a7.webp"))
+			assert.NoFileExists(t, filepath.Join(currentDir, "uploads", "36ee6d5c-820b-4f0c-9637-73b63dacc2a7.jpg"))
+			assert.NoFileExists(t, filepath.Join(currentDir, "uploads", "36ee6d5c-820b-4f0c-9637-73b63dacc2a7.png"))
+			assert.NoFileExists(t, filepath.Join(currentDir, "uploads", "36ee6d5c-820b-4f0c-9637-73b63dacc2a7.webp"))
 
-			os.RemoveAll(filepath.Join(currentDir, uploadsDir))
+			os.RemoveAll(filepath.Join(currentDir, "uploads"))
 		})
 	}
 }
@@ -99,8 +136,10 @@ func TestFailingSaveCover(t *testing.T) {
 
 	testCases := []struct {
 		imageName      string
+		expectedStatus int
+		expectedCode   string
 	}{
-		{"test.gif"},
+		{"test.gif", http.StatusUnsupportedMediaType, "unsupported_type"},
 	}
 
 	for _, tc := range testCases {
@@ -122,11 +161,99 @@ func TestFailingSaveCover(t *testing.T) {
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
 
-			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tc.expectedCode+`"`)
 
 			currentDir, _ := os.Getwd()
 
-			os.RemoveAll(filepath.Join(currentDir, uploadsDir))
+			os.RemoveAll(filepath.Join(currentDir, "uploads"))
+		})
+	}
+}
+
+func TestSaveCoverValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	s, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	SetStore(s)
+
+	viper.Set("COVER_MAX_BYTES", 50000)
+	viper.Set("COVER_MAX_DIMENSION", 1000)
+	t.Cleanup(func() {
+		viper.Set("COVER_MAX_BYTES", nil)
+		viper.Set("COVER_MAX_DIMENSION", nil)
+	})
+
+	router := gin.New()
+	router.POST("/upload", func(c *gin.Context) {
+		SaveCover(c, "book")
+	})
+
+	testCases := []struct {
+		name           string
+		field          string
+		data           []byte
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"missing file", "image", testJPEG(t, 10, 10), http.StatusBadRequest, "missing_file"},
+		{"too large", "cover", make([]byte, 60000), http.StatusRequestEntityTooLarge, "too_large"},
+		{"text", "cover", []byte("not an image"), http.StatusUnsupportedMediaType, "unsupported_type"},
+		{"truncated", "cover", testJPEG(t, 10, 10)[:20], http.StatusUnprocessableEntity, "invalid_image"},
+		{"dimensions", "cover", testJPEG(t, 1200, 10), http.StatusUnprocessableEntity, "invalid_dimensions"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, uploadRequest(t, tc.field, tc.data))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tc.expectedCode+`"`)
 		})
 	}
+
+	files, err := s.List("")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestSaveCoverOrientation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	s, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	SetStore(s)
+
+	router := gin.New()
+	router.POST("/upload", func(c *gin.Context) {
+		SaveCover(c, "book")
+	})
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, 400, 200)), nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, uploadRequest(t, "cover", withOrientation(buf.Bytes(), 6)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	file, err := s.Get(Key("book", OriginalSize))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "Exif")
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, config.Width)
+	assert.Equal(t, 400, config.Height)
 }
@@ -0,0 +1,77 @@
+package cover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// CacheControl is sent with every served cover. Covers keep their URL when
+// they are replaced, so clients revalidate them with the ETag after an hour.
+const CacheControl = "public, max-age=3600"
+
+// URL returns the public URL of the cover of the book in the given size.
+// The base URL can be changed with COVER_URL.
+func URL(bookID uuid.UUID, size string) string {
+	viper.SetDefault("COVER_URL", "/apis/core/1/api/public/book/cover")
+
+	base := strings.TrimRight(viper.GetString("COVER_URL"), "/")
+	return fmt.Sprintf("%s/%s_%dx0.jpg", base, bookID, Sizes[size])
+}
+
+// Serve writes the file with an ETag built from its content, Last-Modified
+// and Cache-Control headers. Conditional requests are answered with 304.
+func Serve(c *gin.Context, key string) {
+	s, err := Store()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		return
+	}
+
+	info, err := s.Stat(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"msg": "Cover not found"})
+		return
+	}
+
+	file, err := s.Get(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"msg": "Cover not found"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		return
+	}
+
+	serveData(c, key, info.ModTime, data)
+}
+
+// serveData writes the data with ETag, Last-Modified and Cache-Control
+// headers and answers conditional requests.
+func serveData(c *gin.Context, name string, modTime time.Time, data []byte) {
+	c.Header("ETag", ETag(data))
+	c.Header("Cache-Control", CacheControl)
+	c.Header("Content-Type", http.DetectContentType(data))
+	c.Header("Content-Disposition", `inline; filename="`+name+`"`)
+
+	http.ServeContent(c.Writer, c.Request, name, modTime, bytes.NewReader(data))
+}
+
+// ETag returns a strong entity tag for the content.
+func ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
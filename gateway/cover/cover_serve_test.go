@@ -0,0 +1,66 @@
+package cover
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	old, _ := Store()
+	t.Cleanup(func() { SetStore(old) })
+
+	s, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	SetStore(s)
+	assert.NoError(t, s.Put("a-l.jpg", strings.NewReader("cover")))
+
+	router := gin.New()
+	router.GET("/:key", func(c *gin.Context) {
+		Serve(c, c.Param("key"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/a-l.jpg", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "cover", w.Body.String())
+	assert.Equal(t, ETag([]byte("cover")), w.Header().Get("ETag"))
+	assert.Equal(t, CacheControl, w.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+
+	req := httptest.NewRequest(http.MethodGet, "/a-l.jpg", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w304 := httptest.NewRecorder()
+	router.ServeHTTP(w304, req)
+	assert.Equal(t, http.StatusNotModified, w304.Code)
+	assert.Empty(t, w304.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/a-l.jpg", nil)
+	req.Header.Set("If-Modified-Since", w.Header().Get("Last-Modified"))
+	w304 = httptest.NewRecorder()
+	router.ServeHTTP(w304, req)
+	assert.Equal(t, http.StatusNotModified, w304.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/a-l.jpg", nil)
+	req.Header.Set("If-None-Match", `"outdated"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing.jpg", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestURL(t *testing.T) {
+	id := uuid.MustParse("36ee6d5c-820b-4f0c-9637-73b63dacc2a7")
+	assert.Equal(t, "/apis/core/1/api/public/book/cover/36ee6d5c-820b-4f0c-9637-73b63dacc2a7_400x0.jpg", URL(id, "l"))
+	assert.Equal(t, "/apis/core/1/api/public/book/cover/36ee6d5c-820b-4f0c-9637-73b63dacc2a7_100x0.jpg", URL(id, "s"))
+}
@@ -2,23 +2,37 @@ package cover
 
 import (
 	"encoding/base64"
-	"os"
-	"path/filepath"
+	"io"
 
 	"github.com/google/uuid"
 )
 
+// ShowCover returns the cover as a base64 data URI. Books without a cover
+// get the placeholder none.jpg.
 func ShowCover(size string, bookID uuid.UUID) string {
-	path, _ := getPath()
-	filename := filepath.Join(path, bookID.String()+"-"+size+".jpg")
+	data, err := DataURI(Key(bookID.String(), size))
+	if err != nil {
+		data, _ = DataURI("none.jpg")
+	}
+	return data
+}
 
-	if _, err := os.Stat(filename); err != nil {
-		filename = filepath.Join(path, "none.jpg")
+// DataURI returns the file as a base64 data URI.
+func DataURI(key string) (string, error) {
+	s, err := Store()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := s.Get(key)
+	if err != nil {
+		return "", err
 	}
+	defer file.Close()
 
-	data, err := os.ReadFile(filename)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		return ""
+		return "", err
 	}
-	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data)
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data), nil
 }
@@ -0,0 +1,89 @@
+package cover
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrNotExist is returned if a file is not in the store.
+var ErrNotExist = errors.New("cover: file does not exist")
+
+// FileInfo describes a file in the store.
+type FileInfo struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// CoverStore stores the cover files. Keys are slash separated paths
+// relative to the root of the store, e.g. "{uuid}-l.jpg".
+type CoverStore interface {
+	// Put creates or replaces the file.
+	Put(key string, r io.Reader) error
+	// Get opens the file. The caller has to close it.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the file.
+	Delete(key string) error
+	// Stat returns the size and modification time of the file.
+	Stat(key string) (FileInfo, error)
+	// Size returns the total size of all files.
+	Size() (int64, error)
+	// List returns all files whose key starts with prefix.
+	List(prefix string) ([]FileInfo, error)
+}
+
+var (
+	storeMu sync.Mutex
+	store   CoverStore
+)
+
+// Store returns the configured cover store. COVER_STORE selects the driver,
+// fs (default) or s3.
+func Store() (CoverStore, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		s, err := NewStore()
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	}
+
+	return store, nil
+}
+
+// SetStore replaces the cover store, e.g. in tests.
+func SetStore(s CoverStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+// NewStore creates the cover store from the config.
+func NewStore() (CoverStore, error) {
+	viper.SetDefault("COVER_STORE", "fs")
+	viper.SetDefault("COVER_ROOT", "uploads")
+	viper.SetDefault("COVER_S3_REGION", "us-east-1")
+
+	switch viper.GetString("COVER_STORE") {
+	case "fs":
+		return NewFSStore(viper.GetString("COVER_ROOT"))
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  viper.GetString("COVER_S3_ENDPOINT"),
+			Bucket:    viper.GetString("COVER_S3_BUCKET"),
+			Region:    viper.GetString("COVER_S3_REGION"),
+			AccessKey: viper.GetString("COVER_S3_ACCESS_KEY"),
+			SecretKey: viper.GetString("COVER_S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("cover: unknown store %q", viper.GetString("COVER_STORE"))
+	}
+}
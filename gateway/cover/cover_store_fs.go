@@ -0,0 +1,165 @@
+package cover
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FSStore stores the covers in a directory of the local filesystem.
+type FSStore struct {
+	root string
+}
+
+// NewFSStore creates a filesystem store and its root directory.
+// A relative root is resolved against the working directory.
+func NewFSStore(root string) (*FSStore, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("cover: invalid root %q: %w", root, err)
+	}
+
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("cover: failed to create root %q: %w", abs, err)
+	}
+
+	return &FSStore{root: abs}, nil
+}
+
+// Put writes the file to a temporary file first and renames it, so readers
+// never see a partially written file.
+func (s *FSStore) Put(key string, r io.Reader) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get opens the file.
+func (s *FSStore) Get(key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	return f, err
+}
+
+// Delete removes the file.
+func (s *FSStore) Delete(key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotExist
+	}
+	return err
+}
+
+// Stat returns the size and modification time of the file.
+func (s *FSStore) Stat(key string) (FileInfo, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return FileInfo{}, ErrNotExist
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	return FileInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Size returns the total size of all files below the root.
+func (s *FSStore) Size() (int64, error) {
+	files, err := s.List("")
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total, nil
+}
+
+// List returns all files whose key starts with prefix.
+func (s *FSStore) List(prefix string) ([]FileInfo, error) {
+	files := []FileInfo{}
+
+	err := filepath.WalkDir(s.root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, FileInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+
+	return files, err
+}
+
+// path maps the key to a path below the root and rejects keys leaving it.
+func (s *FSStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if key == "" || clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("cover: invalid key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,79 @@
+package currency
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// DefaultLocale is used to format prices if the visitor sends no locale.
+var DefaultLocale = language.German
+
+// suffixLanguages lists the languages that put the currency symbol after the amount.
+var suffixLanguages = map[string]bool{
+	"bg": true, "cs": true, "da": true, "de": true, "el": true, "es": true,
+	"et": true, "fi": true, "fr": true, "hr": true, "hu": true, "it": true,
+	"lt": true, "lv": true, "nb": true, "pl": true, "pt": true, "ro": true,
+	"ru": true, "sk": true, "sl": true, "sv": true,
+}
+
+// prefixRegions lists the regions that put the symbol in front of the amount
+// although their language usually doesn't.
+var prefixRegions = map[string]bool{
+	"AT": true,
+	"CH": true,
+	"LI": true,
+}
+
+// Currency describes an ISO 4217 currency.
+type Currency struct {
+	Code       string `json:"code"`
+	MinorUnits int    `json:"minorUnits"`
+}
+
+// Parse returns the currency for the ISO 4217 code.
+func Parse(code string) (Currency, error) {
+	unit, err := currency.ParseISO(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		return Currency{}, fmt.Errorf("invalid currency %q", code)
+	}
+
+	scale, _ := currency.Standard.Rounding(unit)
+
+	return Currency{Code: unit.String(), MinorUnits: scale}, nil
+}
+
+// Format formats an amount in minor units of the currency for the locale,
+// e.g. "12,50 €" for German or "€12.50" for English.
+func (c Currency) Format(amount int64, locale language.Tag) string {
+	unit := currency.MustParseISO(c.Code)
+	p := message.NewPrinter(locale)
+
+	symbol := p.Sprint(currency.NarrowSymbol(unit))
+	value := p.Sprint(number.Decimal(float64(amount)/math.Pow10(c.MinorUnits), number.Scale(c.MinorUnits)))
+
+	base, _ := locale.Base()
+	region, _ := locale.Region()
+	if suffixLanguages[base.String()] && !prefixRegions[region.String()] {
+		return value + " " + symbol
+	}
+	if len(symbol) > 1 && symbol == c.Code {
+		return symbol + " " + value
+	}
+	return symbol + value
+}
+
+// ParseLocale returns the preferred locale of a locale string or an
+// Accept-Language header. DefaultLocale is returned if nothing matches.
+func ParseLocale(s string) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(s)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	return tags[0]
+}
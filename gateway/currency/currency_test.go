@@ -0,0 +1,121 @@
+package currency
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		code       string
+		expected   string
+		minorUnits int
+		valid      bool
+	}{
+		{"EUR", "EUR", 2, true},
+		{"chf", "CHF", 2, true},
+		{"JPY", "JPY", 0, true},
+		{"XYZ", "", 0, false},
+		{"", "", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.code, func(t *testing.T) {
+			c, err := Parse(tc.code)
+			if !tc.valid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, c.Code)
+			assert.Equal(t, tc.minorUnits, c.MinorUnits)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		code     string
+		locale   string
+		amount   int64
+		expected string
+	}{
+		{"EUR", "de-DE", 123450, "1.234,50 €"},
+		{"EUR", "en-US", 123450, "€1,234.50"},
+		{"USD", "en-US", 300, "$3.00"},
+		{"CHF", "de-CH", 1230, "CHF 12.30"},
+		{"CZK", "cs", 9900, "99,00 Kč"},
+		{"JPY", "en", 1235, "¥1,235"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.code+" "+tc.locale, func(t *testing.T) {
+			c, err := Parse(tc.code)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, c.Format(tc.amount, language.Make(tc.locale)))
+		})
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	assert.Equal(t, language.Make("de-CH"), ParseLocale("de-CH,de;q=0.9,en;q=0.8"))
+	assert.Equal(t, language.Make("en"), ParseLocale("en"))
+	assert.Equal(t, DefaultLocale, ParseLocale(""))
+	assert.Equal(t, DefaultLocale, ParseLocale("%%%"))
+}
+
+func TestRates(t *testing.T) {
+	eur, _ := Parse("EUR")
+	chf, _ := Parse("CHF")
+	pln, _ := Parse("PLN")
+	jpy, _ := Parse("JPY")
+	usd, _ := Parse("USD")
+
+	rates := NewRates()
+	rates.Set("EUR", "CHF", 0.94)
+	rates.Set("EUR", "PLN", 4.3)
+	rates.Set("EUR", "JPY", 162.987)
+	rates.Set("USD", "CHF", 0.8)
+	rates.Set("USD", "PLN", 5)
+
+	testCases := []struct {
+		name     string
+		amount   int64
+		from     Currency
+		to       Currency
+		expected int64
+		ok       bool
+	}{
+		{"same", 250, eur, eur, 250, true},
+		{"direct", 1000, eur, chf, 940, true},
+		{"inverse", 940, chf, eur, 1000, true},
+		{"cross", 940, chf, pln, 4300, true},
+		{"minor units", 1000, eur, jpy, 1630, true},
+		{"cross over pivot", 800, chf, pln, 3660, true},
+		{"missing", 100, eur, usd, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, ok := rates.Convert(tc.amount, tc.from, tc.to)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestReadRatesFile(t *testing.T) {
+	file, err := ReadRatesFile(strings.NewReader(`{"base": "eur", "date": "2026-10-01", "rates": {"chf": 0.94, "EUR": 1}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", file.Base)
+	assert.Equal(t, map[string]float64{"CHF": 0.94}, file.Rates)
+
+	_, err = ReadRatesFile(strings.NewReader(`{"base": "EUR", "rates": {"XYZ": 1}}`))
+	assert.Error(t, err)
+
+	_, err = ReadRatesFile(strings.NewReader(`{"base": "EUR", "rates": {"CHF": 0}}`))
+	assert.Error(t, err)
+}
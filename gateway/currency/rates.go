@@ -0,0 +1,108 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Pivot is the currency cross rates are calculated over. Rates are usually
+// quoted in it, e.g. by the ECB.
+const Pivot = "EUR"
+
+// Rates holds exchange rates and converts amounts between currencies.
+type Rates struct {
+	rates map[[2]string]float64
+}
+
+// RatesFile is the format of a local exchange rates file, e.g.
+// {"base": "EUR", "rates": {"CHF": 0.94, "PLN": 4.31}}.
+type RatesFile struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date,omitempty"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// NewRates creates an empty set of exchange rates.
+func NewRates() *Rates {
+	return &Rates{rates: map[[2]string]float64{}}
+}
+
+// Set stores the rate for one unit of base in quote.
+func (r *Rates) Set(base, quote string, rate float64) {
+	r.rates[[2]string{base, quote}] = rate
+}
+
+// Rate returns the exchange rate from one currency to another. Inverse rates
+// and cross rates over the Pivot currency are used if there is no direct
+// rate.
+func (r *Rates) Rate(from, to string) (float64, bool) {
+	if from == to {
+		return 1, true
+	}
+
+	if rate, ok := r.direct(from, to); ok {
+		return rate, true
+	}
+
+	a, okA := r.direct(from, Pivot)
+	b, okB := r.direct(Pivot, to)
+	if okA && okB {
+		return a * b, true
+	}
+
+	return 0, false
+}
+
+// Convert converts an amount in minor units of one currency into minor units
+// of another.
+func (r *Rates) Convert(amount int64, from, to Currency) (int64, bool) {
+	rate, ok := r.Rate(from.Code, to.Code)
+	if !ok {
+		return 0, false
+	}
+	return int64(math.Round(float64(amount) * rate * math.Pow10(to.MinorUnits-from.MinorUnits))), true
+}
+
+func (r *Rates) direct(from, to string) (float64, bool) {
+	if rate, ok := r.rates[[2]string{from, to}]; ok && rate > 0 {
+		return rate, true
+	}
+	if rate, ok := r.rates[[2]string{to, from}]; ok && rate > 0 {
+		return 1 / rate, true
+	}
+	return 0, false
+}
+
+// ReadRatesFile reads and validates an exchange rates file.
+func ReadRatesFile(reader io.Reader) (RatesFile, error) {
+	var file RatesFile
+	if err := json.NewDecoder(reader).Decode(&file); err != nil {
+		return RatesFile{}, fmt.Errorf("failed to decode rates file: %w", err)
+	}
+
+	base, err := Parse(file.Base)
+	if err != nil {
+		return RatesFile{}, err
+	}
+	file.Base = base.Code
+
+	rates := make(map[string]float64, len(file.Rates))
+	for code, rate := range file.Rates {
+		quote, err := Parse(code)
+		if err != nil {
+			return RatesFile{}, err
+		}
+		if rate <= 0 {
+			return RatesFile{}, fmt.Errorf("invalid rate for %s", quote.Code)
+		}
+		if quote.Code == base.Code {
+			continue
+		}
+		rates[quote.Code] = rate
+	}
+	file.Rates = rates
+
+	return file, nil
+}
@@ -0,0 +1,16 @@
+package export
+
+// Table is a simple tabular document that can be written as CSV or PDF.
+type Table struct {
+	Title  string
+	Lines  []string
+	Header []string
+	Rows   [][]string
+}
+
+const (
+	// ContentTypeCSV is the content type of CSV documents.
+	ContentTypeCSV = "text/csv; charset=utf-8"
+	// ContentTypePDF is the content type of PDF documents.
+	ContentTypePDF = "application/pdf"
+)
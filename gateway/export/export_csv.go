@@ -0,0 +1,24 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes the header and rows of the table as CSV.
+// Title and lines are omitted to keep the file importable.
+func WriteCSV(w io.Writer, t Table) error {
+	writer := csv.NewWriter(w)
+
+	if len(t.Header) > 0 {
+		if err := writer.Write(t.Header); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.WriteAll(t.Rows); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 40.0
+	fontSize   = 9.0
+	titleSize  = 14.0
+	lineHeight = 12.0
+	// charWidth is the average glyph width of Helvetica relative to the font size.
+	charWidth = 0.5
+)
+
+// WritePDF writes the table as a plain A4 PDF document using the built-in
+// Helvetica font. Long tables are split into several pages.
+func WritePDF(w io.Writer, t Table) error {
+	pages := layoutPages(t)
+
+	var buf bytes.Buffer
+	var offsets []int
+
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+	}
+
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 5+i*2))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// layoutPages renders the content streams of all pages.
+func layoutPages(t Table) []string {
+	var pages []string
+	var page strings.Builder
+	y := pageHeight - margin
+
+	text := func(x float64, size float64, s string) {
+		fmt.Fprintf(&page, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, x, y, escape(s))
+	}
+
+	newPage := func() {
+		pages = append(pages, page.String())
+		page.Reset()
+		y = pageHeight - margin
+	}
+
+	if t.Title != "" {
+		text(margin, titleSize, t.Title)
+		y -= lineHeight * 2
+	}
+
+	for _, line := range t.Lines {
+		text(margin, fontSize, line)
+		y -= lineHeight
+	}
+	if len(t.Lines) > 0 {
+		y -= lineHeight
+	}
+
+	columns := len(t.Header)
+	for _, row := range t.Rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return append(pages, page.String())
+	}
+
+	colWidth := (pageWidth - 2*margin) / float64(columns)
+	maxChars := int(colWidth/(fontSize*charWidth)) - 1
+
+	row := func(cells []string) {
+		for i, cell := range cells {
+			text(margin+float64(i)*colWidth, fontSize, truncate(cell, maxChars))
+		}
+		y -= lineHeight
+	}
+
+	if len(t.Header) > 0 {
+		row(t.Header)
+	}
+
+	for _, cells := range t.Rows {
+		if y < margin {
+			newPage()
+			if len(t.Header) > 0 {
+				row(t.Header)
+			}
+		}
+		row(cells)
+	}
+
+	return append(pages, page.String())
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// escape encodes s for a PDF string literal in WinAnsiEncoding.
+// Characters outside of Latin-1 are replaced by a question mark.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCSV(t *testing.T) {
+	table := Table{
+		Title:  "Report",
+		Header: []string{"title", "price"},
+		Rows: [][]string{
+			{"Faust", "1.50"},
+			{"Der Prozess, Roman", "2.00"},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteCSV(&buf, table))
+	assert.Equal(t, "title,price\nFaust,1.50\n\"Der Prozess, Roman\",2.00\n", buf.String())
+}
+
+func TestWritePDF(t *testing.T) {
+	table := Table{
+		Title:  "Report (Test)",
+		Lines:  []string{"Branch: Köln"},
+		Header: []string{"title", "price"},
+	}
+	for i := range 200 {
+		table.Rows = append(table.Rows, []string{fmt.Sprintf("Book %d", i), "1.00"})
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WritePDF(&buf, table))
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	assert.Contains(t, out, `(Report \(Test\)) Tj`)
+	assert.Contains(t, out, "(Branch: K\xf6ln) Tj")
+	assert.Contains(t, out, "(Book 199) Tj")
+	assert.Contains(t, out, "/Count 4")
+}
+
+func TestTruncate(t *testing.T) {
+	testCases := []struct {
+		in       string
+		n        int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"a longer title", 8, "a lon..."},
+		{"abcdef", 2, "ab"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.expected, truncate(tc.in, tc.n))
+		})
+	}
+}
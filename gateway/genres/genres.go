@@ -0,0 +1,86 @@
+package genres
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+)
+
+// Tree arranges the genres by their parents. Genres whose parent is missing
+// are roots. The books of a genre are taken from counts and include the
+// books of its subgenres. Siblings are sorted by name.
+func Tree(genres []models.Genre, counts map[uint]int64) []*models.GenreNode {
+	nodes := make(map[uint]*models.GenreNode, len(genres))
+	for _, g := range genres {
+		nodes[g.ID] = &models.GenreNode{ID: g.ID, Name: g.Name, ParentID: g.ParentID, Children: []*models.GenreNode{}}
+	}
+
+	roots := []*models.GenreNode{}
+	for _, g := range genres {
+		node := nodes[g.ID]
+		if parent, ok := parentOf(nodes, g); ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	var count func(n *models.GenreNode) int64
+	count = func(n *models.GenreNode) int64 {
+		slices.SortFunc(n.Children, byName)
+		n.Books = counts[n.ID]
+		for _, c := range n.Children {
+			n.Books += count(c)
+		}
+		return n.Books
+	}
+	slices.SortFunc(roots, byName)
+	for _, r := range roots {
+		count(r)
+	}
+
+	return roots
+}
+
+// Descendants returns the ID of the genre and the IDs of all its subgenres.
+func Descendants(genres []models.Genre, id uint) []uint {
+	children := map[uint][]uint{}
+	for _, g := range genres {
+		if g.ParentID != nil {
+			children[*g.ParentID] = append(children[*g.ParentID], g.ID)
+		}
+	}
+
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !slices.Contains(ids, child) {
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// CanMove tells whether the genre can be moved below the parent without
+// creating a cycle. A nil parent moves it to the top.
+func CanMove(genres []models.Genre, id uint, parent *uint) bool {
+	if parent == nil {
+		return true
+	}
+	return !slices.Contains(Descendants(genres, id), *parent)
+}
+
+// parentOf returns the node of the parent of the genre, if it exists.
+func parentOf(nodes map[uint]*models.GenreNode, g models.Genre) (*models.GenreNode, bool) {
+	if g.ParentID == nil || *g.ParentID == g.ID {
+		return nil, false
+	}
+	parent, ok := nodes[*g.ParentID]
+	return parent, ok
+}
+
+func byName(a, b *models.GenreNode) int {
+	return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+}
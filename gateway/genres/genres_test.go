@@ -0,0 +1,90 @@
+package genres
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func parent(id uint) *uint {
+	return &id
+}
+
+var genres = []models.Genre{
+	{ID: 1, Name: "Fiction"},
+	{ID: 2, Name: "Crime", ParentID: parent(1)},
+	{ID: 3, Name: "Scandinavian", ParentID: parent(2)},
+	{ID: 4, Name: "Classics", ParentID: parent(1)},
+	{ID: 5, Name: "Cookbooks"},
+	{ID: 6, Name: "Orphan", ParentID: parent(99)},
+}
+
+func TestTree(t *testing.T) {
+	tree := Tree(genres, map[uint]int64{1: 1, 2: 2, 3: 4, 5: 8})
+
+	assert.Len(t, tree, 3)
+	assert.Equal(t, []string{"Cookbooks", "Fiction", "Orphan"}, []string{tree[0].Name, tree[1].Name, tree[2].Name})
+
+	fiction := tree[1]
+	assert.Equal(t, int64(7), fiction.Books)
+	assert.Len(t, fiction.Children, 2)
+	assert.Equal(t, "Classics", fiction.Children[0].Name)
+	assert.Equal(t, int64(0), fiction.Children[0].Books)
+	assert.Equal(t, "Crime", fiction.Children[1].Name)
+	assert.Equal(t, int64(6), fiction.Children[1].Books)
+	assert.Equal(t, "Scandinavian", fiction.Children[1].Children[0].Name)
+	assert.NotNil(t, fiction.Children[1].Children[0].Children)
+
+	assert.Equal(t, int64(8), tree[0].Books)
+	assert.Equal(t, int64(0), tree[2].Books)
+}
+
+func TestTreeEmpty(t *testing.T) {
+	assert.Empty(t, Tree(nil, nil))
+	assert.NotNil(t, Tree(nil, nil))
+}
+
+func TestDescendants(t *testing.T) {
+	testCases := []struct {
+		id  uint
+		ids []uint
+	}{
+		{1, []uint{1, 2, 4, 3}},
+		{2, []uint{2, 3}},
+		{3, []uint{3}},
+		{5, []uint{5}},
+		{42, []uint{42}},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.ids, Descendants(genres, tc.id), tc.id)
+	}
+}
+
+func TestDescendantsCycle(t *testing.T) {
+	cycle := []models.Genre{
+		{ID: 1, Name: "A", ParentID: parent(2)},
+		{ID: 2, Name: "B", ParentID: parent(1)},
+	}
+	assert.Equal(t, []uint{1, 2}, Descendants(cycle, 1))
+}
+
+func TestCanMove(t *testing.T) {
+	testCases := []struct {
+		id     uint
+		parent *uint
+		want   bool
+	}{
+		{2, nil, true},
+		{2, parent(5), true},
+		{3, parent(4), true},
+		{2, parent(2), false},
+		{1, parent(3), false},
+		{2, parent(3), false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, CanMove(genres, tc.id, tc.parent), tc.id)
+	}
+}
@@ -0,0 +1,136 @@
+package names
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/abaldeweg/warehouse-server/gateway/search"
+)
+
+// Name is a parsed name.
+type Name struct {
+	Firstname string
+	Surname   string
+}
+
+// Parse splits a name into firstname and surname. "Goethe, Johann Wolfgang"
+// is read as surname and firstname, otherwise the last word is the surname.
+// A single word is the surname.
+func Parse(name string) (firstname, surname string) {
+	if before, after, ok := strings.Cut(name, ","); ok {
+		return normalize(after), normalize(before)
+	}
+
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return "", ""
+	}
+	return strings.Join(words[:len(words)-1], " "), words[len(words)-1]
+}
+
+// ParseList splits a list of names separated by semicolons, e.g.
+// "Goethe, Johann Wolfgang; Friedrich Schiller". Empty names are skipped.
+func ParseList(list string) []Name {
+	parsed := []Name{}
+	for _, name := range strings.Split(list, ";") {
+		firstname, surname := Parse(name)
+		if firstname == "" && surname == "" {
+			continue
+		}
+		parsed = append(parsed, Name{Firstname: firstname, Surname: surname})
+	}
+	return parsed
+}
+
+// normalize trims the name and collapses whitespace.
+func normalize(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Key returns the words of the name, folded and sorted, so names written
+// in a different order, case or with other diacritics have the same key.
+func Key(firstname, surname string) string {
+	words := search.Tokenize(firstname + " " + surname)
+	slices.Sort(words)
+	return strings.Join(words, " ")
+}
+
+// AuthorKey returns the key of the name of the author.
+func AuthorKey(a models.Author) string {
+	return Key(a.Firstname, a.Surname)
+}
+
+// Duplicates groups the authors with the same key or keys differing by a
+// few edits, one per six letters of the shorter key and at most
+// maxDistance. Groups are sorted by the first author and the authors of a
+// group by ID.
+func Duplicates(authors []models.Author, maxDistance int) [][]models.Author {
+	keys := make([]string, len(authors))
+	lengths := make([]int, len(authors))
+	for i, a := range authors {
+		keys[i] = AuthorKey(a)
+		lengths[i] = utf8.RuneCountInString(keys[i])
+	}
+
+	parent := make([]int, len(authors))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	order := make([]int, len(authors))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return lengths[a] - lengths[b]
+	})
+
+	for x, i := range order {
+		if keys[i] == "" {
+			continue
+		}
+		limit := min(maxDistance, lengths[i]/6)
+		for _, j := range order[x+1:] {
+			if lengths[j]-lengths[i] > limit {
+				break
+			}
+			if root(i) == root(j) {
+				continue
+			}
+			if keys[i] == keys[j] || search.Distance(keys[i], keys[j], limit) <= limit {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+
+	groups := map[int][]models.Author{}
+	for i, a := range authors {
+		groups[root(i)] = append(groups[root(i)], a)
+	}
+
+	duplicates := [][]models.Author{}
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+		slices.SortFunc(g, func(a, b models.Author) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+		duplicates = append(duplicates, g)
+	}
+	slices.SortFunc(duplicates, func(a, b []models.Author) int {
+		return cmp.Compare(a[0].ID, b[0].ID)
+	})
+
+	return duplicates
+}
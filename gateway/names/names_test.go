@@ -0,0 +1,67 @@
+package names
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name      string
+		firstname string
+		surname   string
+	}{
+		{"Goethe, Johann Wolfgang", "Johann Wolfgang", "Goethe"},
+		{"  Johann   Wolfgang Goethe ", "Johann Wolfgang", "Goethe"},
+		{"Homer", "", "Homer"},
+		{"Kafka,Franz", "Franz", "Kafka"},
+		{"", "", ""},
+	}
+
+	for _, tc := range testCases {
+		firstname, surname := Parse(tc.name)
+		assert.Equal(t, tc.firstname, firstname, tc.name)
+		assert.Equal(t, tc.surname, surname, tc.name)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	assert.Equal(t, []Name{{"Johann Wolfgang", "Goethe"}, {"Friedrich", "Schiller"}}, ParseList("Goethe, Johann Wolfgang; Friedrich Schiller"))
+	assert.Equal(t, []Name{{"", "Homer"}}, ParseList(" ; Homer;"))
+	assert.Empty(t, ParseList(""))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "goethe johann wolfgang", Key("Johann Wolfgang", "Goethe"))
+	assert.Equal(t, Key("Johann Wolfgang", "Goethe"), Key("Johann", "Wolfgang Goethe"))
+	assert.Equal(t, Key("Émile", "Zola"), Key("emile", "ZOLA"))
+}
+
+func TestDuplicates(t *testing.T) {
+	authors := []models.Author{
+		{ID: 1, Firstname: "Johann Wolfgang", Surname: "Goethe"},
+		{ID: 2, Firstname: "Franz", Surname: "Kafka"},
+		{ID: 3, Firstname: "Johann", Surname: "Wolfgang Goethe"},
+		{ID: 4, Firstname: "Johann Wolfgang", Surname: "Göthe"},
+		{ID: 5, Firstname: "Emile", Surname: "Zola"},
+		{ID: 6, Firstname: "Émile", Surname: "Zola"},
+		{ID: 7, Firstname: "Li", Surname: "Wu"},
+		{ID: 8, Firstname: "Li", Surname: "Xu"},
+		{ID: 9, Firstname: "Thomas", Surname: "Mann"},
+		{ID: 10, Firstname: "Heinrich", Surname: "Mann"},
+	}
+
+	duplicates := Duplicates(authors, 2)
+
+	ids := [][]uint64{}
+	for _, g := range duplicates {
+		group := []uint64{}
+		for _, a := range g {
+			group = append(group, a.ID)
+		}
+		ids = append(ids, group)
+	}
+	assert.Equal(t, [][]uint64{{1, 3, 4}, {5, 6}}, ids)
+}
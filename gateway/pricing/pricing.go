@@ -0,0 +1,64 @@
+package pricing
+
+import (
+	"sort"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+)
+
+// Suggestion is the price calculated for a book from a price list.
+type Suggestion struct {
+	Price      models.Money `json:"price"`
+	BasePrice  models.Money `json:"basePrice"`
+	Percentage float64      `json:"percentage"`
+	Amount     models.Money `json:"amount"`
+	Rules      []uint       `json:"rules"`
+}
+
+// Suggest calculates the price of the book from the rules.
+// The base price is taken from the most specific matching rule that has one,
+// ties are resolved by position. Percentages and amounts of all matching
+// rules are summed up and applied to the base price. The result is rounded
+// to the given steps. It returns false if no matching rule has a base price.
+func Suggest(book *models.Book, rules []models.PriceRule, steps models.Money) (Suggestion, bool) {
+	matching := make([]models.PriceRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Matches(book) {
+			matching = append(matching, r)
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].Position != matching[j].Position {
+			return matching[i].Position < matching[j].Position
+		}
+		return matching[i].ID < matching[j].ID
+	})
+
+	var base *models.PriceRule
+	for i, r := range matching {
+		if r.BasePrice == nil {
+			continue
+		}
+		if base == nil || r.Specificity() > base.Specificity() {
+			base = &matching[i]
+		}
+	}
+
+	if base == nil {
+		return Suggestion{}, false
+	}
+
+	s := Suggestion{BasePrice: *base.BasePrice, Rules: []uint{}}
+	for _, r := range matching {
+		s.Percentage += r.Percentage
+		s.Amount = s.Amount.Add(r.Amount)
+		s.Rules = append(s.Rules, r.ID)
+	}
+
+	s.Price = s.BasePrice.AddPercent(s.Percentage).Add(s.Amount)
+	s.Price.Amount = max(s.Price.Amount, 0)
+	s.Price = s.Price.RoundToSteps(steps)
+
+	return s, true
+}
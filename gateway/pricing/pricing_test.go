@@ -0,0 +1,73 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func eur(amount int64) models.Money {
+	return models.NewMoney(amount, "EUR")
+}
+
+func TestSuggest(t *testing.T) {
+	rules := []models.PriceRule{
+		{ID: 1, Name: "default", BasePrice: ptr(eur(200))},
+		{ID: 2, Name: "hardcover", FormatID: ptr(uint(2)), BasePrice: ptr(eur(400))},
+		{ID: 3, Name: "bad condition", ConditionID: ptr(uint(3)), Percentage: -50},
+		{ID: 4, Name: "old", YearTo: ptr(1950), Amount: eur(150)},
+		{ID: 5, Name: "crime hardcover", FormatID: ptr(uint(2)), GenreID: ptr(uint(7)), BasePrice: ptr(eur(500)), Position: 1},
+	}
+
+	testCases := []struct {
+		name     string
+		book     models.Book
+		price    models.Money
+		base     models.Money
+		matching []uint
+	}{
+		{"default", models.Book{FormatID: 1, ReleaseYear: 2000}, eur(200), eur(200), []uint{1}},
+		{"hardcover", models.Book{FormatID: 2, ReleaseYear: 2000}, eur(400), eur(400), []uint{1, 2}},
+		{"bad condition", models.Book{FormatID: 2, ConditionID: ptr(uint(3)), ReleaseYear: 2000}, eur(200), eur(400), []uint{1, 2, 3}},
+		{"old", models.Book{FormatID: 1, ReleaseYear: 1900}, eur(350), eur(200), []uint{1, 4}},
+		{"most specific", models.Book{FormatID: 2, GenreID: ptr(uint(7)), ReleaseYear: 2000}, eur(500), eur(500), []uint{1, 2, 5}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, ok := Suggest(&tc.book, rules, eur(50))
+			assert.True(t, ok)
+			assert.Equal(t, tc.price, s.Price)
+			assert.Equal(t, tc.base, s.BasePrice)
+			assert.Equal(t, tc.matching, s.Rules)
+		})
+	}
+}
+
+func TestSuggestWithoutBasePrice(t *testing.T) {
+	rules := []models.PriceRule{
+		{ID: 1, Name: "discount", Percentage: -10},
+	}
+
+	_, ok := Suggest(&models.Book{FormatID: 1}, rules, eur(0))
+	assert.False(t, ok)
+}
+
+func TestSuggestRoundsToSteps(t *testing.T) {
+	rules := []models.PriceRule{
+		{ID: 1, Name: "default", BasePrice: ptr(eur(333)), Percentage: 10},
+	}
+
+	s, ok := Suggest(&models.Book{FormatID: 1}, rules, eur(50))
+	assert.True(t, ok)
+	assert.Equal(t, eur(350), s.Price)
+
+	s, ok = Suggest(&models.Book{FormatID: 1}, rules, eur(0))
+	assert.True(t, ok)
+	assert.Equal(t, eur(366), s.Price)
+}
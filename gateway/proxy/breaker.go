@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// States of a circuit breaker.
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// ErrOpen is returned while the circuit of an upstream is open.
+var ErrOpen = errors.New("proxy: circuit open")
+
+// Breaker stops requests to an upstream after Threshold consecutive
+// failures. After Cooldown a single probe request is let through, its
+// result closes the circuit again or keeps it open for another Cooldown.
+type Breaker struct {
+	Name      string
+	Threshold int
+	Cooldown  time.Duration
+
+	mu          sync.Mutex
+	state       string
+	failures    int
+	probing     bool
+	openedAt    time.Time
+	lastError   string
+	lastFailure time.Time
+	lastSuccess time.Time
+	now         func() time.Time
+}
+
+// Health describes the state of an upstream.
+type Health struct {
+	Name        string     `json:"name"`
+	State       string     `json:"state"`
+	Failures    int        `json:"failures"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	RetryAt     *time.Time `json:"retry_at,omitempty"`
+}
+
+// NewBreaker creates a closed circuit breaker.
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Name: name, Threshold: max(threshold, 1), Cooldown: cooldown, state: StateClosed, now: time.Now}
+}
+
+// Allow reports whether a request may be sent. In the half-open state only
+// one probe is allowed at a time.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.Cooldown {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	}
+
+	return nil
+}
+
+// Success records a successful request and closes the circuit.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+	b.lastSuccess = b.now()
+}
+
+// Failure records a failed request. The circuit opens if the threshold is
+// reached or the probe of a half-open circuit failed.
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	b.lastFailure = b.now()
+	if err != nil {
+		b.lastError = err.Error()
+	}
+
+	if b.state == StateHalfOpen || b.failures >= b.Threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Cancel releases the probe of a half-open circuit without a result, e.g.
+// if the client went away.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// RetryAt returns when the next probe is allowed.
+func (b *Breaker) RetryAt() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.openedAt.Add(b.Cooldown)
+}
+
+// Health returns the current state of the upstream.
+func (b *Breaker) Health() Health {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := Health{Name: b.Name, State: b.state, Failures: b.failures, LastError: b.lastError}
+	if !b.lastFailure.IsZero() {
+		t := b.lastFailure
+		h.LastFailure = &t
+	}
+	if !b.lastSuccess.IsZero() {
+		t := b.lastSuccess
+		h.LastSuccess = &t
+	}
+	if b.state != StateClosed {
+		t := b.openedAt.Add(b.Cooldown)
+		h.RetryAt = &t
+	}
+	return h
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*Breaker{}
+)
+
+// breakerFor returns the breaker of the upstream, one per scheme and host.
+func breakerFor(name string, cfg Config) *Breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[name]
+	if !ok {
+		b = NewBreaker(name, cfg.BreakerThreshold, cfg.BreakerCooldown)
+		breakers[name] = b
+	}
+	return b
+}
+
+// Register adds the upstream to the list of Upstreams before the first
+// request is sent to it.
+func Register(serviceURL string) error {
+	u, err := url.Parse(serviceURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("proxy: invalid service url %q", serviceURL)
+	}
+
+	cfg, _ := shared()
+	breakerFor(u.Scheme+"://"+u.Host, cfg)
+	return nil
+}
+
+// Upstreams returns the health of all registered or used upstreams.
+func Upstreams() []Health {
+	breakersMu.Lock()
+	list := make([]*Breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	health := make([]Health, 0, len(list))
+	for _, b := range list {
+		health = append(health, b.Health())
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Name < health[j].Name })
+
+	return health
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker("core", 2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Allow())
+	b.Failure(errors.New("refused"))
+	assert.NoError(t, b.Allow())
+	b.Failure(errors.New("refused"))
+
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+	assert.Equal(t, StateOpen, b.Health().State)
+	assert.Equal(t, "refused", b.Health().LastError)
+
+	// one probe after the cooldown
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+	assert.Equal(t, StateHalfOpen, b.Health().State)
+
+	// a failed probe opens the circuit again
+	b.Failure(errors.New("refused"))
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Cancel()
+	assert.NoError(t, b.Allow())
+	b.Success()
+
+	assert.Equal(t, StateClosed, b.Health().State)
+	assert.Equal(t, 0, b.Health().Failures)
+	assert.NoError(t, b.Allow())
+}
@@ -2,70 +2,402 @@ package proxy
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 )
 
+// Config holds the timeouts, retry and circuit breaker settings of the proxy.
+type Config struct {
+	Timeout               time.Duration
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConnsPerHost   int
+	Retries               int
+	RetryBackoff          time.Duration
+	BreakerThreshold      int
+	BreakerCooldown       time.Duration
+}
+
+// NewConfig reads the proxy settings from PROXY_TIMEOUT, PROXY_DIAL_TIMEOUT,
+// PROXY_RESPONSE_HEADER_TIMEOUT, PROXY_MAX_IDLE_CONNS, PROXY_RETRIES,
+// PROXY_BREAKER_THRESHOLD and PROXY_BREAKER_COOLDOWN.
+func NewConfig() Config {
+	viper.SetDefault("PROXY_TIMEOUT", "20s")
+	viper.SetDefault("PROXY_DIAL_TIMEOUT", "5s")
+	viper.SetDefault("PROXY_RESPONSE_HEADER_TIMEOUT", "15s")
+	viper.SetDefault("PROXY_MAX_IDLE_CONNS", 32)
+	viper.SetDefault("PROXY_RETRIES", 2)
+	viper.SetDefault("PROXY_BREAKER_THRESHOLD", 5)
+	viper.SetDefault("PROXY_BREAKER_COOLDOWN", "30s")
+
+	return Config{
+		Timeout:               viper.GetDuration("PROXY_TIMEOUT"),
+		DialTimeout:           viper.GetDuration("PROXY_DIAL_TIMEOUT"),
+		ResponseHeaderTimeout: viper.GetDuration("PROXY_RESPONSE_HEADER_TIMEOUT"),
+		MaxIdleConnsPerHost:   viper.GetInt("PROXY_MAX_IDLE_CONNS"),
+		Retries:               viper.GetInt("PROXY_RETRIES"),
+		RetryBackoff:          100 * time.Millisecond,
+		BreakerThreshold:      viper.GetInt("PROXY_BREAKER_THRESHOLD"),
+		BreakerCooldown:       viper.GetDuration("PROXY_BREAKER_COOLDOWN"),
+	}
+}
+
+var (
+	mu     sync.Mutex
+	config *Config
+	client *http.Client
+)
+
+// shared returns the configuration and the client with the pooled
+// transport that is used for all upstream requests.
+func shared() (Config, *http.Client) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if client == nil {
+		cfg := NewConfig()
+		config = &cfg
+		client = &http.Client{
+			Transport: newTransport(cfg),
+			// redirects are passed to the client
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return *config, client
+}
+
+// Reset discards the shared client and the circuit breakers, the next
+// request reads the configuration again.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if client != nil {
+		client.CloseIdleConnections()
+	}
+	client = nil
+	config = nil
+
+	breakersMu.Lock()
+	breakers = map[string]*Breaker{}
+	breakersMu.Unlock()
+}
+
+func newTransport(cfg Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.MaxIdleConnsPerHost * 4,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// hopHeaders are meaningful only for a single connection and are not
+// forwarded, see RFC 9110 section 7.6.1.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Proxy forwards the request to path of the service and streams the
+// response back to the client. Idempotent requests without a body are
+// retried if the service can't be reached or answers with 502, 503 or 504.
+// While the circuit of the service is open, requests fail immediately with
+// 503. If an error is returned, the error response has already been written.
 func Proxy(c *gin.Context, serviceURL string, path string) error {
-	const duration = 20 * time.Second
+	cfg, client := shared()
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), duration)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
 	defer cancel()
 
-	req, err := request(ctx, c, serviceURL, path)
+	target, err := targetURL(serviceURL, path, c.Request.URL.RawQuery)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		return err
+	}
+
+	breaker := breakerFor(target.Scheme+"://"+target.Host, cfg)
+	if err := breaker.Allow(); err != nil {
+		retryAt := breaker.RetryAt()
+		c.Header("Retry-After", strconv.Itoa(max(int(time.Until(retryAt).Seconds()+1), 1)))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":    "Service temporarily unavailable",
+			"upstream": breaker.Name,
+			"retry_at": retryAt.Unix(),
+		})
 		return err
 	}
 
-	return response(req, c)
+	resp, err := send(ctx, c, client, target, cfg)
+	switch {
+	case err != nil && c.Request.Context().Err() != nil:
+		// the client went away, this says nothing about the upstream
+		breaker.Cancel()
+	case err != nil:
+		breaker.Failure(err)
+	case isUnavailable(resp.StatusCode):
+		breaker.Failure(fmt.Errorf("upstream responded with %d", resp.StatusCode))
+	default:
+		breaker.Success()
+	}
+
+	if err != nil {
+		return writeError(c, ctx, err)
+	}
+	defer resp.Body.Close()
+
+	return response(c, resp)
 }
 
-func request(ctx context.Context, c *gin.Context, serviceURL string, path string) (*http.Request, error) {
-	fullURL := serviceURL + path + "?" + c.Request.URL.RawQuery
+// Do sends a request of the gateway itself, e.g. to the auth service, with
+// the shared transport and the circuit breaker of the upstream. The request
+// times out after PROXY_TIMEOUT, including reading the body, which the
+// caller must close. While the circuit is open, ErrOpen is returned.
+func Do(req *http.Request) (*http.Response, error) {
+	cfg, client := shared()
+
+	if req.URL.Scheme == "" || req.URL.Host == "" {
+		return nil, fmt.Errorf("proxy: invalid service url %q", req.URL)
+	}
+
+	breaker := breakerFor(req.URL.Scheme+"://"+req.URL.Host, cfg)
+	if err := breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), cfg.Timeout)
+	resp, err := client.Do(req.WithContext(ctx))
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		breaker.Cancel()
+	case err != nil:
+		breaker.Failure(err)
+	case isUnavailable(resp.StatusCode):
+		breaker.Failure(fmt.Errorf("upstream responded with %d", resp.StatusCode))
+	default:
+		breaker.Success()
+	}
 
-	req, err := http.NewRequestWithContext(ctx, c.Request.Method, fullURL, c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+		cancel()
 		return nil, err
 	}
+	resp.Body = cancelBody{resp.Body, cancel}
+
+	return resp, nil
+}
+
+// cancelBody releases the context of a request once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// send sends the request and retries it if possible.
+func send(ctx context.Context, c *gin.Context, client *http.Client, target *url.URL, cfg Config) (*http.Response, error) {
+	retries := 0
+	if retryable(c.Request) {
+		retries = cfg.Retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := request(ctx, c, target)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if attempt >= retries || !shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
 
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			req.Header.Add(key, value)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cfg.RetryBackoff * time.Duration(attempt+1)):
 		}
 	}
+}
 
-	return req, nil
+// targetURL joins the path to the service URL and appends the query.
+func targetURL(serviceURL, path, rawQuery string) (*url.URL, error) {
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("proxy: invalid service url %q", serviceURL)
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + strings.TrimLeft(path, "/")
+	u.RawPath = ""
+	u.RawQuery = rawQuery
+
+	return u, nil
 }
 
-func response(req *http.Request, c *gin.Context) error {
-	client := &http.Client{}
+func request(ctx context.Context, c *gin.Context, target *url.URL) (*http.Request, error) {
+	body := c.Request.Body
+	if c.Request.ContentLength == 0 {
+		body = http.NoBody
+	}
 
-	resp, err := client.Do(req)
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, target.String(), body)
 	if err != nil {
-		if err == context.DeadlineExceeded {
-			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
-		} else {
-			c.JSON(http.StatusBadGateway, gin.H{"msg": err.Error()})
-		}
+		return nil, err
+	}
+	req.ContentLength = c.Request.ContentLength
+
+	req.Header = c.Request.Header.Clone()
+	removeHopHeaders(req.Header)
+	setForwardedHeaders(req.Header, c.Request)
+
+	return req, nil
+}
+
+func response(c *gin.Context, resp *http.Response) error {
+	header := c.Writer.Header()
+	for key, values := range resp.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	removeHopHeaders(header)
+
+	c.Status(resp.StatusCode)
+	c.Writer.WriteHeaderNow()
+
+	if _, err := io.Copy(flushWriter{c.Writer}, resp.Body); err != nil {
+		// the status has been sent, the client sees a truncated body
 		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	return nil
+}
+
+// flushWriter flushes every write, so streamed responses reach the client
+// without delay.
+type flushWriter struct {
+	w gin.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.w.Flush()
+	return n, err
+}
+
+func writeError(c *gin.Context, ctx context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+	} else {
 		c.JSON(http.StatusBadGateway, gin.H{"msg": "Bad Gateway"})
-		return err
 	}
+	return err
+}
+
+// removeHopHeaders deletes the hop-by-hop headers and the headers listed
+// in the Connection header.
+func removeHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
 
-	for key, value := range resp.Header {
-		c.Header(key, value[0])
+// setForwardedHeaders adds the client address, the original host and the
+// protocol of the request.
+func setForwardedHeaders(header http.Header, r *http.Request) {
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := header.Values("X-Forwarded-For"); len(prior) > 0 {
+			ip = strings.Join(prior, ", ") + ", " + ip
+		}
+		header.Set("X-Forwarded-For", ip)
 	}
 
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	if header.Get("X-Forwarded-Host") == "" {
+		header.Set("X-Forwarded-Host", r.Host)
+	}
 
-	return nil
+	if header.Get("X-Forwarded-Proto") == "" {
+		proto := "http"
+		if r.TLS != nil {
+			proto = "https"
+		}
+		header.Set("X-Forwarded-Proto", proto)
+	}
+}
+
+// retryable reports whether the request can be sent again. Only idempotent
+// methods without a body qualify, as the body is streamed.
+func retryable(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return r.ContentLength == 0
+	}
+	return false
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+
+	return isUnavailable(resp.StatusCode)
+}
+
+// isUnavailable reports whether the status tells that the upstream or a
+// service behind it is down.
+func isUnavailable(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
 }
@@ -1,12 +1,17 @@
 package proxy
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,3 +51,209 @@ func TestProxy(t *testing.T) {
 
 	assert.Equal(t, "TEST", string(body))
 }
+
+func TestProxyHeaders(t *testing.T) {
+	mockService := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/base/api/me", r.URL.Path)
+			assert.Equal(t, "a=1&b=2", r.URL.RawQuery)
+			assert.Empty(t, r.Header.Get("X-Hop"))
+			assert.Empty(t, r.Header.Get("Keep-Alive"))
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.Equal(t, "10.0.0.1, 192.0.2.1", r.Header.Get("X-Forwarded-For"))
+			assert.Equal(t, "example.com", r.Header.Get("X-Forwarded-Host"))
+			assert.Equal(t, "http", r.Header.Get("X-Forwarded-Proto"))
+
+			w.Header().Add("Set-Cookie", "a=1")
+			w.Header().Add("Set-Cookie", "b=2")
+			w.Header().Set("Keep-Alive", "timeout=5")
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+	defer mockService.Close()
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		assert.NoError(t, Proxy(c, mockService.URL+"/base/", "/api/me"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/test?a=1&b=2", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "1")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []string{"a=1", "b=2"}, w.Header().Values("Set-Cookie"))
+	assert.Empty(t, w.Header().Get("Keep-Alive"))
+}
+
+func TestProxyRetries(t *testing.T) {
+	var calls int
+	mockService := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer mockService.Close()
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Any("/test", func(c *gin.Context) {
+		_ = Proxy(c, mockService.URL, "/test")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, calls)
+
+	// requests with a body are not retried
+	calls = 0
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestProxyTimeout(t *testing.T) {
+	viper.Set("PROXY_TIMEOUT", "50ms")
+	Reset()
+	t.Cleanup(func() {
+		viper.Set("PROXY_TIMEOUT", nil)
+		Reset()
+	})
+
+	mockService := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}),
+	)
+	defer mockService.Close()
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		assert.Error(t, Proxy(c, mockService.URL, "/test"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestProxyUnreachable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		assert.Error(t, Proxy(c, "http://127.0.0.1:1", "/test"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestProxyCircuitBreaker(t *testing.T) {
+	viper.Set("PROXY_BREAKER_THRESHOLD", 2)
+	viper.Set("PROXY_RETRIES", 0)
+	Reset()
+	t.Cleanup(func() {
+		viper.Set("PROXY_BREAKER_THRESHOLD", nil)
+		viper.Set("PROXY_RETRIES", nil)
+		Reset()
+	})
+
+	var calls int
+	mockService := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer mockService.Close()
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		_ = Proxy(c, mockService.URL, "/test")
+	})
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	}
+
+	// the third request is answered by the open circuit
+	assert.Equal(t, 2, calls)
+
+	upstreams := Upstreams()
+	assert.Len(t, upstreams, 1)
+	assert.Equal(t, StateOpen, upstreams[0].State)
+	assert.Equal(t, 2, upstreams[0].Failures)
+}
+
+func TestDo(t *testing.T) {
+	viper.Set("PROXY_TIMEOUT", "100ms")
+	viper.Set("PROXY_BREAKER_THRESHOLD", 2)
+	Reset()
+	t.Cleanup(func() {
+		viper.Set("PROXY_TIMEOUT", nil)
+		viper.Set("PROXY_BREAKER_THRESHOLD", nil)
+		Reset()
+	})
+
+	var calls atomic.Int32
+	mockService := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			if r.URL.Path == "/slow" {
+				time.Sleep(time.Second)
+			}
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer mockService.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, mockService.URL+"/me", nil)
+	resp, err := Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, mockService.URL+"/slow", nil)
+		_, err := Do(req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	// the circuit is open after the timeouts
+	req, _ = http.NewRequest(http.MethodGet, mockService.URL+"/me", nil)
+	_, err = Do(req)
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, int32(3), calls.Load())
+
+	req, _ = http.NewRequest(http.MethodGet, "/me", nil)
+	_, err = Do(req)
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package router
+
+import (
+	"github.com/abaldeweg/warehouse-server/gateway/core/controllers"
+	"github.com/abaldeweg/warehouse-server/gateway/db/mdb"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// handlers returns the native handlers that routes can refer to by name.
+func handlers(db *gorm.DB, mongoDB *mdb.MDBClient) map[string]gin.HandlerFunc {
+	return map[string]gin.HandlerFunc{
+		"author.find":       func(c *gin.Context) { controllers.NewAuthorController(db).GetAuthors(c) },
+		"author.show":       func(c *gin.Context) { controllers.NewAuthorController(db).GetAuthor(c) },
+		"author.create":     func(c *gin.Context) { controllers.NewAuthorController(db).CreateAuthor(c) },
+		"author.update":     func(c *gin.Context) { controllers.NewAuthorController(db).UpdateAuthor(c) },
+		"author.delete":     func(c *gin.Context) { controllers.NewAuthorController(db).DeleteAuthor(c) },
+		"author.duplicates": func(c *gin.Context) { controllers.NewAuthorController(db).GetDuplicates(c) },
+		"author.merge":      func(c *gin.Context) { controllers.NewAuthorController(db).MergeAuthors(c) },
+
+		"book.clean":              func(c *gin.Context) { controllers.NewBookController(db).CleanBooks(c) },
+		"book.stats":              func(c *gin.Context) { controllers.NewBookController(db).ShowStats(c) },
+		"book.covers":             func(c *gin.Context) { controllers.NewBookController(db).BatchCovers(c) },
+		"book.orphans":            func(c *gin.Context) { controllers.NewBookController(db).ShowOrphanedCovers(c) },
+		"book.orphans.delete":     func(c *gin.Context) { controllers.NewBookController(db).DeleteOrphanedCovers(c) },
+		"book.inventory.found":    func(c *gin.Context) { controllers.NewBookController(db).FindInventory(c) },
+		"book.inventory.notfound": func(c *gin.Context) { controllers.NewBookController(db).NotFoundInventory(c) },
+		"book.search":             func(c *gin.Context) { controllers.NewBookController(db).SearchBooks(c) },
+		"book.show":               func(c *gin.Context) { controllers.NewBookController(db).ShowBook(c) },
+		"book.bulk":               func(c *gin.Context) { controllers.NewBookController(db).BulkBook(c) },
+		"book.suggested_price":    func(c *gin.Context) { controllers.NewBookController(db).SuggestedPrice(c) },
+		"book.update":             func(c *gin.Context) { controllers.NewBookController(db).UpdateBook(c) },
+		"book.cover":              func(c *gin.Context) { controllers.NewBookController(db).ShowCover(c) },
+		"book.cover.upload":       func(c *gin.Context) { controllers.NewBookController(db).UploadCover(c) },
+		"book.cover.delete":       func(c *gin.Context) { controllers.NewBookController(db).DeleteCover(c) },
+		"book.images":             func(c *gin.Context) { controllers.NewBookImageController(db).List(c) },
+		"book.images.create":      func(c *gin.Context) { controllers.NewBookImageController(db).Create(c) },
+		"book.images.order":       func(c *gin.Context) { controllers.NewBookImageController(db).Reorder(c) },
+		"book.images.update":      func(c *gin.Context) { controllers.NewBookImageController(db).Update(c) },
+		"book.images.delete":      func(c *gin.Context) { controllers.NewBookImageController(db).Delete(c) },
+		"book.sell":               func(c *gin.Context) { controllers.NewBookController(db).SellBook(c) },
+		"book.remove":             func(c *gin.Context) { controllers.NewBookController(db).RemoveBook(c) },
+		"book.reserve":            func(c *gin.Context) { controllers.NewBookController(db).ReserveBook(c) },
+		"book.delete":             func(c *gin.Context) { controllers.NewBookController(db).DeleteBook(c) },
+
+		"branch.list":        func(c *gin.Context) { controllers.NewBranchController(db).List(c) },
+		"branch.memberships": func(c *gin.Context) { controllers.NewBranchController(db).Memberships(c) },
+		"branch.show":        func(c *gin.Context) { controllers.NewBranchController(db).Show(c) },
+		"branch.update":      func(c *gin.Context) { controllers.NewBranchController(db).Update(c) },
+		"branch.create":      func(c *gin.Context) { controllers.NewBranchController(db).Create(c) },
+		"branch.archive":     func(c *gin.Context) { controllers.NewBranchController(db).Archive(c) },
+		"branch.restore":     func(c *gin.Context) { controllers.NewBranchController(db).Restore(c) },
+
+		"condition.list":   func(c *gin.Context) { controllers.NewConditionController(db).FindAll(c) },
+		"condition.show":   func(c *gin.Context) { controllers.NewConditionController(db).FindOne(c) },
+		"condition.create": func(c *gin.Context) { controllers.NewConditionController(db).Create(c) },
+		"condition.update": func(c *gin.Context) { controllers.NewConditionController(db).Update(c) },
+		"condition.delete": func(c *gin.Context) { controllers.NewConditionController(db).Delete(c) },
+
+		"format.list":   func(c *gin.Context) { controllers.NewFormatController(db).FindAll(c) },
+		"format.show":   func(c *gin.Context) { controllers.NewFormatController(db).FindOne(c) },
+		"format.create": func(c *gin.Context) { controllers.NewFormatController(db).Create(c) },
+		"format.update": func(c *gin.Context) { controllers.NewFormatController(db).Update(c) },
+		"format.delete": func(c *gin.Context) { controllers.NewFormatController(db).Delete(c) },
+
+		"genre.list":   func(c *gin.Context) { controllers.NewGenreController(db).FindAll(c) },
+		"genre.tree":   func(c *gin.Context) { controllers.NewGenreController(db).Tree(c) },
+		"genre.show":   func(c *gin.Context) { controllers.NewGenreController(db).FindOne(c) },
+		"genre.create": func(c *gin.Context) { controllers.NewGenreController(db).Create(c) },
+		"genre.update": func(c *gin.Context) { controllers.NewGenreController(db).Update(c) },
+		"genre.move":   func(c *gin.Context) { controllers.NewGenreController(db).Move(c) },
+		"genre.delete": func(c *gin.Context) { controllers.NewGenreController(db).Delete(c) },
+
+		"inventory.list":   func(c *gin.Context) { controllers.NewInventoryController(db).List(c) },
+		"inventory.show":   func(c *gin.Context) { controllers.NewInventoryController(db).Show(c) },
+		"inventory.report": func(c *gin.Context) { controllers.NewInventoryController(db).Report(c) },
+		"inventory.create": func(c *gin.Context) { controllers.NewInventoryController(db).Create(c) },
+		"inventory.update": func(c *gin.Context) { controllers.NewInventoryController(db).Update(c) },
+		"inventory.delete": func(c *gin.Context) { controllers.NewInventoryController(db).Delete(c) },
+
+		"pricelist.list":    func(c *gin.Context) { controllers.NewPriceRuleController(db).FindAll(c) },
+		"pricelist.preview": func(c *gin.Context) { controllers.NewPriceRuleController(db).Preview(c) },
+		"pricelist.show":    func(c *gin.Context) { controllers.NewPriceRuleController(db).FindOne(c) },
+		"pricelist.create":  func(c *gin.Context) { controllers.NewPriceRuleController(db).Create(c) },
+		"pricelist.update":  func(c *gin.Context) { controllers.NewPriceRuleController(db).Update(c) },
+		"pricelist.delete":  func(c *gin.Context) { controllers.NewPriceRuleController(db).Delete(c) },
+
+		"exchange_rate.list":   func(c *gin.Context) { controllers.NewExchangeRateController(db).FindAll(c) },
+		"exchange_rate.save":   func(c *gin.Context) { controllers.NewExchangeRateController(db).Save(c) },
+		"exchange_rate.import": func(c *gin.Context) { controllers.NewExchangeRateController(db).Import(c) },
+		"exchange_rate.delete": func(c *gin.Context) { controllers.NewExchangeRateController(db).Delete(c) },
+
+		"health.upstreams": handleUpstreamHealth,
+
+		"public.book.show":           func(c *gin.Context) { controllers.NewPublicBookController(db).Show(c) },
+		"public.book.recommendation": func(c *gin.Context) { controllers.NewPublicBookController(db).Recommendation(c) },
+		"public.book.search":         func(c *gin.Context) { controllers.NewPublicBookController(db).Search(c) },
+		"public.book.cover":          func(c *gin.Context) { controllers.NewPublicBookController(db).Image(c) },
+		"public.book.image":          func(c *gin.Context) { controllers.NewPublicBookController(db).GalleryImage(c) },
+		"public.branch.list":         func(c *gin.Context) { controllers.NewPublicBranchController(db).GetBranches(c) },
+		"public.branch.show":         func(c *gin.Context) { controllers.NewPublicBranchController(db).GetBranch(c) },
+		"public.genre.list":          func(c *gin.Context) { controllers.NewPublicGenreController(db).FindAll(c) },
+		"public.reservation.create":  func(c *gin.Context) { controllers.NewPublicReservationController(db).Create(c) },
+
+		"reservation.list":   func(c *gin.Context) { controllers.NewReservationController(db).FindAll(c) },
+		"reservation.status": func(c *gin.Context) { controllers.NewReservationController(db).ReservationStatus(c) },
+		"reservation.show":   func(c *gin.Context) { controllers.NewReservationController(db).FindOne(c) },
+		"reservation.create": func(c *gin.Context) { controllers.NewReservationController(db).Create(c) },
+		"reservation.update": func(c *gin.Context) { controllers.NewReservationController(db).Update(c) },
+		"reservation.delete": func(c *gin.Context) { controllers.NewReservationController(db).Delete(c) },
+
+		"tag.list":   func(c *gin.Context) { controllers.NewTagController(db).FindAll(c) },
+		"tag.show":   func(c *gin.Context) { controllers.NewTagController(db).FindOne(c) },
+		"tag.create": func(c *gin.Context) { controllers.NewTagController(db).Create(c) },
+		"tag.update": func(c *gin.Context) { controllers.NewTagController(db).Update(c) },
+		"tag.delete": func(c *gin.Context) { controllers.NewTagController(db).Delete(c) },
+
+		"transfer.list":    func(c *gin.Context) { controllers.NewTransferController(db).FindAll(c) },
+		"transfer.show":    func(c *gin.Context) { controllers.NewTransferController(db).FindOne(c) },
+		"transfer.create":  func(c *gin.Context) { controllers.NewTransferController(db).Create(c) },
+		"transfer.update":  func(c *gin.Context) { controllers.NewTransferController(db).Update(c) },
+		"transfer.ship":    func(c *gin.Context) { controllers.NewTransferController(db).Ship(c) },
+		"transfer.cancel":  func(c *gin.Context) { controllers.NewTransferController(db).Cancel(c) },
+		"transfer.mapping": func(c *gin.Context) { controllers.NewTransferController(db).Mapping(c) },
+		"transfer.receive": func(c *gin.Context) { controllers.NewTransferController(db).Receive(c) },
+
+		"role.list":        func(c *gin.Context) { controllers.NewRoleController(db).FindAll(c) },
+		"role.permissions": func(c *gin.Context) { controllers.NewRoleController(db).Permissions(c) },
+		"role.create":      func(c *gin.Context) { controllers.NewRoleController(db).Create(c) },
+		"role.update":      func(c *gin.Context) { controllers.NewRoleController(db).Update(c) },
+		"role.delete":      func(c *gin.Context) { controllers.NewRoleController(db).Delete(c) },
+
+		"analyze.shop_search": func(c *gin.Context) { controllers.NewAnalyzeController(mongoDB, db).GetShopSearchEntries(c) },
+	}
+}
+
+// middlewares returns the middlewares that routes can refer to by name.
+// They run after the role check and before the handler.
+func middlewares(db *gorm.DB, mongoDB *mdb.MDBClient) map[string]gin.HandlerFunc {
+	return map[string]gin.HandlerFunc{
+		"own_branch":     IsOwnBranchMiddleware(),
+		"health_token":   TokenMiddleware(viper.GetString("HEALTH_TOKEN")),
+		"analyze.record": func(c *gin.Context) { controllers.NewAnalyzeController(mongoDB, db).Create(c) },
+	}
+}
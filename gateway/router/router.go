@@ -1,23 +1,38 @@
 package router
 
 import (
+	"crypto/subtle"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/abaldeweg/warehouse-server/gateway/auth"
-	"github.com/abaldeweg/warehouse-server/gateway/core/controllers"
 	"github.com/abaldeweg/warehouse-server/gateway/core/database"
+	"github.com/abaldeweg/warehouse-server/gateway/core/repository"
 	"github.com/abaldeweg/warehouse-server/gateway/cover"
 	"github.com/abaldeweg/warehouse-server/gateway/db/mdb"
 	"github.com/abaldeweg/warehouse-server/gateway/proxy"
+	"github.com/abaldeweg/warehouse-server/gateway/search"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
 
 var authenticator = auth.Authenticate
 
+var (
+	// stop is closed on shutdown to end the background jobs.
+	stop     = make(chan struct{})
+	stopOnce sync.Once
+)
+
+// Shutdown ends the background jobs started by Routes.
+func Shutdown() {
+	stopOnce.Do(func() { close(stop) })
+}
+
 // Routes sets up the routes for the gateway.
 func Routes() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
@@ -31,426 +46,167 @@ func Routes() *gin.Engine {
 
 	db := database.Connect()
 
-	mongoDB, _ := mdb.NewMDBClient()
+	index, err := search.Default()
+	if err != nil {
+		log.Fatalf("search: %v", err)
+	}
+	syncer, err := search.Attach(db, index)
+	if err != nil {
+		log.Fatalf("search: %v", err)
+	}
+	viper.SetDefault("SEARCH_REBUILD_INTERVAL", "15m")
+	if interval := viper.GetDuration("SEARCH_REBUILD_INTERVAL"); interval > 0 {
+		go syncer.StartRebuild(interval, stop)
+	}
 
-	apiCore := r.Group(`/apis/core/1`)
-	{
-		apiCoreAuthor := apiCore.Group(`/api/author`)
-		{
-			apiCoreAuthor.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreAuthor.GET(`/find`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ac := controllers.NewAuthorController(db)
-				ac.GetAuthors(c)
-			})
-
-			apiCoreAuthor.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ac := controllers.NewAuthorController(db)
-				ac.GetAuthor(c)
-			})
-			apiCoreAuthor.POST(`/new`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ac := controllers.NewAuthorController(db)
-				ac.CreateAuthor(c)
-			})
-			apiCoreAuthor.PUT(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ac := controllers.NewAuthorController(db)
-				ac.UpdateAuthor(c)
-			})
-			apiCoreAuthor.DELETE(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				ac := controllers.NewAuthorController(db)
-				ac.DeleteAuthor(c)
-			})
+	if err := proxy.Register(viper.GetString("API_CORE")); err != nil {
+		log.Printf("warning: %v", err)
+	}
+	if me := viper.GetString("AUTH_API_ME"); me != "" {
+		if err := proxy.Register(me); err != nil {
+			log.Printf("warning: %v", err)
 		}
+	}
 
-		// @fix: port to new API
-		apiCoreBook := apiCore.Group(`/api/book`)
-		{
-			apiCoreBook.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreBook.GET(`/find`, handleCoreAPI("/api/book/find"))
-			apiCoreBook.DELETE(`/clean`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.CleanBooks(c)
-			})
-			apiCoreBook.GET(`/stats`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.ShowStats(c)
-			})
-			apiCoreBook.PUT(`/inventory/found/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.FindInventory(c)
-			})
-			apiCoreBook.PUT(`/inventory/notfound/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.NotFoundInventory(c)
-			})
-			apiCoreBook.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.ShowBook(c)
-			})
-			apiCoreBook.POST(`/new`, handleCoreAPI("/api/book/new"))
-			apiCoreBook.PUT(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.UpdateBook(c)
-			})
-			apiCoreBook.GET(`/cover/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.ShowCover(c)
-			})
-			apiCoreBook.POST(`/cover/:id`, handleCover)
-			apiCoreBook.DELETE(`/cover/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.DeleteCover(c)
-			})
-			apiCoreBook.PUT(`/sell/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.SellBook(c)
-			})
-			apiCoreBook.PUT(`/remove/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.RemoveBook(c)
-			})
-			apiCoreBook.PUT(`/reserve/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.ReserveBook(c)
-			})
-			apiCoreBook.DELETE(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBookController(db)
-				bc.DeleteBook(c)
-			})
-		}
+	if interval := viper.GetDuration("COVER_GC_INTERVAL"); interval > 0 {
+		go cover.StartGC(repository.NewBookRepository(db).ExistingIDs, interval, stop)
+	}
 
-		apiCoreBranch := apiCore.Group(`/api/branch`)
-		{
-			apiCoreBranch.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreBranch.GET(`/`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBranchController(db)
-				bc.List(c)
-			})
-			apiCoreBranch.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewBranchController(db)
-				bc.Show(c)
-			})
-			apiCoreBranch.PUT(`/:id`, RoleMiddleware("ROLE_ADMIN"), IsOwnBranchMiddleware(), func(c *gin.Context) {
-				bc := controllers.NewBranchController(db)
-				bc.Update(c)
-			})
-		}
+	mongoDB, _ := mdb.NewMDBClient()
 
-		apiCoreCondition := apiCore.Group(`/api/condition`)
-		{
-			apiCoreCondition.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreCondition.GET(`/`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewConditionController(db)
-				bc.FindAll(c)
-			})
-			apiCoreCondition.POST(`/new`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				bc := controllers.NewConditionController(db)
-				bc.Create(c)
-			})
-			apiCoreCondition.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				bc := controllers.NewConditionController(db)
-				bc.FindOne(c)
-			})
-			apiCoreCondition.PUT(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				bc := controllers.NewConditionController(db)
-				bc.Update(c)
-			})
-			apiCoreCondition.DELETE(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				bc := controllers.NewConditionController(db)
-				bc.Delete(c)
-			})
-		}
+	cfg, err := LoadRouteConfig()
+	if err != nil {
+		log.Fatalf("routes: %v", err)
+	}
 
-		apiCoreFormat := apiCore.Group(`/api/format`)
-		{
-			apiCoreFormat.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreFormat.GET(`/`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				fc := controllers.NewFormatController(db)
-				fc.FindAll(c)
-			})
-			apiCoreFormat.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				fc := controllers.NewFormatController(db)
-				fc.FindOne(c)
-			})
-			apiCoreFormat.POST(`/new`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				fc := controllers.NewFormatController(db)
-				fc.Create(c)
-			})
-			apiCoreFormat.PUT(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				fc := controllers.NewFormatController(db)
-				fc.Update(c)
-			})
-			apiCoreFormat.DELETE(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				fc := controllers.NewFormatController(db)
-				fc.Delete(c)
-			})
+	h, m := handlers(db, mongoDB), middlewares(db, mongoDB)
+	if err := cfg.Validate(h, m); err != nil {
+		log.Fatalf("routes: invalid config:\n%v", err)
+	}
+	cfg.register(r, h, m, repository.NewRoleRepository(db).Permissions)
+
+	return r
+}
+
+// handleCoreAPI handles requests to the core API.
+func handleCoreAPI(path string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		safePath := filepath.Join("/", path)
+
+		if err := proxy.Proxy(c, viper.GetString("API_CORE"), safePath); err != nil {
+			log.Printf("proxy %s: %v", safePath, err)
 		}
+	}
+}
 
-		apiCoreGenre := apiCore.Group(`/api/genre`)
-		{
-			apiCoreGenre.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreGenre.GET(`/`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ac := controllers.NewGenreController(db)
-				ac.FindAll(c)
-			})
-			apiCoreGenre.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ac := controllers.NewGenreController(db)
-				ac.FindOne(c)
-			})
-			apiCoreGenre.POST(`/new`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				ac := controllers.NewGenreController(db)
-				ac.Create(c)
-			})
-			apiCoreGenre.PUT(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				ac := controllers.NewGenreController(db)
-				ac.Update(c)
-			})
-			apiCoreGenre.DELETE(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				ac := controllers.NewGenreController(db)
-				ac.Delete(c)
-			})
+// handleUpstreamHealth reports the circuit breaker state of the upstreams.
+func handleUpstreamHealth(c *gin.Context) {
+	upstreams := proxy.Upstreams()
+
+	status := "ok"
+	for _, u := range upstreams {
+		if u.State != proxy.StateClosed {
+			status = "degraded"
 		}
+	}
 
-		apiCoreInventory := apiCore.Group(`/api/inventory`)
-		{
-			apiCoreInventory.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreInventory.GET(`/`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ic := controllers.NewInventoryController(db)
-				ic.List(c)
-			})
-			apiCoreInventory.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				ic := controllers.NewInventoryController(db)
-				ic.Show(c)
-			})
-			apiCoreInventory.POST(`/new`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				ic := controllers.NewInventoryController(db)
-				ic.Create(c)
-			})
-			apiCoreInventory.PUT(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				ic := controllers.NewInventoryController(db)
-				ic.Update(c)
-			})
-			apiCoreInventory.DELETE(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				ic := controllers.NewInventoryController(db)
-				ic.Delete(c)
-			})
+	c.JSON(http.StatusOK, gin.H{"status": status, "upstreams": upstreams})
+}
+
+// TokenMiddleware allows access with the token as bearer token. It doesn't
+// depend on the auth service, e.g. to check the health of the upstreams
+// while it is down. Without a token all requests are denied.
+func TokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+			return
 		}
+		c.Next()
+	}
+}
 
-		// @fix: port to new API
-		apiCore.GET(`/api/me`, handleCoreAPI("/api/me"))
-		apiCore.POST(`/api/login_check`, handleCoreAPI("/api/login_check"))
-		apiCore.PUT(`/api/password`, handleCoreAPI("/api/password"))
-
-		apiCorePublic := apiCore.Group(`/api/public`)
-		{
-			apiCorePublicBook := apiCorePublic.Group(`/book`)
-			apiCorePublicBook.GET(`/find`, func(ctx *gin.Context) {
-				apiAnalyze := controllers.NewAnalyzeController(mongoDB, db)
-				apiAnalyze.Create(ctx)
-			}, handleCoreAPI("/api/public/book/find"))
-			{
-				apiCorePublicBook.GET(`/:id`, func(c *gin.Context) {
-					pbc := controllers.NewPublicBookController(db)
-					pbc.Show(c)
-				})
-				apiCorePublicBook.GET(`/recommendation/:branch`, func(c *gin.Context) {
-					pbc := controllers.NewPublicBookController(db)
-					pbc.Recommendation(c)
-				})
-				apiCorePublicBook.GET(`/cover/:image`, func(c *gin.Context) {
-					pbc := controllers.NewPublicBookController(db)
-					pbc.Image(c)
-				})
+// RoleMiddleware ensures that the user has the specified role in the active
+// branch before allowing access. The super admin role is checked in all
+// branches of the user.
+func RoleMiddleware(requiredRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if requiredRole == auth.RoleSuperAdmin {
+			user, ok := c.Get("user")
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+				return
 			}
-			apiCorePublic.GET(`/branch/`, func(c *gin.Context) {
-				ac := controllers.NewPublicBranchController(db)
-				ac.GetBranches(c)
-			})
-			apiCorePublic.GET(`/branch/show/:id`, func(c *gin.Context) {
-				ac := controllers.NewPublicBranchController(db)
-				ac.GetBranch(c)
-			})
-			apiCorePublic.GET(`/genre/:id`, func(c *gin.Context) {
-				pbc := controllers.NewPublicGenreController(db)
-				pbc.FindAll(c)
-			})
-			// apiCorePublic.POST(`/reservation/new`, handleCoreAPI("/api/public/reservation/new"))
-			apiCorePublic.POST(`/reservation/new`, func(c *gin.Context) {
-				rc := controllers.NewPublicReservationController(db)
-				rc.Create(c)
-			})
+			if !user.(auth.User).IsSuperAdmin() {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+				return
+			}
+			c.Next()
+			return
 		}
 
-		apiCoreReservation := apiCore.Group(`/api/reservation`)
-		{
-			apiCoreReservation.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
-				c.Next()
-			})
-
-			apiCoreReservation.GET(`/list`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				rc := controllers.NewReservationController(db)
-				rc.FindAll(c)
-			})
-			apiCoreReservation.GET(`/status`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				rc := controllers.NewReservationController(db)
-				rc.ReservationStatus(c)
-			})
-			apiCoreReservation.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				rc := controllers.NewReservationController(db)
-				rc.FindOne(c)
-			})
-			apiCoreReservation.POST(`/new`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				rc := controllers.NewReservationController(db)
-				rc.Create(c)
-			})
-			apiCoreReservation.PUT(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				rc := controllers.NewReservationController(db)
-				rc.Update(c)
-			})
-			apiCoreReservation.DELETE(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				rc := controllers.NewReservationController(db)
-				rc.Delete(c)
-			})
+		membership, ok := auth.ActiveBranch(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+			return
 		}
 
-		apiCoreTag := apiCore.Group(`/api/tag`)
-		{
-			apiCoreTag.Use(func(c *gin.Context) {
-				if !authenticator(c) {
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
-					return
-				}
+		for _, role := range membership.Roles {
+			if role == requiredRole {
 				c.Next()
-			})
-
-			apiCoreTag.GET(`/`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				tc := controllers.NewTagController(db)
-				tc.FindAll(c)
-			})
-			apiCoreTag.GET(`/:id`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				tc := controllers.NewTagController(db)
-				tc.FindOne(c)
-			})
-			apiCoreTag.POST(`/new`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-				tc := controllers.NewTagController(db)
-				tc.Create(c)
-			})
-			apiCoreTag.PUT(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				tc := controllers.NewTagController(db)
-				tc.Update(c)
-			})
-			apiCoreTag.DELETE(`/:id`, RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) {
-				tc := controllers.NewTagController(db)
-				tc.Delete(c)
-			})
-		}
-
-		apiAnalyze := apiCore.Group(`/api/analyze`)
-		apiAnalyze.Use(func(c *gin.Context) {
-			if !authenticator(c) {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 				return
 			}
-			c.Next()
-		})
-		apiAnalyze.GET(`/shop-search`, RoleMiddleware("ROLE_USER"), func(c *gin.Context) {
-			ac := controllers.NewAnalyzeController(mongoDB, db)
-			ac.GetShopSearchEntries(c)
-		})
-	}
+		}
 
-	return r
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+	}
 }
 
-// handleCoreAPI handles requests to the core API.
-func handleCoreAPI(path string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		safePath := filepath.Join("/", path)
+// RoleSource returns the permissions of the roles a branch configured.
+type RoleSource func(branchId uint) (map[string][]string, error)
 
-		if err := proxy.Proxy(c, viper.GetString("API_CORE"), safePath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+// PermissionsMiddleware resolves the permissions of the user's roles in the
+// active branch.
+func PermissionsMiddleware(roles RoleSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		membership, ok := auth.ActiveBranch(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 			return
 		}
-	}
-}
 
-// handleCover handles requests to the core API.
-func handleCover(c *gin.Context) {
-	imageID := c.Param("id")
-
-	validate := validator.New()
-	errUUID := validate.Var(imageID, "uuid")
-	errInt := validate.Var(imageID, "numeric")
+		var branchRoles map[string][]string
+		if roles != nil {
+			var err error
+			if branchRoles, err = roles(uint(membership.Branch.Id)); err != nil {
+				log.Printf("roles of branch %d: %v", membership.Branch.Id, err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"msg": "Internal Error"})
+				return
+			}
+		}
 
-	if errUUID != nil && errInt != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"msg": "Invalid ID"})
-		return
+		auth.SetPermissions(c, auth.ResolvePermissions(membership.Roles, branchRoles))
+		c.Next()
 	}
+}
 
-	if authenticator(c) {
-		cover.SaveCover(c, imageID)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Image uploaded successfully"})
-		return
+// PermissionMiddleware ensures that the user has the permission in the
+// active branch before allowing access.
+func PermissionMiddleware(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.HasPermission(c, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+			return
+		}
+		c.Next()
 	}
-
-	c.JSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 }
 
-// RoleMiddleware ensures that the user has the specified role before allowing access.
-func RoleMiddleware(requiredRole string) gin.HandlerFunc {
+// BranchMiddleware sets the active branch of the request. It is taken from
+// the X-Branch-ID header and must be one of the user's branches, without the
+// header the default branch of the user is used.
+func BranchMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, ok := c.Get("user")
 		if !ok {
@@ -458,24 +214,53 @@ func RoleMiddleware(requiredRole string) gin.HandlerFunc {
 			return
 		}
 
-		userRoles := user.(auth.User).Roles
-
-		for _, role := range userRoles {
-			if role == requiredRole {
-				c.Next()
+		var membership auth.Membership
+		if header := c.GetHeader(auth.BranchHeader); header != "" {
+			branchId, err := strconv.Atoi(header)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
+				return
+			}
+			if membership, ok = user.(auth.User).Membership(branchId); !ok {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
 				return
 			}
+		} else if membership, ok = user.(auth.User).DefaultMembership(); !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+			return
 		}
 
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "Forbidden"})
+		auth.SetActiveBranch(c, membership)
+		c.Next()
 	}
 }
 
-// IsOwnBranchMiddleware ensures that the user is associated with the branch being accessed.
-// It checks if the user's branch ID matches the branch ID provided in the request context.
-func IsOwnBranchMiddleware() gin.HandlerFunc {
+// DefaultBranchMiddleware ensures that the active branch is the default
+// branch of the user. The core doesn't know the X-Branch-ID header and works
+// in the default branch, so proxied requests for other branches are
+// rejected instead of changing the wrong branch.
+func DefaultBranchMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, ok := c.Get("user")
+		activeId, active := auth.BranchID(c)
+		if !ok || !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+			return
+		}
+
+		if m, ok := user.(auth.User).DefaultMembership(); ok && uint(m.Branch.Id) == activeId {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Only the default branch is supported"})
+	}
+}
+
+// IsOwnBranchMiddleware ensures that the branch being accessed is the active branch.
+func IsOwnBranchMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		activeId, ok := auth.BranchID(c)
 		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
 			return
@@ -487,7 +272,7 @@ func IsOwnBranchMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if user.(auth.User).Branch.Id == branchId {
+		if uint(branchId) == activeId {
 			c.Next()
 			return
 		}
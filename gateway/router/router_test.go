@@ -0,0 +1,174 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBranchMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user := auth.User{
+		Id:     1,
+		Branch: auth.Branch{Id: 1},
+		Roles:  []string{"ROLE_USER", "ROLE_ADMIN"},
+		Memberships: []auth.Membership{
+			{Branch: auth.Branch{Id: 1}, Roles: []string{"ROLE_USER", "ROLE_ADMIN"}},
+			{Branch: auth.Branch{Id: 2}, Roles: []string{"ROLE_USER"}},
+		},
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user", user) }, BranchMiddleware())
+	r.GET("/branch", func(c *gin.Context) {
+		id, _ := auth.BranchID(c)
+		c.JSON(http.StatusOK, id)
+	})
+	r.GET("/admin", RoleMiddleware("ROLE_ADMIN"), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/super", RoleMiddleware(auth.RoleSuperAdmin), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.PUT("/branch/:id", IsOwnBranchMiddleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		method string
+		path   string
+		branch string
+		status int
+		body   string
+	}{
+		{http.MethodGet, "/branch", "", http.StatusOK, "1"},
+		{http.MethodGet, "/branch", "2", http.StatusOK, "2"},
+		{http.MethodGet, "/branch", "3", http.StatusForbidden, ""},
+		{http.MethodGet, "/branch", "abc", http.StatusBadRequest, ""},
+		{http.MethodGet, "/admin", "1", http.StatusNoContent, ""},
+		{http.MethodGet, "/admin", "2", http.StatusForbidden, ""},
+		{http.MethodGet, "/super", "1", http.StatusForbidden, ""},
+		{http.MethodPut, "/branch/2", "2", http.StatusNoContent, ""},
+		{http.MethodPut, "/branch/2", "1", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.branch != "" {
+			req.Header.Set(auth.BranchHeader, tt.branch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.method+" "+tt.path+" in "+tt.branch)
+		if tt.body != "" {
+			assert.Equal(t, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	roles := func(branchId uint) (map[string][]string, error) {
+		if branchId == 2 {
+			return map[string][]string{"ROLE_VOLUNTEER": {auth.PermBookSell}}, nil
+		}
+		return nil, nil
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", auth.User{
+			Id: 1,
+			Memberships: []auth.Membership{
+				{Branch: auth.Branch{Id: 1}, Roles: []string{"ROLE_USER"}},
+				{Branch: auth.Branch{Id: 2}, Roles: []string{"ROLE_VOLUNTEER"}},
+			},
+		})
+	}, BranchMiddleware(), PermissionsMiddleware(roles))
+	r.PUT("/sell", PermissionMiddleware(auth.PermBookSell), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.DELETE("/book", PermissionMiddleware(auth.PermBookDelete), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		method string
+		path   string
+		branch string
+		status int
+	}{
+		{http.MethodPut, "/sell", "1", http.StatusNoContent},
+		{http.MethodDelete, "/book", "1", http.StatusNoContent},
+		{http.MethodPut, "/sell", "2", http.StatusNoContent},
+		{http.MethodDelete, "/book", "2", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set(auth.BranchHeader, tt.branch)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.method+" "+tt.path+" in "+tt.branch)
+	}
+}
+
+func TestTokenMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/health", TokenMiddleware("secret"), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/disabled", TokenMiddleware(""), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		path          string
+		authorization string
+		status        int
+	}{
+		{"/health", "Bearer secret", http.StatusNoContent},
+		{"/health", "Bearer wrong", http.StatusUnauthorized},
+		{"/health", "secret", http.StatusUnauthorized},
+		{"/health", "", http.StatusUnauthorized},
+		{"/disabled", "Bearer ", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", tt.authorization)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.path+" with "+tt.authorization)
+	}
+}
+
+func TestDefaultBranchMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user := auth.User{
+		Id:     1,
+		Branch: auth.Branch{Id: 1},
+		Memberships: []auth.Membership{
+			{Branch: auth.Branch{Id: 1}, Roles: []string{"ROLE_USER"}},
+			{Branch: auth.Branch{Id: 2}, Roles: []string{"ROLE_USER"}},
+		},
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user", user) }, BranchMiddleware())
+	r.GET("/core", DefaultBranchMiddleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		branch string
+		status int
+	}{
+		{"", http.StatusNoContent},
+		{"1", http.StatusNoContent},
+		{"2", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/core", nil)
+		if tt.branch != "" {
+			req.Header.Set(auth.BranchHeader, tt.branch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, "in "+tt.branch)
+	}
+}
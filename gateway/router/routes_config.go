@@ -0,0 +1,200 @@
+package router
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+//go:embed routes.yaml
+var defaultRoutes []byte
+
+// roles that routes can require.
+var roles = []string{"ROLE_USER", "ROLE_ADMIN", auth.RoleSuperAdmin}
+
+var methods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// RouteConfig describes the routes of the gateway.
+type RouteConfig struct {
+	Groups []RouteGroup `mapstructure:"groups"`
+}
+
+// RouteGroup is a list of routes with a common prefix. If Auth is set, the
+// routes require a logged in user and work in the user's active branch.
+type RouteGroup struct {
+	Prefix string  `mapstructure:"prefix"`
+	Auth   bool    `mapstructure:"auth"`
+	Routes []Route `mapstructure:"routes"`
+}
+
+// Route is served by the native Handler or proxied to the Upstream path of
+// the core API. Role and Permission are checked in the active branch.
+type Route struct {
+	Method     string   `mapstructure:"method"`
+	Path       string   `mapstructure:"path"`
+	Role       string   `mapstructure:"role"`
+	Permission string   `mapstructure:"permission"`
+	Handler    string   `mapstructure:"handler"`
+	Upstream   string   `mapstructure:"upstream"`
+	Middleware []string `mapstructure:"middleware"`
+}
+
+// LoadRouteConfig reads the routes from the file in ROUTES_FILE or, if it is
+// not set, the routes.yaml embedded in the binary.
+func LoadRouteConfig() (RouteConfig, error) {
+	data := defaultRoutes
+	if file := viper.GetString("ROUTES_FILE"); file != "" {
+		var err error
+		if data, err = os.ReadFile(file); err != nil {
+			return RouteConfig{}, err
+		}
+	}
+
+	return ParseRouteConfig(data)
+}
+
+// ParseRouteConfig parses a route config in YAML.
+func ParseRouteConfig(data []byte) (RouteConfig, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return RouteConfig{}, err
+	}
+
+	var cfg RouteConfig
+	if err := v.Unmarshal(&cfg); err != nil {
+		return RouteConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that every route is complete, unique and refers only to
+// existing handlers, middlewares, roles and permissions.
+func (cfg RouteConfig) Validate(handlers, middlewares map[string]gin.HandlerFunc) error {
+	var errs []error
+	seen := map[string]bool{}
+
+	for _, g := range cfg.Groups {
+		for _, r := range g.Routes {
+			route := strings.ToUpper(r.Method) + " " + joinPath(g.Prefix, r.Path)
+			fail := func(format string, args ...any) {
+				errs = append(errs, fmt.Errorf("%s: %s", route, fmt.Sprintf(format, args...)))
+			}
+
+			if !slices.Contains(methods, strings.ToUpper(r.Method)) {
+				fail("invalid method %q", r.Method)
+			}
+			if !strings.HasPrefix(r.Path, "/") {
+				fail("path must start with /")
+			}
+			if seen[route] {
+				fail("defined twice")
+			}
+			seen[route] = true
+
+			switch {
+			case r.Handler != "" && r.Upstream != "":
+				fail("has both a handler and an upstream")
+			case r.Handler == "" && r.Upstream == "":
+				fail("needs a handler or an upstream")
+			case r.Handler != "" && handlers[r.Handler] == nil:
+				fail("unknown handler %q", r.Handler)
+			case r.Upstream != "" && !strings.HasPrefix(r.Upstream, "/"):
+				fail("upstream must start with /")
+			}
+
+			if r.Role != "" {
+				if !slices.Contains(roles, r.Role) {
+					fail("unknown role %q", r.Role)
+				}
+				if !g.Auth {
+					fail("role %q requires auth", r.Role)
+				}
+			}
+
+			if r.Permission != "" {
+				if !auth.IsPermission(r.Permission) {
+					fail("unknown permission %q", r.Permission)
+				}
+				if !g.Auth {
+					fail("permission %q requires auth", r.Permission)
+				}
+			}
+
+			for _, name := range r.Middleware {
+				if middlewares[name] == nil {
+					fail("unknown middleware %q", name)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// register adds the routes to the engine. The config must be valid. The
+// roles of the branches are read from the source, without a source only the
+// default roles apply. Proxied routes with auth only work in the default
+// branch of the user, as the core doesn't support other branches.
+func (cfg RouteConfig) register(r *gin.Engine, handlers, middlewares map[string]gin.HandlerFunc, roles RoleSource) {
+	for _, g := range cfg.Groups {
+		group := r.Group(g.Prefix)
+		if g.Auth {
+			group.Use(authenticate, BranchMiddleware(), PermissionsMiddleware(roles))
+		}
+
+		for _, route := range g.Routes {
+			var chain []gin.HandlerFunc
+			if route.Role != "" {
+				chain = append(chain, RoleMiddleware(route.Role))
+			}
+			if route.Permission != "" {
+				chain = append(chain, PermissionMiddleware(route.Permission))
+			}
+			for _, name := range route.Middleware {
+				chain = append(chain, middlewares[name])
+			}
+			if route.Handler != "" {
+				chain = append(chain, handlers[route.Handler])
+			} else {
+				if g.Auth {
+					chain = append(chain, DefaultBranchMiddleware())
+				}
+				chain = append(chain, handleCoreAPI(route.Upstream))
+			}
+
+			group.Handle(strings.ToUpper(route.Method), route.Path, chain...)
+		}
+	}
+}
+
+// authenticate aborts the request if the user is not logged in.
+func authenticate(c *gin.Context) {
+	if !authenticator(c) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Unauthorized"})
+		return
+	}
+	c.Next()
+}
+
+func joinPath(prefix, path string) string {
+	return strings.TrimRight(prefix, "/") + "/" + strings.TrimLeft(path, "/")
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRouteConfig(t *testing.T) {
+	cfg, err := ParseRouteConfig(defaultRoutes)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cfg.Groups)
+	assert.NoError(t, cfg.Validate(handlers(nil, nil), middlewares(nil, nil)))
+}
+
+func TestRouteConfigValidate(t *testing.T) {
+	cfg, err := ParseRouteConfig([]byte(`
+groups:
+  - prefix: /api/test
+    routes:
+      - {method: GET, path: /a, handler: test.missing}
+      - {method: GET, path: /b}
+      - {method: GET, path: /c, handler: test, upstream: /api/c}
+      - {method: FETCH, path: /d, handler: test}
+      - {method: GET, path: /e, role: ROLE_USER, handler: test}
+      - {method: GET, path: f, handler: test}
+      - {method: GET, path: /g, middleware: [missing], handler: test}
+      - {method: GET, path: /i, permission: book.sell, handler: test}
+  - prefix: /api/test
+    auth: true
+    routes:
+      - {method: GET, path: /h, role: ROLE_NONE, upstream: /api/h}
+      - {method: GET, path: /h, role: ROLE_USER, upstream: api/h}
+      - {method: GET, path: /j, permission: book.fly, handler: test}
+`))
+	assert.NoError(t, err)
+
+	h := map[string]gin.HandlerFunc{"test": func(c *gin.Context) {}}
+	err = cfg.Validate(h, map[string]gin.HandlerFunc{})
+	assert.EqualError(t, err, `GET /api/test/a: unknown handler "test.missing"
+GET /api/test/b: needs a handler or an upstream
+GET /api/test/c: has both a handler and an upstream
+FETCH /api/test/d: invalid method "FETCH"
+GET /api/test/e: role "ROLE_USER" requires auth
+GET /api/test/f: path must start with /
+GET /api/test/g: unknown middleware "missing"
+GET /api/test/i: permission "book.sell" requires auth
+GET /api/test/h: unknown role "ROLE_NONE"
+GET /api/test/h: defined twice
+GET /api/test/h: upstream must start with /
+GET /api/test/j: unknown permission "book.fly"`)
+}
+
+func TestRouteConfigRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator = auth.AuthenticateTEST
+
+	cfg, err := ParseRouteConfig([]byte(`
+groups:
+  - prefix: /api/test
+    auth: true
+    routes:
+      - {method: GET, path: /user, role: ROLE_USER, handler: test}
+      - {method: GET, path: /other, role: ROLE_OTHER, handler: test}
+      - {method: DELETE, path: /user, permission: book.delete, handler: test}
+      - {method: DELETE, path: /admin, permission: format.edit, handler: test}
+  - prefix: /api/public
+    routes:
+      - {method: post, path: /test, middleware: [header], handler: test}
+`))
+	assert.NoError(t, err)
+
+	h := map[string]gin.HandlerFunc{"test": func(c *gin.Context) { c.Status(http.StatusNoContent) }}
+	m := map[string]gin.HandlerFunc{"header": func(c *gin.Context) { c.Header("X-Test", "1") }}
+	r := gin.New()
+	cfg.register(r, h, m, nil)
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/api/test/user", http.StatusNoContent},
+		{http.MethodGet, "/api/test/other", http.StatusForbidden},
+		{http.MethodDelete, "/api/test/user", http.StatusNoContent},
+		{http.MethodDelete, "/api/test/admin", http.StatusNoContent},
+		{http.MethodPost, "/api/public/test", http.StatusNoContent},
+		{http.MethodGet, "/api/public/test", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.status, w.Code, tt.method+" "+tt.path)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/public/test", nil))
+	assert.Equal(t, "1", w.Header().Get("X-Test"))
+}
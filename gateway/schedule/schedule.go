@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+)
+
+// searchDays limits the days searched for the next opening.
+const searchDays = 366
+
+// IsOpen reports whether the branch is open at the time.
+func IsOpen(s models.BranchSchedule, t time.Time) bool {
+	t = t.In(s.Location())
+	if IsClosed(s, t) {
+		return false
+	}
+
+	clock := t.Format("15:04")
+	for _, h := range s.Hours {
+		if h.Weekday == t.Weekday() && h.Opens <= clock && clock < h.Closes {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosed reports whether a closure covers the day of the time.
+func IsClosed(s models.BranchSchedule, t time.Time) bool {
+	day := t.In(s.Location()).Format(time.DateOnly)
+	for _, c := range s.Closures {
+		to := c.To
+		if to == "" {
+			to = c.From
+		}
+		if c.From <= day && day <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// ClosingTime returns the time the branch closes on the day of the time. It
+// returns false if the branch doesn't open that day.
+func ClosingTime(s models.BranchSchedule, t time.Time) (time.Time, bool) {
+	loc := s.Location()
+	t = t.In(loc)
+	if IsClosed(s, t) {
+		return time.Time{}, false
+	}
+
+	closes := ""
+	for _, h := range s.Hours {
+		if h.Weekday == t.Weekday() && h.Closes > closes {
+			closes = h.Closes
+		}
+	}
+	if closes == "" {
+		return time.Time{}, false
+	}
+
+	clock, err := time.Parse("15:04", closes)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), clock.Hour(), clock.Minute(), 0, 0, loc), true
+}
+
+// PickupDeadline returns until when books reserved at the time are held:
+// the closing time of the PickupDays-th opening day after the day of the
+// reservation. It returns false if the branch sets no pickup days or doesn't
+// open within a year.
+func PickupDeadline(s models.BranchSchedule, t time.Time) (time.Time, bool) {
+	if s.PickupDays <= 0 {
+		return time.Time{}, false
+	}
+
+	t = t.In(s.Location())
+	days := 0
+	for i := 1; i <= searchDays; i++ {
+		closes, ok := ClosingTime(s, t.AddDate(0, 0, i))
+		if !ok {
+			continue
+		}
+		if days++; days == s.PickupDays {
+			return closes, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var berlin, _ = time.LoadLocation("Europe/Berlin")
+
+var s = models.BranchSchedule{
+	Timezone: "Europe/Berlin",
+	Hours: []models.OpeningHours{
+		{Weekday: time.Monday, Opens: "10:00", Closes: "13:00"},
+		{Weekday: time.Monday, Opens: "14:00", Closes: "18:00"},
+		{Weekday: time.Wednesday, Opens: "10:00", Closes: "18:00"},
+		{Weekday: time.Saturday, Opens: "09:00", Closes: "12:30"},
+	},
+	Closures: []models.Closure{
+		{From: "2026-12-24", To: "2026-12-28", Reason: "Christmas"},
+		{From: "2026-12-30"},
+	},
+	PickupDays: 2,
+}
+
+func at(day string, clock string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04", day+" "+clock, berlin)
+	return t
+}
+
+func TestIsOpen(t *testing.T) {
+	testCases := []struct {
+		time time.Time
+		open bool
+	}{
+		{at("2026-10-19", "09:59"), false},
+		{at("2026-10-19", "10:00"), true},
+		{at("2026-10-19", "13:30"), false},
+		{at("2026-10-19", "17:59"), true},
+		{at("2026-10-19", "18:00"), false},
+		{at("2026-10-20", "12:00"), false},
+		{at("2026-10-24", "12:00"), true},
+		{at("2026-12-26", "10:00"), false},
+		{at("2026-12-30", "12:00"), false},
+		{at("2026-10-19", "12:00").UTC(), true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.open, IsOpen(s, tc.time), tc.time.String())
+	}
+
+	assert.False(t, IsOpen(models.BranchSchedule{}, at("2026-10-19", "12:00")))
+}
+
+func TestClosingTime(t *testing.T) {
+	closes, ok := ClosingTime(s, at("2026-10-19", "08:00"))
+	assert.True(t, ok)
+	assert.WithinDuration(t, at("2026-10-19", "18:00"), closes, 0)
+
+	_, ok = ClosingTime(s, at("2026-10-20", "08:00"))
+	assert.False(t, ok)
+
+	_, ok = ClosingTime(s, at("2026-12-28", "08:00"))
+	assert.False(t, ok)
+}
+
+func TestPickupDeadline(t *testing.T) {
+	testCases := []struct {
+		name     string
+		time     time.Time
+		deadline time.Time
+	}{
+		{"next opening days", at("2026-10-19", "11:00"), at("2026-10-24", "12:30")},
+		{"from a closed day", at("2026-10-20", "11:00"), at("2026-10-24", "12:30")},
+		{"across closures", at("2026-12-23", "11:00"), at("2027-01-04", "18:00")},
+		{"after closures", at("2027-01-02", "11:00"), at("2027-01-06", "18:00")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deadline, ok := PickupDeadline(s, tc.time)
+			assert.True(t, ok)
+			assert.WithinDuration(t, tc.deadline, deadline, 0)
+		})
+	}
+
+	_, ok := PickupDeadline(models.BranchSchedule{Hours: s.Hours}, at("2026-10-19", "11:00"))
+	assert.False(t, ok)
+
+	_, ok = PickupDeadline(models.BranchSchedule{PickupDays: 1}, at("2026-10-19", "11:00"))
+	assert.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, s.Validate())
+	assert.ErrorIs(t, models.BranchSchedule{Hours: []models.OpeningHours{{Opens: "12:00", Closes: "10:00"}}}.Validate(), models.ErrInvalidSchedule)
+	assert.ErrorIs(t, models.BranchSchedule{Closures: []models.Closure{{From: "2026-01-02", To: "2026-01-01"}}}.Validate(), models.ErrInvalidSchedule)
+}
@@ -0,0 +1,51 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	testCases := []struct {
+		text string
+		want string
+	}{
+		{"Émile Zola", "emile zola"},
+		{"Straße", "strasse"},
+		{"Søren Kierkegaard", "soren kierkegaard"},
+		{"Œuvres complètes", "oeuvres completes"},
+		{"Łódź", "lodz"},
+		{"plain", "plain"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, Fold(tc.text), tc.text)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"der", "schwarm", "2", "auflage"}, Tokenize("Der Schwarm (2. Auflage)"))
+	assert.Equal(t, []string{"l", "etranger"}, Tokenize("L'Étranger"))
+	assert.Empty(t, Tokenize(" - "))
+}
+
+func TestDistance(t *testing.T) {
+	testCases := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"kafka", "kafka", 2, 0},
+		{"kafka", "kafak", 2, 1},
+		{"kafka", "kaffka", 2, 1},
+		{"kafka", "kfka", 2, 1},
+		{"kafka", "kofke", 2, 2},
+		{"kafka", "zola", 1, 2},
+		{"kafka", "k", 2, 3},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, distance([]rune(tc.a), []rune(tc.b), tc.limit), tc.a+" "+tc.b)
+	}
+}
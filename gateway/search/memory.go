@@ -0,0 +1,235 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factors of the score of words that don't match exactly.
+const (
+	prefixFactor = 0.8
+	typoFactor   = 0.5
+)
+
+// MemoryIndex is an inverted index kept in memory. Words match exactly, as
+// prefix of an indexed word or, if long enough, with a typo.
+type MemoryIndex struct {
+	mu    sync.RWMutex
+	kinds map[string]*memoryKind
+}
+
+type memoryKind struct {
+	ready    bool
+	docs     map[string]memoryDoc
+	postings map[string]map[string]float64
+	words    []string
+}
+
+type memoryDoc struct {
+	branchID uint
+	words    []string
+}
+
+// NewMemoryIndex creates an empty index.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{kinds: map[string]*memoryKind{}}
+}
+
+// Replace replaces all documents of the kind and marks it ready.
+func (m *MemoryIndex) Replace(kind string, docs []Document) error {
+	k := &memoryKind{
+		ready:    true,
+		docs:     make(map[string]memoryDoc, len(docs)),
+		postings: map[string]map[string]float64{},
+	}
+	for _, doc := range docs {
+		k.put(doc)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kinds[kind] = k
+	return nil
+}
+
+// Put adds or replaces documents.
+func (m *MemoryIndex) Put(kind string, docs ...Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.kind(kind)
+	for _, doc := range docs {
+		k.delete(doc.ID)
+		k.put(doc)
+	}
+	return nil
+}
+
+// Delete removes documents.
+func (m *MemoryIndex) Delete(kind string, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.kind(kind)
+	for _, id := range ids {
+		k.delete(id)
+	}
+	return nil
+}
+
+// Search finds the documents matching every word of the query. Rare words
+// and matches in fields with a higher weight rank higher.
+func (m *MemoryIndex) Search(q Query) (Result, error) {
+	m.mu.Lock()
+	k, ok := m.kinds[q.Kind]
+	if !ok || !k.ready {
+		m.mu.Unlock()
+		return Result{}, ErrNotReady
+	}
+	if k.words == nil {
+		k.words = make([]string, 0, len(k.postings))
+		for word := range k.postings {
+			k.words = append(k.words, word)
+		}
+		sort.Strings(k.words)
+	}
+	words := k.words
+	m.mu.Unlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var scores map[string]float64
+	for _, token := range Tokenize(q.Text) {
+		matches := k.match(token, words)
+		next := map[string]float64{}
+		for word, factor := range matches {
+			idf := math.Log(1 + float64(len(k.docs))/float64(len(k.postings[word])))
+			for id, tf := range k.postings[word] {
+				if scores != nil {
+					if _, ok := scores[id]; !ok {
+						continue
+					}
+				}
+				if q.BranchID != 0 && k.docs[id].branchID != q.BranchID {
+					continue
+				}
+				next[id] = max(next[id], factor*idf*tf/(tf+1))
+			}
+		}
+		for id := range next {
+			next[id] += scores[id]
+		}
+		scores = next
+		if len(scores) == 0 {
+			break
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	result := Result{Total: len(hits)}
+	if q.Offset < len(hits) {
+		hits = hits[max(q.Offset, 0):]
+		if q.Limit > 0 && q.Limit < len(hits) {
+			hits = hits[:q.Limit]
+		}
+		result.Hits = hits
+	}
+	return result, nil
+}
+
+// kind returns the documents of the kind. The lock must be held.
+func (m *MemoryIndex) kind(kind string) *memoryKind {
+	k, ok := m.kinds[kind]
+	if !ok {
+		k = &memoryKind{docs: map[string]memoryDoc{}, postings: map[string]map[string]float64{}}
+		m.kinds[kind] = k
+	}
+	return k
+}
+
+func (k *memoryKind) put(doc Document) {
+	weights := map[string]float64{}
+	for _, f := range doc.Fields {
+		for _, word := range Tokenize(f.Text) {
+			weights[word] += f.Weight
+		}
+	}
+
+	words := make([]string, 0, len(weights))
+	for word, weight := range weights {
+		if k.postings[word] == nil {
+			k.postings[word] = map[string]float64{}
+			k.words = nil
+		}
+		k.postings[word][doc.ID] = weight
+		words = append(words, word)
+	}
+	k.docs[doc.ID] = memoryDoc{branchID: doc.BranchID, words: words}
+}
+
+func (k *memoryKind) delete(id string) {
+	doc, ok := k.docs[id]
+	if !ok {
+		return
+	}
+	for _, word := range doc.words {
+		delete(k.postings[word], id)
+		if len(k.postings[word]) == 0 {
+			delete(k.postings, word)
+			k.words = nil
+		}
+	}
+	delete(k.docs, id)
+}
+
+// match returns the indexed words matching the token with the factor of
+// their score. The words must be sorted. Tokens of four letters may have one
+// typo, of eight letters two typos.
+func (k *memoryKind) match(token string, words []string) map[string]float64 {
+	matches := map[string]float64{}
+	if _, ok := k.postings[token]; ok {
+		matches[token] = 1
+	}
+
+	i := sort.SearchStrings(words, token)
+	for ; i < len(words) && strings.HasPrefix(words[i], token); i++ {
+		if words[i] != token {
+			matches[words[i]] = prefixFactor
+		}
+	}
+
+	typos := 0
+	runes := []rune(token)
+	switch {
+	case len(runes) >= 8:
+		typos = 2
+	case len(runes) >= 4:
+		typos = 1
+	}
+	if typos == 0 {
+		return matches
+	}
+
+	for _, word := range words {
+		if _, ok := matches[word]; ok {
+			continue
+		}
+		if d := distance(runes, []rune(word), typos); d <= typos {
+			matches[word] = typoFactor / float64(d)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func books() *MemoryIndex {
+	i := NewMemoryIndex()
+	i.Replace(Books, []Document{
+		{ID: "1", BranchID: 1, Fields: []Field{{"Der Prozess", 3}, {"Franz Kafka", 2}}},
+		{ID: "2", BranchID: 1, Fields: []Field{{"Das Schloss", 3}, {"Franz Kafka", 2}}},
+		{ID: "3", BranchID: 2, Fields: []Field{{"Germinal", 3}, {"Émile Zola", 2}}},
+		{ID: "4", BranchID: 2, Fields: []Field{{"Kafka am Strand", 3}, {"Haruki Murakami", 2}}},
+		{ID: "5", BranchID: 1, Fields: []Field{{"Die Verwandlung", 3}, {"Franz Kafka", 2}, {"Erzählung", 1.5}}},
+	})
+	return i
+}
+
+func TestSearch(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"word", Query{Text: "germinal"}, []string{"3"}},
+		{"all words", Query{Text: "kafka prozess"}, []string{"1"}},
+		{"no match", Query{Text: "kafka germinal"}, []string{}},
+		{"title first", Query{Text: "kafka"}, []string{"4", "1", "2", "5"}},
+		{"prefix", Query{Text: "verwand"}, []string{"5"}},
+		{"diacritics", Query{Text: "EMILE"}, []string{"3"}},
+		{"diacritics in query", Query{Text: "erzählung"}, []string{"5"}},
+		{"typo", Query{Text: "kafak prozes"}, []string{"1"}},
+		{"two typos", Query{Text: "verwnadlugn"}, []string{"5"}},
+		{"typo in four letters", Query{Text: "zolx"}, []string{"3"}},
+		{"no typo in short words", Query{Text: "das"}, []string{"2"}},
+		{"branch", Query{Text: "kafka", BranchID: 2}, []string{"4"}},
+		{"limit", Query{Text: "kafka", Limit: 2}, []string{"4", "1"}},
+		{"offset", Query{Text: "kafka", Offset: 3}, []string{"5"}},
+	}
+
+	i := books()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.query.Kind = Books
+			result, err := i.Search(tc.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, result.IDs())
+		})
+	}
+}
+
+func TestSearchRanksExactMatches(t *testing.T) {
+	i := NewMemoryIndex()
+	i.Replace(Books, []Document{
+		{ID: "1", Fields: []Field{{"Kafkaesk", 3}}},
+		{ID: "2", Fields: []Field{{"Kafka", 3}}},
+	})
+
+	result, err := i.Search(Query{Kind: Books, Text: "kafka"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "1"}, result.IDs())
+	assert.Equal(t, 2, result.Total)
+}
+
+func TestPutAndDelete(t *testing.T) {
+	i := books()
+
+	assert.NoError(t, i.Put(Books, Document{ID: "3", BranchID: 2, Fields: []Field{{"Nana", 3}, {"Émile Zola", 2}}}))
+	result, _ := i.Search(Query{Kind: Books, Text: "germinal"})
+	assert.Empty(t, result.IDs())
+	result, _ = i.Search(Query{Kind: Books, Text: "nana"})
+	assert.Equal(t, []string{"3"}, result.IDs())
+
+	assert.NoError(t, i.Delete(Books, "3", "unknown"))
+	result, _ = i.Search(Query{Kind: Books, Text: "zola"})
+	assert.Empty(t, result.IDs())
+}
+
+func TestNotReady(t *testing.T) {
+	i := NewMemoryIndex()
+	i.Put(Authors, Document{ID: "1", Fields: []Field{{"Franz Kafka", 1}}})
+
+	_, err := i.Search(Query{Kind: Authors, Text: "kafka"})
+	assert.ErrorIs(t, err, ErrNotReady)
+
+	_, err = noIndex{}.Search(Query{Kind: Books, Text: "kafka"})
+	assert.ErrorIs(t, err, ErrNotReady)
+}
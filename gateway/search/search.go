@@ -0,0 +1,129 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Kinds of documents in the index.
+const (
+	Books   = "book"
+	Authors = "author"
+)
+
+// ErrNotReady is returned while the documents of a kind are not indexed
+// yet. Callers fall back to searching the database.
+var ErrNotReady = errors.New("search: index not ready")
+
+// Document is a book or an author as it is indexed.
+type Document struct {
+	ID       string
+	BranchID uint
+	Fields   []Field
+}
+
+// Field is a text of a document. Matches in fields with a higher weight
+// rank higher.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Query searches documents of a kind. Every word of the text has to match,
+// a BranchID other than 0 only finds documents of the branch.
+type Query struct {
+	Kind     string
+	Text     string
+	BranchID uint
+	Offset   int
+	Limit    int
+}
+
+// Hit is a document found by a query.
+type Hit struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Result lists the hits of a query, best first, from the offset up to the
+// limit. Total counts all hits.
+type Result struct {
+	Total int
+	Hits  []Hit
+}
+
+// IDs returns the IDs of the hits.
+func (r Result) IDs() []string {
+	ids := make([]string, 0, len(r.Hits))
+	for _, h := range r.Hits {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+// Index is a full-text index of documents of several kinds.
+type Index interface {
+	// Replace replaces all documents of the kind and marks it ready.
+	Replace(kind string, docs []Document) error
+	// Put adds or replaces documents.
+	Put(kind string, docs ...Document) error
+	// Delete removes documents.
+	Delete(kind string, ids ...string) error
+	// Search finds documents. It returns ErrNotReady until the kind was
+	// replaced once.
+	Search(q Query) (Result, error)
+}
+
+var (
+	indexMu sync.Mutex
+	index   Index
+)
+
+// Default returns the configured index. SEARCH_INDEX selects the driver,
+// memory (default) or none to always search the database.
+func Default() (Index, error) {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	if index == nil {
+		i, err := NewIndex()
+		if err != nil {
+			return nil, err
+		}
+		index = i
+	}
+
+	return index, nil
+}
+
+// SetIndex replaces the index, e.g. in tests.
+func SetIndex(i Index) {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	index = i
+}
+
+// NewIndex creates the index from the config.
+func NewIndex() (Index, error) {
+	viper.SetDefault("SEARCH_INDEX", "memory")
+
+	switch viper.GetString("SEARCH_INDEX") {
+	case "memory":
+		return NewMemoryIndex(), nil
+	case "none":
+		return noIndex{}, nil
+	default:
+		return nil, fmt.Errorf("search: unknown index %q", viper.GetString("SEARCH_INDEX"))
+	}
+}
+
+// noIndex indexes nothing and is never ready.
+type noIndex struct{}
+
+func (noIndex) Replace(string, []Document) error { return nil }
+func (noIndex) Put(string, ...Document) error    { return nil }
+func (noIndex) Delete(string, ...string) error   { return nil }
+func (noIndex) Search(Query) (Result, error)     { return Result{}, ErrNotReady }
@@ -0,0 +1,568 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Weights of the fields of the documents.
+const (
+	weightTitle       = 3
+	weightSubtitle    = 2
+	weightAuthor      = 2
+	weightContributor = 1
+	weightTag         = 1.5
+	weightGenre       = 1
+	weightDescription = 1
+)
+
+// delay collects changes before they are indexed.
+const delay = 200 * time.Millisecond
+
+// retryDelay is the time until changes that failed to be indexed are tried
+// again.
+const retryDelay = 5 * time.Second
+
+// batchSize is the number of rows loaded at once when rebuilding the index.
+const batchSize = 1000
+
+// idCondition matches the conditions on a column gorm can't parse, e.g.
+// "id = ?" or "book.id IN (?)".
+var idCondition = regexp.MustCompile(`^\s*(?:\w+\.)?(\w+)\s*(?:=|IN)\s*\(?\?\)?\s*$`)
+
+// Syncer keeps the index in sync with the books and authors in the
+// database. Changes made through gorm are collected by callbacks and indexed
+// in the background once their transaction is committed. Raw SQL and other
+// writers, like the core, are only seen by StartRebuild.
+type Syncer struct {
+	db    *gorm.DB
+	index Index
+
+	mu      sync.Mutex
+	pending map[string]map[string]bool
+	rebuild map[string]bool
+	wake    chan struct{}
+}
+
+// Attach registers the callbacks on the database and builds the index in
+// the background.
+func Attach(db *gorm.DB, index Index) (*Syncer, error) {
+	s := NewSyncer(db, index)
+
+	if _, ok := db.ConnPool.(*txPool); !ok {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		pool := &txPool{ConnPool: db.ConnPool, db: sqlDB}
+		db.ConnPool, db.Statement.ConnPool = pool, pool
+	}
+
+	for _, cb := range []struct {
+		processor interface {
+			Register(string, func(*gorm.DB)) error
+		}
+	}{
+		{db.Callback().Create().After("gorm:create")},
+		{db.Callback().Update().After("gorm:update")},
+		{db.Callback().Delete().After("gorm:delete")},
+	} {
+		if err := cb.processor.Register("search:sync", s.record); err != nil {
+			return nil, err
+		}
+	}
+
+	s.Rebuild(Books)
+	s.Rebuild(Authors)
+	go s.run()
+
+	return s, nil
+}
+
+// NewSyncer creates a syncer without registering callbacks.
+func NewSyncer(db *gorm.DB, index Index) *Syncer {
+	return &Syncer{
+		db:      db,
+		index:   index,
+		pending: map[string]map[string]bool{},
+		rebuild: map[string]bool{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Rebuild schedules indexing all documents of the kind.
+func (s *Syncer) Rebuild(kind string) {
+	s.mu.Lock()
+	s.rebuild[kind] = true
+	s.mu.Unlock()
+	s.notify()
+}
+
+// StartRebuild indexes all documents again in the given interval until stop
+// is closed, so changes the callbacks don't see are indexed as well.
+func (s *Syncer) StartRebuild(interval time.Duration, stop <-chan struct{}) {
+	if _, ok := s.index.(noIndex); ok {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.Rebuild(Books)
+			s.Rebuild(Authors)
+		}
+	}
+}
+
+// Flush indexes the collected changes. If that fails, they are kept to be
+// indexed with the next flush.
+func (s *Syncer) Flush() (err error) {
+	s.mu.Lock()
+	pending, rebuild := s.pending, s.rebuild
+	s.pending, s.rebuild = map[string]map[string]bool{}, map[string]bool{}
+	s.mu.Unlock()
+
+	defer func() {
+		if err != nil {
+			s.requeue(pending, rebuild)
+		}
+	}()
+
+	if rebuild[Authors] {
+		if err := s.rebuildAuthors(); err != nil {
+			return err
+		}
+	} else if err := s.syncAuthors(keys(pending["author"])); err != nil {
+		return err
+	}
+
+	if rebuild[Books] {
+		return s.rebuildBooks()
+	}
+
+	ids := keys(pending["book"])
+	for _, related := range []struct {
+		table string
+		query string
+	}{
+		{"author", "SELECT id FROM book WHERE author_id IN ?"},
+		{"author", "SELECT book_id FROM book_contributor WHERE author_id IN ?"},
+		{"genre", "SELECT id FROM book WHERE genre_id IN ?"},
+		{"tag", "SELECT book_id FROM book_tag WHERE tag_id IN ?"},
+	} {
+		if len(pending[related.table]) == 0 {
+			continue
+		}
+		var bookIDs []string
+		if err := s.db.Raw(related.query, keys(pending[related.table])).Scan(&bookIDs).Error; err != nil {
+			return err
+		}
+		ids = append(ids, bookIDs...)
+	}
+	return s.syncBooks(ids)
+}
+
+func (s *Syncer) run() {
+	for range s.wake {
+		time.Sleep(delay)
+		if err := s.Flush(); err != nil {
+			log.Printf("search: %v", err)
+			time.AfterFunc(retryDelay, s.notify)
+		}
+	}
+}
+
+// requeue adds changes that failed to be indexed to the collected ones.
+func (s *Syncer) requeue(pending map[string]map[string]bool, rebuild map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for kind := range rebuild {
+		s.rebuild[kind] = true
+	}
+	for table, ids := range pending {
+		if s.pending[table] == nil {
+			s.pending[table] = map[string]bool{}
+		}
+		for id := range ids {
+			s.pending[table][id] = true
+		}
+	}
+}
+
+func (s *Syncer) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// record collects the rows changed by a statement. If the rows are unknown,
+// all books are indexed again. Changes in a transaction are collected when
+// it is committed.
+func (s *Syncer) record(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil && db.Statement.Table == "" {
+		return
+	}
+
+	table, column := db.Statement.Table, "id"
+	switch table {
+	case "book", "author", "genre", "tag":
+	case "book_tag", "book_contributor":
+		table, column = "book", "book_id"
+	default:
+		return
+	}
+
+	ids, ok := changedIDs(db, column)
+	if tx, inTx := db.Statement.ConnPool.(*txConn); inTx {
+		tx.onCommit(func() { s.add(table, ids, ok) })
+		return
+	}
+	s.add(table, ids, ok)
+}
+
+// add collects the changed rows of the table, with ok false all books are
+// indexed again.
+func (s *Syncer) add(table string, ids []string, ok bool) {
+	s.mu.Lock()
+	if ok {
+		if s.pending[table] == nil {
+			s.pending[table] = map[string]bool{}
+		}
+		for _, id := range ids {
+			s.pending[table][id] = true
+		}
+	} else {
+		s.rebuild[Books] = true
+		if table == "author" {
+			s.rebuild[Authors] = true
+		}
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *Syncer) rebuildBooks() error {
+	docs := []Document{}
+	var books []models.Book
+	err := s.books().FindInBatches(&books, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, b := range books {
+			docs = append(docs, BookDocument(b))
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+	return s.index.Replace(Books, docs)
+}
+
+func (s *Syncer) syncBooks(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var books []models.Book
+	if err := s.books().Where("book.id IN ?", ids).Find(&books).Error; err != nil {
+		return err
+	}
+
+	docs := make([]Document, 0, len(books))
+	found := map[string]bool{}
+	for _, b := range books {
+		docs = append(docs, BookDocument(b))
+		found[b.ID.String()] = true
+	}
+	if err := s.index.Put(Books, docs...); err != nil {
+		return err
+	}
+	return s.index.Delete(Books, missing(ids, found)...)
+}
+
+func (s *Syncer) rebuildAuthors() error {
+	docs := []Document{}
+	var authors []models.Author
+	err := s.db.Model(&models.Author{}).FindInBatches(&authors, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, a := range authors {
+			docs = append(docs, AuthorDocument(a))
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+	return s.index.Replace(Authors, docs)
+}
+
+func (s *Syncer) syncAuthors(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var authors []models.Author
+	if err := s.db.Where("id IN ?", ids).Find(&authors).Error; err != nil {
+		return err
+	}
+
+	docs := make([]Document, 0, len(authors))
+	found := map[string]bool{}
+	for _, a := range authors {
+		docs = append(docs, AuthorDocument(a))
+		found[fmt.Sprint(a.ID)] = true
+	}
+	if err := s.index.Put(Authors, docs...); err != nil {
+		return err
+	}
+	return s.index.Delete(Authors, missing(ids, found)...)
+}
+
+func (s *Syncer) books() *gorm.DB {
+	return s.db.Model(&models.Book{}).Preload("Author").Scopes(models.PreloadContributors).Preload("Genre").Preload("Tags")
+}
+
+// BookDocument returns the document of a book with its author,
+// contributors, genre and tags loaded.
+func BookDocument(b models.Book) Document {
+	doc := Document{ID: b.ID.String()}
+	if b.BranchID != nil {
+		doc.BranchID = *b.BranchID
+	}
+
+	doc.Fields = append(doc.Fields, Field{b.Title, weightTitle})
+	if b.Subtitle != nil {
+		doc.Fields = append(doc.Fields, Field{*b.Subtitle, weightSubtitle})
+	}
+	if b.ShortDescription != nil {
+		doc.Fields = append(doc.Fields, Field{*b.ShortDescription, weightDescription})
+	}
+	if b.Author != nil && !slices.ContainsFunc(b.Contributors, func(c models.BookContributor) bool {
+		return c.AuthorID == b.Author.ID
+	}) {
+		doc.Fields = append(doc.Fields, Field{b.Author.Firstname + " " + b.Author.Surname, weightAuthor})
+	}
+	for _, c := range b.Contributors {
+		if c.Author == nil {
+			continue
+		}
+		weight := float64(weightContributor)
+		if c.Role == models.ContributorAuthor {
+			weight = weightAuthor
+		}
+		doc.Fields = append(doc.Fields, Field{c.Author.Firstname + " " + c.Author.Surname, weight})
+	}
+	if b.Genre != nil {
+		doc.Fields = append(doc.Fields, Field{b.Genre.Name, weightGenre})
+	}
+	for _, t := range b.Tags {
+		doc.Fields = append(doc.Fields, Field{t.Name, weightTag})
+	}
+
+	return doc
+}
+
+// AuthorDocument returns the document of an author.
+func AuthorDocument(a models.Author) Document {
+	return Document{
+		ID:     fmt.Sprint(a.ID),
+		Fields: []Field{{a.Firstname + " " + a.Surname, 1}},
+	}
+}
+
+// changedIDs returns the values of the column in the rows of the statement
+// or, if there are none, in its conditions.
+func changedIDs(db *gorm.DB, column string) ([]string, bool) {
+	if ids, ok := rowIDs(db, column); ok {
+		return ids, true
+	}
+
+	where, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil, false
+	}
+	for _, expr := range where.Exprs {
+		switch e := expr.(type) {
+		case clause.Eq:
+			if isColumn(db, e.Column, column) {
+				return values(e.Value), true
+			}
+		case clause.IN:
+			if isColumn(db, e.Column, column) {
+				return values(e.Values), true
+			}
+		case clause.Expr:
+			if m := idCondition.FindStringSubmatch(e.SQL); m != nil && m[1] == column && len(e.Vars) == 1 {
+				return values(e.Vars[0]), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// rowIDs reads the column of the models or maps the statement works on.
+func rowIDs(db *gorm.DB, column string) ([]string, bool) {
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	if !rv.IsValid() {
+		return nil, false
+	}
+
+	var rows []reflect.Value
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			rows = append(rows, reflect.Indirect(rv.Index(i)))
+		}
+	default:
+		rows = []reflect.Value{rv}
+	}
+
+	ids := []string{}
+	for _, row := range rows {
+		var value any
+		switch row.Kind() {
+		case reflect.Map:
+			v := row.MapIndex(reflect.ValueOf(column))
+			if !v.IsValid() {
+				return nil, false
+			}
+			value = v.Interface()
+		case reflect.Struct:
+			if db.Statement.Schema == nil {
+				return nil, false
+			}
+			field := db.Statement.Schema.LookUpField(column)
+			if field == nil {
+				return nil, false
+			}
+			v, zero := field.ValueOf(db.Statement.Context, row)
+			if zero {
+				return nil, false
+			}
+			value = v
+		default:
+			return nil, false
+		}
+		ids = append(ids, values(value)...)
+	}
+	return ids, len(ids) > 0
+}
+
+func isColumn(db *gorm.DB, c any, column string) bool {
+	var name string
+	switch c := c.(type) {
+	case clause.Column:
+		name = c.Name
+	case string:
+		name = c
+	}
+	if name == clause.PrimaryKey && db.Statement.Schema != nil && db.Statement.Schema.PrioritizedPrimaryField != nil {
+		name = db.Statement.Schema.PrioritizedPrimaryField.DBName
+	}
+	return name == column
+}
+
+// values formats a value or the elements of a slice as IDs.
+func values(v any) []string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		ids := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			ids = append(ids, values(rv.Index(i).Interface())...)
+		}
+		return ids
+	}
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		return values(rv.Elem().Interface())
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+func keys(m map[string]bool) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func missing(ids []string, found map[string]bool) []string {
+	var gone []string
+	for _, id := range ids {
+		if !found[id] {
+			gone = append(gone, id)
+		}
+	}
+	return gone
+}
+
+// txPool begins transactions that run functions once they are committed.
+type txPool struct {
+	gorm.ConnPool
+	db *sql.DB
+}
+
+func (p *txPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &txConn{Tx: tx, db: p.db}, nil
+}
+
+func (p *txPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+// txConn is a transaction that runs functions once it is committed.
+type txConn struct {
+	*sql.Tx
+	db *sql.DB
+
+	mu        sync.Mutex
+	committed []func()
+}
+
+func (t *txConn) onCommit(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.committed = append(t.committed, fn)
+}
+
+func (t *txConn) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	committed := t.committed
+	t.committed = nil
+	t.mu.Unlock()
+
+	for _, fn := range committed {
+		fn()
+	}
+	return nil
+}
+
+func (t *txConn) GetDBConn() (*sql.DB, error) {
+	return t.db, nil
+}
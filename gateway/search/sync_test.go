@@ -0,0 +1,150 @@
+package search
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/abaldeweg/warehouse-server/gateway/core/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSyncer(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/search.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Author{}, &models.Genre{}, &models.Tag{}, &models.Book{}, &models.BookImage{}, &models.BookContributor{}))
+
+	index := NewMemoryIndex()
+	s, err := Attach(db, index)
+	require.NoError(t, err)
+
+	search := func(kind, text string) []string {
+		t.Helper()
+		require.NoError(t, s.Flush())
+		result, err := index.Search(Query{Kind: kind, Text: text})
+		require.NoError(t, err)
+		return result.IDs()
+	}
+
+	author := models.Author{Firstname: "Franz", Surname: "Kafka"}
+	require.NoError(t, db.Create(&author).Error)
+	genre := models.Genre{Name: "Klassiker", BranchID: 1}
+	require.NoError(t, db.Create(&genre).Error)
+	tag := models.Tag{Name: "Novelle", BranchID: 1}
+	require.NoError(t, db.Create(&tag).Error)
+
+	branchID, authorID := uint(1), uint(author.ID)
+	book := models.Book{ID: uuid.New(), BranchID: &branchID, Title: "Die Verwandlung", AuthorID: &authorID, GenreID: &genre.ID}
+	require.NoError(t, db.Omit("Tags").Create(&book).Error)
+	id := book.ID.String()
+
+	assert.Equal(t, []string{id}, search(Books, "kafka verwandlung"))
+	assert.Equal(t, []string{id}, search(Books, "klassiker"))
+	assert.NotEmpty(t, search(Authors, "kafka"))
+
+	require.NoError(t, db.Table("book_tag").Create([]map[string]any{{"book_id": book.ID, "tag_id": tag.ID}}).Error)
+	assert.Equal(t, []string{id}, search(Books, "novelle"))
+
+	require.NoError(t, db.Model(&book).Update("title", "Der Prozess").Error)
+	assert.Empty(t, search(Books, "verwandlung"))
+	assert.Equal(t, []string{id}, search(Books, "prozess"))
+
+	require.NoError(t, db.Model(&models.Author{}).Where("id = ?", author.ID).Update("surname", "Kavka").Error)
+	assert.Equal(t, []string{id}, search(Books, "kavka prozess"))
+
+	require.NoError(t, db.Model(&models.Genre{}).Where("id = ?", genre.ID).Update("name", "Moderne").Error)
+	assert.Equal(t, []string{id}, search(Books, "moderne"))
+
+	require.NoError(t, db.Model(&models.Tag{}).Where("id = ?", tag.ID).Update("name", "Roman").Error)
+	assert.Equal(t, []string{id}, search(Books, "roman"))
+
+	translator := models.Author{Firstname: "Max", Surname: "Brod"}
+	require.NoError(t, db.Create(&translator).Error)
+	require.NoError(t, db.Create(&models.BookContributor{BookID: book.ID, AuthorID: translator.ID, Role: models.ContributorTranslator}).Error)
+	assert.Equal(t, []string{id}, search(Books, "brod kavka prozess"))
+
+	require.NoError(t, db.Where("id = ?", book.ID).Delete(&models.Book{}).Error)
+	assert.Empty(t, search(Books, "prozess"))
+
+	require.NoError(t, db.Delete(&models.Author{}, author.ID).Error)
+	assert.Empty(t, search(Authors, "kavka"))
+}
+
+func TestSyncerTransaction(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/search.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Author{}, &models.Genre{}, &models.Tag{}, &models.Book{}, &models.BookImage{}, &models.BookContributor{}))
+
+	index := NewMemoryIndex()
+	s, err := Attach(db, index)
+	require.NoError(t, err)
+
+	search := func(text string) []string {
+		t.Helper()
+		require.NoError(t, s.Flush())
+		result, err := index.Search(Query{Kind: Books, Text: text})
+		require.NoError(t, err)
+		return result.IDs()
+	}
+
+	branchID := uint(1)
+	book := models.Book{ID: uuid.New(), BranchID: &branchID, Title: "Der Steppenwolf"}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Create(&book).Error; err != nil {
+			return err
+		}
+		assert.Empty(t, search("steppenwolf"))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{book.ID.String()}, search("steppenwolf"))
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&book).Update("title", "Siddhartha").Error; err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	assert.Empty(t, search("siddhartha"))
+	assert.Equal(t, []string{book.ID.String()}, search("steppenwolf"))
+}
+
+// failingIndex fails to put documents until it is told to work.
+type failingIndex struct {
+	Index
+	fail bool
+}
+
+func (i *failingIndex) Put(kind string, docs ...Document) error {
+	if i.fail {
+		return errors.New("unavailable")
+	}
+	return i.Index.Put(kind, docs...)
+}
+
+func TestSyncerRequeue(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/search.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Author{}, &models.Genre{}, &models.Tag{}, &models.Book{}, &models.BookImage{}, &models.BookContributor{}))
+
+	index := &failingIndex{Index: NewMemoryIndex(), fail: true}
+	s := NewSyncer(db, index)
+	s.Rebuild(Books)
+	require.NoError(t, s.Flush())
+
+	branchID := uint(1)
+	book := models.Book{ID: uuid.New(), BranchID: &branchID, Title: "Demian"}
+	require.NoError(t, db.Omit("Tags").Create(&book).Error)
+	s.add(Books, []string{book.ID.String()}, true)
+	assert.Error(t, s.Flush())
+
+	index.fail = false
+	require.NoError(t, s.Flush())
+	result, err := index.Search(Query{Kind: Books, Text: "demian"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{book.ID.String()}, result.IDs())
+}